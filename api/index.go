@@ -6,9 +6,12 @@ import (
 	"sync"
 
 	"x-track/config"
+	"x-track/events"
 	"x-track/middleware"
+	"x-track/migrations"
 	"x-track/models"
 	"x-track/routes"
+	"x-track/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,12 +45,31 @@ func initializeApp() {
 		panic("Failed to connect to database: " + err.Error())
 	}
 
+	// Apply pending migrations; the advisory lock keeps concurrent cold starts from racing
+	if cfg.Database.MigrateOnStart {
+		if _, err := migrations.NewMigrator(db).Up(0); err != nil {
+			panic("Failed to run migrations: " + err.Error())
+		}
+	}
+
+	// Initialize event bus
+	if err := events.Init(cfg.Events.Backend, db, cfg.Database.GetDSN()); err != nil {
+		panic("Failed to initialize event bus: " + err.Error())
+	}
+	service.StartEventSubscribers(db)
+
 	// Initialize Gin router
 	router = gin.New()
 	router.Use(gin.Recovery())
 
-	// Apply CORS middleware
-	router.Use(middleware.CORSMiddleware())
+	// Only believe forwarded client IPs from configured proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic("Invalid TRUSTED_PROXIES: " + err.Error())
+	}
+	router.TrustedPlatform = cfg.Server.TrustedPlatform
+
+	// Apply request ID and CORS middleware
+	router.Use(middleware.RequestIDMiddleware(), middleware.CORSMiddleware())
 
 	// Setup routes
 	routes.SetupRoutes(router, db)
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"x-track/config"
+	"x-track/models"
+	"x-track/service"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// command is a subcommand of the server binary. Administrative commands print their
+// result to stdout as JSON so they can be scripted; logs go to stderr.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "start the API server (default)", runServe},
+		{"migrate", "apply, revert or list schema migrations", runMigrate},
+		{"create-user", "create a user", runCreateUser},
+		{"reset-password", "set a new password for a user", runResetPassword},
+		{"create-account", "create a trading account with an API token", runCreateAccount},
+		{"rotate-token", "replace an account API token", runRotateToken},
+		{"import", "import statistics into an account", runImport},
+		{"export", "export the statistics of an account", runExport},
+	}
+}
+
+// run dispatches to a subcommand, serving when there is none
+func run(args []string) error {
+	if len(args) == 0 {
+		return runServe(nil)
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Println(usage())
+		return nil
+	}
+	for _, cmd := range commands() {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	return usageError(usage())
+}
+
+func usage() string {
+	var b strings.Builder
+	b.WriteString("usage: x-track <command> [options]\n\ncommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "\n  %-16s %s", cmd.name, cmd.summary)
+	}
+	b.WriteString("\n\nRun x-track <command> -h for the options of a command.")
+	return b.String()
+}
+
+// usageError is returned for invalid command lines
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// newFlagSet creates the flag set of a command; parseFlags reports its problems
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseFlags parses a command's options, which take no positional arguments
+func parseFlags(fs *flag.FlagSet, synopsis string, args []string) error {
+	err := fs.Parse(args)
+	if err == nil && fs.NArg() > 0 {
+		err = fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if err == nil {
+		return nil
+	}
+	return flagUsage(fs, synopsis, err)
+}
+
+// flagUsage describes the options of a command after the problem with its command line
+func flagUsage(fs *flag.FlagSet, synopsis string, err error) error {
+	var b strings.Builder
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(&b, "%v\n", err)
+	}
+	fmt.Fprintf(&b, "usage: x-track %s %s\n\noptions:\n", fs.Name(), synopsis)
+	fs.SetOutput(&b)
+	fs.PrintDefaults()
+	fs.SetOutput(io.Discard)
+	return usageError(strings.TrimRight(b.String(), "\n"))
+}
+
+// openDB loads the configuration and connects to the database. SQL logging is turned
+// off because stdout carries the command's result.
+func openDB() (*gorm.DB, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	db, err := models.InitDB(cfg.Database.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	return db, nil
+}
+
+// findUser looks a user up by ID or username
+func findUser(userService *service.UserService, ref string) (*models.User, error) {
+	var user *models.User
+	var err error
+	if id, parseErr := strconv.ParseUint(ref, 10, 64); parseErr == nil {
+		user, err = userService.GetUserByID(uint(id))
+	} else {
+		user, err = userService.GetUserByUsername(ref)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %q not found", ref)
+	}
+	return user, err
+}
+
+// readPassword reads a password from the first line of stdin, or generates one that
+// satisfies the password policy when fromStdin is false. generated reports the latter,
+// in which case the password has to be handed to the user.
+func readPassword(db *gorm.DB, fromStdin bool) (password string, generated bool, err error) {
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", false, err
+		}
+		password = strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", false, errors.New("no password on stdin")
+		}
+		return password, false, nil
+	}
+
+	// Hex doubles the length, and at least 96 bits of randomness are used
+	n := (service.NewPasswordPolicyService(db).Policy().MinLength + 1) / 2
+	if n < 12 {
+		n = 12
+	}
+	password, err = utils.GenerateSecureToken(n)
+	return password, true, err
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printError reports a failed command on stderr, as JSON like the results
+func printError(err error) {
+	encoder := json.NewEncoder(os.Stderr)
+	encoder.Encode(map[string]string{"error": err.Error()})
+}
@@ -2,9 +2,15 @@ import * as React from 'react';
 import { useState, createContext, useContext, useEffect } from 'react';
 import { Routes, Route, Navigate, useNavigate, useLocation } from 'react-router-dom';
 import { Login } from './components/Login';
+import { Register } from './components/Register';
 import { Dashboard } from './components/Dashboard';
 import { AdminDashboard } from './components/AdminDashboard';
-import { User, AuthResponse } from './types';
+import { ShareView } from './components/ShareView';
+import { ImpersonationBanner } from './components/ImpersonationBanner';
+import { User, AuthResponse, ImpersonationResponse, ImpersonationSession } from './types';
+import { api } from './services/api';
+import { hasPermission, homePath } from './services/permissions';
+import { Permission } from './types';
 
 interface AuthContextType {
   user: User | null;
@@ -22,8 +28,11 @@ const AuthContext = createContext<AuthContextType>({
 
 export const useAuth = () => useContext(AuthContext);
 
+// Where a running impersonation and the admin's own login are kept
+const IMPERSONATION_KEY = 'xtrack_impersonation';
+
 // Protected Route Component
-const ProtectedRoute = ({ children, allowedRoles }: { children: React.ReactNode, allowedRoles?: string[] }) => {
+const ProtectedRoute = ({ children, permission }: { children: React.ReactNode, permission?: Permission }) => {
   const { user, token } = useAuth();
   const location = useLocation();
 
@@ -31,9 +40,13 @@ const ProtectedRoute = ({ children, allowedRoles }: { children: React.ReactNode,
     return <Navigate to="/login" state={{ from: location }} replace />;
   }
 
-  if (allowedRoles && !allowedRoles.includes(user.role)) {
-    // Redirect to appropriate dashboard if role doesn't match
-    return <Navigate to={user.role === 'admin' ? '/admin' : '/dashboard'} replace />;
+  if (permission && !hasPermission(user, permission)) {
+    // Redirect to the page this user can see
+    const home = homePath(user);
+    if (home === location.pathname) {
+      return <div className="min-h-screen flex items-center justify-center text-slate-400">Your role has no access to this page.</div>;
+    }
+    return <Navigate to={home} replace />;
   }
 
   return <>{children}</>;
@@ -44,6 +57,7 @@ const App: React.FC = () => {
   const [token, setToken] = useState<string | null>(null);
   const navigate = useNavigate();
   const [isLoading, setIsLoading] = useState(true);
+  const [impersonation, setImpersonation] = useState<ImpersonationSession | null>(null);
 
   useEffect(() => {
     const savedToken = localStorage.getItem('xtrack_token');
@@ -52,6 +66,20 @@ const App: React.FC = () => {
       try {
         setToken(savedToken);
         setUser(JSON.parse(savedUser));
+        const savedImpersonation = localStorage.getItem(IMPERSONATION_KEY);
+        if (savedImpersonation) setImpersonation(JSON.parse(savedImpersonation));
+        // Permissions may have changed since the last login
+        api.users.getMyPermissions(savedToken).then(res => {
+          if (res.success && res.data) {
+            const permissions = res.data.permissions;
+            setUser(prev => {
+              if (!prev) return prev;
+              const updated = { ...prev, role: res.data!.role, permissions };
+              localStorage.setItem('xtrack_user', JSON.stringify(updated));
+              return updated;
+            });
+          }
+        });
       } catch (e) {
         localStorage.clear();
       }
@@ -63,47 +91,100 @@ const App: React.FC = () => {
     setUser(data.user);
     setToken(data.token);
     localStorage.setItem('xtrack_token', data.token);
+    localStorage.setItem('xtrack_refresh_token', data.refresh_token);
     localStorage.setItem('xtrack_user', JSON.stringify(data.user));
     
-    // Redirect based on role
-    if (data.user.role === 'admin') {
-      navigate('/admin');
-    } else {
-      navigate('/dashboard');
-    }
+    // Redirect based on permissions
+    navigate(homePath(data.user));
   };
 
   const logout = () => {
+    if (impersonation) {
+      // Logging out ends the impersonation and the admin's own session
+      if (token) api.auth.endImpersonation(token);
+      api.auth.logout(impersonation.admin.token);
+      localStorage.removeItem(IMPERSONATION_KEY);
+      setImpersonation(null);
+    } else if (token) {
+      api.auth.logout(token);
+    }
     setUser(null);
     setToken(null);
     localStorage.removeItem('xtrack_token');
+    localStorage.removeItem('xtrack_refresh_token');
     localStorage.removeItem('xtrack_user');
     navigate('/login');
   };
 
+  const startImpersonation = (data: ImpersonationResponse) => {
+    const session: ImpersonationSession = {
+      read_only: data.read_only,
+      expires_at: data.expires_at,
+      impersonator: data.impersonator,
+      admin: { token: token!, refresh_token: localStorage.getItem('xtrack_refresh_token') ?? '', user: user! },
+    };
+    localStorage.setItem(IMPERSONATION_KEY, JSON.stringify(session));
+    setImpersonation(session);
+
+    // Impersonation tokens can't be refreshed; the admin's refresh token is restored on stop
+    setUser(data.user);
+    setToken(data.token);
+    localStorage.setItem('xtrack_token', data.token);
+    localStorage.removeItem('xtrack_refresh_token');
+    localStorage.setItem('xtrack_user', JSON.stringify(data.user));
+    navigate(homePath(data.user));
+  };
+
+  const stopImpersonation = async () => {
+    if (!impersonation) return;
+    if (token) await api.auth.endImpersonation(token);
+    localStorage.removeItem(IMPERSONATION_KEY);
+    setImpersonation(null);
+    const { admin } = impersonation;
+    login({ token: admin.token, refresh_token: admin.refresh_token, expires_at: '', user: admin.user });
+  };
+
   if (isLoading) return null;
 
   return (
     <AuthContext.Provider value={{ user, token, login, logout }}>
+      {impersonation && user && (
+        <ImpersonationBanner user={user} impersonation={impersonation} onStop={stopImpersonation} />
+      )}
+      <div className={impersonation ? 'pt-10' : undefined}>
       <Routes>
         <Route path="/login" element={
-          user ? <Navigate to={user.role === 'admin' ? '/admin' : '/dashboard'} /> : <Login onLoginSuccess={login} />
+          user ? <Navigate to={homePath(user)} /> : <Login onLoginSuccess={login} />
         } />
         
+        {/* Identity provider redirect target for single sign-on */}
+        <Route path="/login/sso" element={
+          user ? <Navigate to={homePath(user)} /> : <Login onLoginSuccess={login} />
+        } />
+
+        {/* Self-registration through an invite link */}
+        <Route path="/register/:inviteCode" element={
+          user ? <Navigate to={homePath(user)} /> : <Register onRegisterSuccess={login} />
+        } />
+
         <Route path="/dashboard" element={
-          <ProtectedRoute allowedRoles={['user']}>
+          <ProtectedRoute permission="accounts:read">
             <Dashboard user={user!} token={token!} onLogout={logout} />
           </ProtectedRoute>
         } />
 
         <Route path="/admin" element={
-          <ProtectedRoute allowedRoles={['admin']}>
-            <AdminDashboard user={user!} token={token!} onLogout={logout} />
+          <ProtectedRoute permission="users:read">
+            <AdminDashboard user={user!} token={token!} onLogout={logout} onImpersonate={startImpersonation} />
           </ProtectedRoute>
         } />
 
-        <Route path="*" element={<Navigate to={user ? (user.role === 'admin' ? '/admin' : '/dashboard') : '/login'} />} />
+        {/* Public investor view, no login required */}
+        <Route path="/share/:shareToken" element={<ShareView />} />
+
+        <Route path="*" element={<Navigate to={user ? homePath(user) : '/login'} />} />
       </Routes>
+      </div>
     </AuthContext.Provider>
   );
 };
@@ -0,0 +1,148 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { Users, Plus, Trash2, X } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { AccountMember } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+interface AccountMembersProps {
+  token: string;
+  accountId: number;
+  accountName: string;
+  onClose: () => void;
+}
+
+// Manages which other users can view or manage one account
+export const AccountMembers: React.FC<AccountMembersProps> = ({ token, accountId, accountName, onClose }) => {
+  const [members, setMembers] = useState<AccountMember[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  const [username, setUsername] = useState('');
+  const [role, setRole] = useState<'viewer' | 'manager'>('viewer');
+
+  const fetchMembers = async () => {
+    const res = await api.accountMembers.list(token, accountId);
+    if (res.success && res.data) {
+      setMembers(res.data);
+    }
+  };
+
+  useEffect(() => {
+    fetchMembers();
+  }, [accountId]);
+
+  const handleGrant = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const res = await api.accountMembers.grant(token, accountId, username, role);
+    if (res.success) {
+      setUsername('');
+      fetchMembers();
+    } else {
+      setError(res.error || 'Failed to share account');
+    }
+  };
+
+  const handleRoleChange = async (member: AccountMember, newRole: 'viewer' | 'manager') => {
+    const res = await api.accountMembers.grant(token, accountId, member.user?.username ?? '', newRole);
+    if (res.success) {
+      fetchMembers();
+    } else {
+      setError(res.error || 'Failed to change role');
+    }
+  };
+
+  const handleRevoke = async (userId: number) => {
+    if (confirm('Stop sharing this account with this user?')) {
+      const res = await api.accountMembers.revoke(token, accountId, userId);
+      if (res.success) {
+        setMembers(members.filter(m => m.user_id !== userId));
+      }
+    }
+  };
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-xl max-h-[90vh] overflow-y-auto shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <Users className="w-5 h-5 text-primary" /> Members &middot; {accountName}
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        <p className="text-sm text-slate-400 mb-4">
+          Viewers can see statistics. Managers can also rename the account and manage its API keys.
+        </p>
+
+        <div className="space-y-3 mb-8">
+          {members.length === 0 && <p className="text-sm text-slate-500">This account isn't shared with anyone.</p>}
+          {members.map(m => (
+            <div key={m.id} className="flex items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3">
+              <span className="text-white font-medium truncate">{m.user?.username ?? `User #${m.user_id}`}</span>
+              <div className="flex items-center gap-3 shrink-0">
+                <select
+                  value={m.role}
+                  onChange={(e) => handleRoleChange(m, e.target.value as 'viewer' | 'manager')}
+                  className="bg-slate-900 border border-slate-700 rounded-lg px-2 py-1 text-sm text-white"
+                >
+                  <option value="viewer">Viewer</option>
+                  <option value="manager">Manager</option>
+                </select>
+                <button
+                  onClick={() => handleRevoke(m.user_id)}
+                  className="text-red-400 hover:text-red-300 transition-colors"
+                  title="Revoke access"
+                >
+                  <Trash2 className="w-4 h-4" />
+                </button>
+              </div>
+            </div>
+          ))}
+        </div>
+
+        <form onSubmit={handleGrant} className="flex flex-col md:flex-row gap-3 border-t border-white/5 pt-6">
+          <input
+            type="text"
+            className="flex-1 bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent transition-all"
+            placeholder="Username"
+            value={username}
+            onChange={(e) => setUsername(e.target.value)}
+            required
+          />
+          <select
+            value={role}
+            onChange={(e) => setRole(e.target.value as 'viewer' | 'manager')}
+            className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white"
+          >
+            <option value="viewer">Viewer</option>
+            <option value="manager">Manager</option>
+          </select>
+          <button
+            type="submit"
+            className="flex items-center justify-center gap-2 bg-primary hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-bold transition-all shadow-lg shadow-primary/25"
+          >
+            <Plus className="w-4 h-4" /> Share
+          </button>
+        </form>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
@@ -0,0 +1,229 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { Key, Plus, RefreshCw, Trash2, X, Copy } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { AccountToken, AccountTokenScope } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+const SCOPES: { value: AccountTokenScope; label: string }[] = [
+  { value: 'statistics:ingest', label: 'Ingest statistics' },
+  { value: 'trades:ingest', label: 'Ingest trades' },
+  { value: 'heartbeat:ingest', label: 'Heartbeat' },
+  { value: 'read', label: 'Read-only query' },
+];
+
+interface AccountTokensProps {
+  token: string;
+  accountId: number;
+  accountName: string;
+  onClose: () => void;
+}
+
+// Manages the API tokens used by terminals to push data for one account
+export const AccountTokens: React.FC<AccountTokensProps> = ({ token, accountId, accountName, onClose }) => {
+  const [tokens, setTokens] = useState<AccountToken[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  // Secret of a token that was just issued; it can't be fetched again
+  const [issued, setIssued] = useState<AccountToken | null>(null);
+
+  const [name, setName] = useState('');
+  const [scopes, setScopes] = useState<AccountTokenScope[]>(['statistics:ingest']);
+  const [expiresAt, setExpiresAt] = useState('');
+  const [allowedIps, setAllowedIps] = useState('');
+  const [graceHours, setGraceHours] = useState(24);
+
+  const fetchTokens = async () => {
+    const res = await api.accountTokens.list(token, accountId);
+    if (res.success && res.data) {
+      setTokens(res.data);
+    }
+  };
+
+  useEffect(() => {
+    fetchTokens();
+  }, [accountId]);
+
+  const toggleScope = (scope: AccountTokenScope) => {
+    setScopes(prev => prev.includes(scope) ? prev.filter(s => s !== scope) : [...prev, scope]);
+  };
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const res = await api.accountTokens.create(token, accountId, {
+      name,
+      scopes,
+      expires_at: expiresAt ? new Date(expiresAt).toISOString() : undefined,
+      allowed_ips: allowedIps.split(',').map(ip => ip.trim()).filter(Boolean),
+    });
+    if (res.success && res.data) {
+      setIssued(res.data);
+      setName('');
+      setExpiresAt('');
+      setAllowedIps('');
+      fetchTokens();
+    } else {
+      setError(res.error || 'Failed to create token');
+    }
+  };
+
+  const handleRotate = async (id: number) => {
+    setError(null);
+    const res = await api.accountTokens.rotate(token, accountId, id, graceHours * 60);
+    if (res.success && res.data) {
+      setIssued(res.data);
+      fetchTokens();
+    } else {
+      setError(res.error || 'Failed to rotate token');
+    }
+  };
+
+  const handleRevoke = async (id: number) => {
+    if (confirm('Revoke this token? Terminals using it will stop reporting immediately.')) {
+      const res = await api.accountTokens.revoke(token, accountId, id);
+      if (res.success) {
+        setTokens(tokens.filter(t => t.id !== id));
+      }
+    }
+  };
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-3xl max-h-[90vh] overflow-y-auto shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <Key className="w-5 h-5 text-primary" /> API Keys &middot; {accountName}
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        {issued?.token && (
+          <div className="mb-6 p-4 rounded-xl bg-amber-500/10 border border-amber-500/20">
+            <p className="text-sm text-amber-300 mb-2">Copy the key for "{issued.name}" now, it won't be shown again.</p>
+            <div className="flex items-center gap-2">
+              <code className="flex-1 font-mono text-xs text-white bg-slate-900 px-3 py-2 rounded-lg break-all">{issued.token}</code>
+              <button
+                onClick={() => navigator.clipboard.writeText(issued.token!)}
+                className="text-slate-400 hover:text-white transition-colors"
+              >
+                <Copy className="w-4 h-4" />
+              </button>
+            </div>
+          </div>
+        )}
+
+        <div className="space-y-3 mb-8">
+          {tokens.length === 0 && <p className="text-sm text-slate-500">No API keys yet.</p>}
+          {tokens.map(t => (
+            <div key={t.id} className="flex flex-col md:flex-row md:items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3">
+              <div className="min-w-0">
+                <div className="flex items-center gap-2">
+                  <span className="text-white font-medium truncate">{t.name}</span>
+                  <span className="font-mono text-xs text-slate-500">{t.token_prefix}…</span>
+                </div>
+                <div className="text-xs text-slate-500 mt-1 flex flex-wrap gap-x-3">
+                  <span>{t.scopes.join(', ')}</span>
+                  {t.expires_at && <span>Expires {new Date(t.expires_at).toLocaleString()}</span>}
+                  {t.allowed_ips && t.allowed_ips.length > 0 && <span>IPs {t.allowed_ips.join(', ')}</span>}
+                  <span>
+                    {t.last_used_at ? `Last used ${new Date(t.last_used_at).toLocaleString()} from ${t.last_used_ip}` : 'Never used'}
+                  </span>
+                </div>
+              </div>
+              <div className="flex items-center gap-2 shrink-0">
+                <button
+                  onClick={() => handleRotate(t.id)}
+                  className="flex items-center gap-1 text-xs text-primary hover:text-indigo-300 transition-colors font-medium"
+                >
+                  <RefreshCw className="w-3 h-3" /> Rotate
+                </button>
+                <button
+                  onClick={() => handleRevoke(t.id)}
+                  className="flex items-center gap-1 text-xs text-red-400 hover:text-red-300 transition-colors font-medium"
+                >
+                  <Trash2 className="w-3 h-3" /> Revoke
+                </button>
+              </div>
+            </div>
+          ))}
+          <div className="flex items-center gap-2 text-xs text-slate-500">
+            <span>Rotated keys keep working for</span>
+            <input
+              type="number"
+              min={0}
+              max={168}
+              value={graceHours}
+              onChange={(e) => setGraceHours(Number(e.target.value))}
+              className="w-16 bg-slate-900 border border-slate-700 rounded-lg px-2 py-1 text-white"
+            />
+            <span>hours</span>
+          </div>
+        </div>
+
+        <form onSubmit={handleCreate} className="space-y-4 border-t border-white/5 pt-6">
+          <h4 className="text-xs font-bold text-slate-400 uppercase tracking-widest">New Key</h4>
+          <input
+            type="text"
+            className="w-full bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent transition-all"
+            placeholder="e.g. VPS terminal 2"
+            value={name}
+            onChange={(e) => setName(e.target.value)}
+            maxLength={100}
+            required
+          />
+          <div className="flex flex-wrap gap-3">
+            {SCOPES.map(scope => (
+              <label key={scope.value} className="flex items-center gap-2 text-sm text-slate-300">
+                <input type="checkbox" checked={scopes.includes(scope.value)} onChange={() => toggleScope(scope.value)} />
+                {scope.label}
+              </label>
+            ))}
+          </div>
+          <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+            <input
+              type="datetime-local"
+              className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white"
+              value={expiresAt}
+              onChange={(e) => setExpiresAt(e.target.value)}
+              title="Optional expiry"
+            />
+            <input
+              type="text"
+              className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white"
+              placeholder="Allowed IPs, e.g. 203.0.113.7, 10.0.0.0/8"
+              value={allowedIps}
+              onChange={(e) => setAllowedIps(e.target.value)}
+            />
+          </div>
+          <div className="flex justify-end">
+            <button
+              type="submit"
+              disabled={scopes.length === 0}
+              className="flex items-center gap-2 bg-primary hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-bold transition-all shadow-lg shadow-primary/25 disabled:opacity-50"
+            >
+              <Plus className="w-4 h-4" /> Create Key
+            </button>
+          </div>
+        </form>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
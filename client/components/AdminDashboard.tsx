@@ -2,15 +2,20 @@ import * as React from 'react';
 import { useState, useEffect, useMemo } from 'react';
 import { 
     LayoutDashboard, Users, Activity, Settings, LogOut, Shield, 
-    Search, Wallet, RefreshCw, Plus, Edit2, Trash2, X, Check, Key,
-    BarChart3, ChevronLeft, DollarSign, TrendingUp, TrendingDown
+    Search, Wallet, RefreshCw, Plus, Edit2, Trash2, X, Check,
+    BarChart3, ChevronLeft, DollarSign, TrendingUp, TrendingDown, ScrollText,
+    PauseCircle, UserX, UserCheck, Ticket, Eye
 } from 'lucide-react';
 import { 
     LineChart, Line, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer,
     AreaChart, Area
 } from 'recharts';
-import { User, Account, Statistic, TodaySummary, OverallSummary } from '../types';
+import { User, UserStatus, Account, Statistic, TodaySummary, OverallSummary, Role, ImpersonationResponse } from '../types';
 import { api } from '../services/api';
+import { hasPermission } from '../services/permissions';
+import { AuditLog } from './AuditLog';
+import { Invites } from './Invites';
+import { ImpersonateUser } from './ImpersonateUser';
 import { motion, AnimatePresence, useMotionValue, useTransform } from 'framer-motion';
 
 const MotionDiv = motion.div as any;
@@ -40,10 +45,11 @@ interface AdminDashboardProps {
   user: User;
   token: string;
   onLogout: () => void;
+  onImpersonate: (data: ImpersonationResponse) => void;
 }
 
-export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onLogout }) => {
-  const [activeTab, setActiveTab] = useState<'users' | 'accounts'>('users');
+export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onLogout, onImpersonate }) => {
+  const [activeTab, setActiveTab] = useState<'users' | 'accounts' | 'invites' | 'audit'>('users');
   const [users, setUsers] = useState<User[]>([]);
   const [accounts, setAccounts] = useState<Account[]>([]);
   const [loading, setLoading] = useState(false);
@@ -63,8 +69,22 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
   const [formData, setFormData] = useState({
       username: '',
       password: '',
-      role: 'user' as 'user' | 'admin'
+      role: 'user'
   });
+  const [roles, setRoles] = useState<Role[]>([]);
+  const canManageUsers = hasPermission(user, 'users:manage');
+  const canSeeAllAccounts = hasPermission(user, 'accounts:manage_all');
+  const canImpersonate = hasPermission(user, 'users:impersonate');
+  // User an admin is about to view the app as
+  const [impersonateTarget, setImpersonateTarget] = useState<User | null>(null);
+
+  useEffect(() => {
+    if (hasPermission(user, 'roles:manage')) {
+      api.roles.getAll(token).then(res => {
+        if (res.success && res.data) setRoles(res.data);
+      });
+    }
+  }, []);
 
   useEffect(() => {
     loadData();
@@ -137,7 +157,7 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
   };
 
   const handleDeleteUser = async (id: number) => {
-      if(confirm('Are you sure you want to delete this user? This action cannot be undone.')) {
+      if(confirm('Are you sure you want to delete this user? Their personal accounts and tokens are deleted too. This action cannot be undone.')) {
           const res = await api.users.delete(token, id);
           if (res.success) {
               loadData();
@@ -147,6 +167,22 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
       }
   };
 
+  const handleSetStatus = async (user: User, status: UserStatus) => {
+      const prompts: Record<UserStatus, string> = {
+          active: `Reactivate ${user.username}?`,
+          suspended: `Suspend ${user.username}? They are logged out and their tokens and personal accounts stop working until reactivated.`,
+          deactivated: `Deactivate ${user.username}? They are logged out and their tokens and personal accounts stop working.`,
+      };
+      if (confirm(prompts[status])) {
+          const res = await api.users.setStatus(token, user.id, status);
+          if (res.success) {
+              loadData();
+          } else {
+              setError(res.error || 'Failed to update user status');
+          }
+      }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
       e.preventDefault();
       try {
@@ -201,12 +237,30 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
             >
                 <Users className="w-4 h-4" /> User Management
             </button>
+            {canSeeAllAccounts && (
             <button 
                 onClick={() => { setActiveTab('accounts'); setSelectedAccount(null); }} 
                 className={`w-full flex items-center gap-3 px-6 py-3 text-sm font-medium transition-colors ${activeTab === 'accounts' || selectedAccount ? 'text-white bg-indigo-500/10 border-r-2 border-indigo-500' : 'text-slate-400 hover:text-white hover:bg-white/5'}`}
             >
                 <Wallet className="w-4 h-4" /> All Accounts
             </button>
+            )}
+            {canManageUsers && (
+            <button 
+                onClick={() => { setActiveTab('invites'); setSelectedAccount(null); }} 
+                className={`w-full flex items-center gap-3 px-6 py-3 text-sm font-medium transition-colors ${activeTab === 'invites' && !selectedAccount ? 'text-white bg-indigo-500/10 border-r-2 border-indigo-500' : 'text-slate-400 hover:text-white hover:bg-white/5'}`}
+            >
+                <Ticket className="w-4 h-4" /> Invites
+            </button>
+            )}
+            {hasPermission(user, 'audit:read') && (
+            <button 
+                onClick={() => { setActiveTab('audit'); setSelectedAccount(null); }} 
+                className={`w-full flex items-center gap-3 px-6 py-3 text-sm font-medium transition-colors ${activeTab === 'audit' && !selectedAccount ? 'text-white bg-indigo-500/10 border-r-2 border-indigo-500' : 'text-slate-400 hover:text-white hover:bg-white/5'}`}
+            >
+                <ScrollText className="w-4 h-4" /> Audit Log
+            </button>
+            )}
         </div>
 
         <div className="p-4 border-t border-indigo-900/20 flex-shrink-0">
@@ -232,7 +286,9 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
         <header className="bg-[#0a0f1c]/50 backdrop-blur-sm border-b border-indigo-900/20 px-8 py-5 flex justify-between items-center flex-shrink-0 z-20">
             <h1 className="text-xl font-bold text-white">
                 {selectedAccount ? `Analytics: ${selectedAccount.name}` : 
-                 activeTab === 'users' ? 'User Management' : 'Global Account List'}
+                 activeTab === 'users' ? 'User Management' :
+                 activeTab === 'invites' ? 'Invites' :
+                 activeTab === 'audit' ? 'Audit Log' : 'Global Account List'}
             </h1>
             <div className="flex items-center gap-4">
                  <button onClick={() => selectedAccount ? handleViewStats(selectedAccount) : loadData()} className="p-2 bg-slate-800 rounded-full text-slate-400 hover:text-white transition-colors">
@@ -402,6 +458,10 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                         </div>
                     </div>
                 </div>
+            ) : activeTab === 'invites' ? (
+                <Invites token={token} roles={roles} />
+            ) : activeTab === 'audit' ? (
+                <AuditLog token={token} />
             ) : activeTab === 'users' ? (
                 // --- USERS LIST ---
                 <MotionDiv initial={{ opacity: 0, y: 10 }} animate={{ opacity: 1, y: 0 }} className="bg-[#0a0f1c] rounded-2xl border border-indigo-900/20 overflow-hidden">
@@ -410,12 +470,14 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                             <h2 className="text-lg font-bold text-white">Registered Users</h2>
                             <p className="text-xs text-slate-500 mt-1">Manage platform access</p>
                         </div>
+                        {canManageUsers && (
                         <button 
                             onClick={handleOpenCreate}
                             className="bg-indigo-600 hover:bg-indigo-500 text-white px-4 py-2 rounded-lg text-sm font-bold flex items-center gap-2 transition-colors"
                         >
                             <Plus className="w-4 h-4" /> Add User
                         </button>
+                        )}
                     </div>
                     <div className="overflow-x-auto">
                         <table className="w-full text-left text-sm text-slate-400">
@@ -424,15 +486,16 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                     <th className="px-6 py-4">ID</th>
                                     <th className="px-6 py-4">Username</th>
                                     <th className="px-6 py-4">Role</th>
+                                    <th className="px-6 py-4">Status</th>
                                     <th className="px-6 py-4">Created At</th>
                                     <th className="px-6 py-4 text-right">Actions</th>
                                 </tr>
                             </thead>
                             <tbody className="divide-y divide-indigo-900/20">
                                 {loading ? (
-                                    <tr><td colSpan={5} className="px-6 py-8 text-center">Loading users...</td></tr>
+                                    <tr><td colSpan={6} className="px-6 py-8 text-center">Loading users...</td></tr>
                                 ) : users.length === 0 ? (
-                                    <tr><td colSpan={5} className="px-6 py-8 text-center">No users found</td></tr>
+                                    <tr><td colSpan={6} className="px-6 py-8 text-center">No users found</td></tr>
                                 ) : users.map((u) => (
                                     <tr key={u.id} className="hover:bg-white/5 transition-colors">
                                         <td className="px-6 py-4 font-mono text-slate-500">#{u.id}</td>
@@ -447,8 +510,28 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                                 {u.role}
                                             </span>
                                         </td>
+                                        <td className="px-6 py-4">
+                                            <span className={`px-2 py-1 rounded-md text-xs font-bold uppercase ${
+                                                (u.status ?? 'active') === 'active' ? 'bg-emerald-500/10 text-emerald-400'
+                                                : u.status === 'suspended' ? 'bg-amber-500/10 text-amber-400'
+                                                : 'bg-slate-800 text-slate-500'
+                                            }`}>
+                                                {u.status ?? 'active'}
+                                            </span>
+                                        </td>
                                         <td className="px-6 py-4 text-slate-500">{new Date(u.created_at).toLocaleDateString()}</td>
                                         <td className="px-6 py-4 text-right flex items-center justify-end gap-2">
+                                            {canImpersonate && u.id !== user.id && (u.status ?? 'active') === 'active' && (
+                                            <button
+                                                onClick={() => setImpersonateTarget(u)}
+                                                className="p-2 text-slate-400 hover:text-amber-400 hover:bg-amber-500/10 rounded-lg transition-colors"
+                                                title="View as user"
+                                            >
+                                                <Eye className="w-4 h-4" />
+                                            </button>
+                                            )}
+                                            {canManageUsers && (
+                                            <>
                                             <button 
                                                 onClick={() => handleOpenEdit(u)}
                                                 className="p-2 text-slate-400 hover:text-indigo-400 hover:bg-indigo-500/10 rounded-lg transition-colors"
@@ -456,6 +539,32 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                             >
                                                 <Edit2 className="w-4 h-4" />
                                             </button>
+                                            {(u.status ?? 'active') === 'active' ? (
+                                            <>
+                                            <button
+                                                onClick={() => handleSetStatus(u, 'suspended')}
+                                                className="p-2 text-slate-400 hover:text-amber-400 hover:bg-amber-500/10 rounded-lg transition-colors"
+                                                title="Suspend"
+                                            >
+                                                <PauseCircle className="w-4 h-4" />
+                                            </button>
+                                            <button
+                                                onClick={() => handleSetStatus(u, 'deactivated')}
+                                                className="p-2 text-slate-400 hover:text-slate-200 hover:bg-white/5 rounded-lg transition-colors"
+                                                title="Deactivate"
+                                            >
+                                                <UserX className="w-4 h-4" />
+                                            </button>
+                                            </>
+                                            ) : (
+                                            <button
+                                                onClick={() => handleSetStatus(u, 'active')}
+                                                className="p-2 text-slate-400 hover:text-emerald-400 hover:bg-emerald-500/10 rounded-lg transition-colors"
+                                                title="Reactivate"
+                                            >
+                                                <UserCheck className="w-4 h-4" />
+                                            </button>
+                                            )}
                                             <button 
                                                 onClick={() => handleDeleteUser(u.id)}
                                                 className="p-2 text-slate-400 hover:text-red-400 hover:bg-red-500/10 rounded-lg transition-colors"
@@ -463,6 +572,8 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                             >
                                                 <Trash2 className="w-4 h-4" />
                                             </button>
+                                            </>
+                                            )}
                                         </td>
                                     </tr>
                                 ))}
@@ -487,7 +598,6 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                     <th className="px-6 py-4">ID</th>
                                     <th className="px-6 py-4">Account Name</th>
                                     <th className="px-6 py-4">Owner</th>
-                                    <th className="px-6 py-4">API Token</th>
                                     <th className="px-6 py-4">Created At</th>
                                     <th className="px-6 py-4 text-right">Actions</th>
                                 </tr>
@@ -513,12 +623,6 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                                                 <span className="text-slate-600 italic">Unknown (ID: {acc.user_id})</span>
                                             )}
                                         </td>
-                                        <td className="px-6 py-4">
-                                            <div className="flex items-center gap-1 text-xs font-mono bg-slate-900 px-2 py-1 rounded w-fit border border-slate-800">
-                                                <Key className="w-3 h-3 text-slate-600" />
-                                                <span className="text-slate-500 truncate max-w-[100px]">{acc.api_token}</span>
-                                            </div>
-                                        </td>
                                         <td className="px-6 py-4 text-slate-500">{new Date(acc.created_at).toLocaleDateString()}</td>
                                         <td className="px-6 py-4 text-right">
                                             <button 
@@ -593,11 +697,18 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                             <label className="block text-xs font-bold text-slate-500 uppercase tracking-widest mb-2">Role</label>
                             <select 
                                 value={formData.role}
-                                onChange={e => setFormData({...formData, role: e.target.value as 'user' | 'admin'})}
+                                onChange={e => setFormData({...formData, role: e.target.value})}
                                 className="w-full bg-[#02040a] border border-slate-800 rounded-lg px-4 py-3 text-white focus:outline-none focus:border-indigo-500 transition-colors appearance-none"
                             >
-                                <option value="user">User</option>
-                                <option value="admin">Admin</option>
+                                {roles.length === 0 && (
+                                    <>
+                                        <option value="user">User</option>
+                                        <option value="admin">Admin</option>
+                                    </>
+                                )}
+                                {roles.map(r => (
+                                    <option key={r.name} value={r.name}>{r.name}{r.description ? ` - ${r.description}` : ''}</option>
+                                ))}
                             </select>
                         </div>
                         
@@ -620,6 +731,14 @@ export const AdminDashboard: React.FC<AdminDashboardProps> = ({ user, token, onL
                 </MotionDiv>
             </MotionDiv>
         )}
+        {impersonateTarget && (
+            <ImpersonateUser
+                token={token}
+                target={impersonateTarget}
+                onStart={onImpersonate}
+                onClose={() => setImpersonateTarget(null)}
+            />
+        )}
       </AnimatePresence>
     </div>
   );
@@ -0,0 +1,181 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { ChevronLeft, ChevronRight, Download, Search } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { AuditEvent, AuditEventFilter, PaginationMeta } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+const PAGE_SIZE = 50;
+
+// Renders the changed fields of an event as "field: before → after"
+const describeChanges = (event: AuditEvent) => {
+  const fields = Array.from(new Set([...Object.keys(event.before ?? {}), ...Object.keys(event.after ?? {})]));
+  const show = (value: unknown) => (value === undefined ? '—' : JSON.stringify(value));
+  return fields.map(field => `${field}: ${show(event.before?.[field])} → ${show(event.after?.[field])}`);
+};
+
+interface AuditLogProps {
+  token: string;
+}
+
+// Filterable, paginated view of the audit log with CSV export
+export const AuditLog: React.FC<AuditLogProps> = ({ token }) => {
+  const [events, setEvents] = useState<AuditEvent[]>([]);
+  const [pagination, setPagination] = useState<PaginationMeta | null>(null);
+  const [page, setPage] = useState(1);
+  const [filter, setFilter] = useState<AuditEventFilter>({});
+  const [applied, setApplied] = useState<AuditEventFilter>({});
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const fetchEvents = async () => {
+    setLoading(true);
+    setError(null);
+    const res = await api.audit.getEvents(token, applied, page, PAGE_SIZE);
+    if (res.success && res.data) {
+      setEvents(res.data);
+      setPagination(res.pagination ?? null);
+    } else {
+      setError(res.error || 'Failed to load audit events');
+    }
+    setLoading(false);
+  };
+
+  useEffect(() => {
+    fetchEvents();
+  }, [page, applied]);
+
+  const handleSearch = (e: React.FormEvent) => {
+    e.preventDefault();
+    setPage(1);
+    setApplied(filter);
+  };
+
+  const handleExport = async () => {
+    const blob = await api.audit.exportCsv(token, applied);
+    if (!blob) {
+      setError('Failed to export audit events');
+      return;
+    }
+    const url = URL.createObjectURL(blob);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = 'audit-events.csv';
+    link.click();
+    URL.revokeObjectURL(url);
+  };
+
+  const inputClass = 'bg-[#02040a] border border-indigo-900/30 rounded-lg px-3 py-2 text-sm text-white focus:outline-none focus:border-indigo-500';
+  const field = (key: keyof AuditEventFilter, placeholder: string, type: string = 'text') => (
+    <input
+      type={type}
+      className={inputClass}
+      placeholder={placeholder}
+      title={placeholder}
+      value={filter[key] ?? ''}
+      onChange={(e) => setFilter({ ...filter, [key]: e.target.value })}
+    />
+  );
+
+  return (
+    <MotionDiv initial={{ opacity: 0, y: 10 }} animate={{ opacity: 1, y: 0 }} className="bg-[#0a0f1c] rounded-2xl border border-indigo-900/20 overflow-hidden">
+      <div className="p-6 border-b border-indigo-900/20 flex justify-between items-center">
+        <div>
+          <h2 className="text-lg font-bold text-white">Audit Log</h2>
+          <p className="text-xs text-slate-500 mt-1">Who did what, from where</p>
+        </div>
+        <button
+          onClick={handleExport}
+          className="bg-indigo-600 hover:bg-indigo-500 text-white px-4 py-2 rounded-lg text-sm font-bold flex items-center gap-2 transition-colors"
+        >
+          <Download className="w-4 h-4" /> Export CSV
+        </button>
+      </div>
+
+      <form onSubmit={handleSearch} className="p-6 border-b border-indigo-900/20 grid grid-cols-2 md:grid-cols-4 gap-3">
+        {field('action', 'Action, e.g. user.update')}
+        {field('target_type', 'Target type, e.g. account')}
+        {field('target_id', 'Target ID')}
+        {field('actor_id', 'Actor user ID')}
+        {field('impersonator_id', 'Impersonator user ID')}
+        {field('request_id', 'Request ID')}
+        {field('from', 'From', 'date')}
+        {field('to', 'To', 'date')}
+        <button type="submit" className="bg-slate-800 hover:bg-slate-700 text-white rounded-lg text-sm font-bold flex items-center justify-center gap-2 transition-colors">
+          <Search className="w-4 h-4" /> Filter
+        </button>
+      </form>
+
+      {error && (
+        <div className="m-6 bg-red-500/10 border border-red-500/20 text-red-400 p-4 rounded-xl">{error}</div>
+      )}
+
+      <div className="overflow-x-auto">
+        <table className="w-full text-left text-sm text-slate-400">
+          <thead className="bg-[#02040a] text-xs uppercase font-bold text-slate-500 tracking-wider">
+            <tr>
+              <th className="px-6 py-4">Time</th>
+              <th className="px-6 py-4">Actor</th>
+              <th className="px-6 py-4">Action</th>
+              <th className="px-6 py-4">Target</th>
+              <th className="px-6 py-4">Changes</th>
+              <th className="px-6 py-4">IP / Request</th>
+            </tr>
+          </thead>
+          <tbody className="divide-y divide-indigo-900/20">
+            {loading ? (
+              <tr><td colSpan={6} className="px-6 py-8 text-center">Loading audit events...</td></tr>
+            ) : events.length === 0 ? (
+              <tr><td colSpan={6} className="px-6 py-8 text-center">No audit events found</td></tr>
+            ) : events.map(event => (
+              <tr key={event.id} className="hover:bg-white/5 transition-colors align-top">
+                <td className="px-6 py-4 text-slate-500 whitespace-nowrap">{new Date(event.created_at).toLocaleString()}</td>
+                <td className="px-6 py-4 text-white">
+                  {event.actor_name || <span className="text-slate-600 italic">anonymous</span>}
+                  {event.actor_id !== null && <span className="font-mono text-xs text-slate-500"> #{event.actor_id}</span>}
+                  {event.impersonator_id && (
+                    <div className="text-xs text-amber-400">via {event.impersonator_name} #{event.impersonator_id}</div>
+                  )}
+                </td>
+                <td className="px-6 py-4 font-mono text-xs text-indigo-300">{event.action}</td>
+                <td className="px-6 py-4 font-mono text-xs">{event.target_type} #{event.target_id}</td>
+                <td className="px-6 py-4 font-mono text-xs max-w-md">
+                  {describeChanges(event).map(line => <div key={line} className="truncate" title={line}>{line}</div>)}
+                </td>
+                <td className="px-6 py-4 font-mono text-xs text-slate-500">
+                  <div>{event.ip}</div>
+                  <div className="truncate max-w-[10rem]" title={event.request_id}>{event.request_id}</div>
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      </div>
+
+      {pagination && (
+        <div className="p-4 border-t border-indigo-900/20 flex items-center justify-between text-xs text-slate-500">
+          <span>Total Events: {pagination.total_items}</span>
+          <div className="flex items-center gap-3">
+            <button
+              onClick={() => setPage(page - 1)}
+              disabled={page <= 1}
+              className="p-1 rounded hover:text-white disabled:opacity-30"
+            >
+              <ChevronLeft className="w-4 h-4" />
+            </button>
+            <span>Page {pagination.page} of {Math.max(pagination.total_pages, 1)}</span>
+            <button
+              onClick={() => setPage(page + 1)}
+              disabled={page >= pagination.total_pages}
+              className="p-1 rounded hover:text-white disabled:opacity-30"
+            >
+              <ChevronRight className="w-4 h-4" />
+            </button>
+          </div>
+        </div>
+      )}
+    </MotionDiv>
+  );
+};
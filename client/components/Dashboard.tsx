@@ -8,11 +8,17 @@ import {
   LayoutDashboard, Wallet, LogOut, Plus, RefreshCw, Trash2,
   TrendingUp, TrendingDown, DollarSign, Activity, Key, Eye, EyeOff,
   Menu, X, Users, ChevronRight, User as UserIcon, Calendar as CalendarIcon, Filter,
-  ChevronLeft
+  ChevronLeft, Share2, Building2, KeyRound
 } from 'lucide-react';
 import { motion, AnimatePresence, useMotionValue, useTransform } from 'framer-motion';
 import { Account, Statistic, TodaySummary, User, OverallSummary } from '../types';
 import { api } from '../services/api';
+import { hasPermission } from '../services/permissions';
+import { AccountTokens } from './AccountTokens';
+import { ShareLinks } from './ShareLinks';
+import { AccountMembers } from './AccountMembers';
+import { Organizations } from './Organizations';
+import { PersonalAccessTokens } from './PersonalAccessTokens';
 
 const MotionDiv = motion.div as any;
 const MotionTr = motion.tr as any;
@@ -160,6 +166,13 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
   // UI State
   const [refreshing, setRefreshing] = useState(false);
   const [showApiToken, setShowApiToken] = useState<Record<number, boolean>>({});
+  const [tokensModalOpen, setTokensModalOpen] = useState(false);
+  const [shareModalOpen, setShareModalOpen] = useState(false);
+  const [membersModalOpen, setMembersModalOpen] = useState(false);
+  const [orgsModalOpen, setOrgsModalOpen] = useState(false);
+  const [patModalOpen, setPatModalOpen] = useState(false);
+  // Accounts other users granted us access to; access_role says what we may do
+  const [sharedAccounts, setSharedAccounts] = useState<Account[]>([]);
   const [filterRange, setFilterRange] = useState<FilterRange>('all');
 
   useEffect(() => {
@@ -178,11 +191,17 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
 
   const fetchAccounts = async () => {
     setLoading(true);
-    const res = await api.accounts.getMyAccounts(token);
+    const [res, sharedRes] = await Promise.all([
+      api.accounts.getMyAccounts(token),
+      api.accounts.getShared(token),
+    ]);
+    const shared = sharedRes.success && sharedRes.data ? sharedRes.data : [];
+    setSharedAccounts(shared);
     if (res.success && res.data) {
       setAccounts(res.data);
-      if (res.data.length > 0 && !selectedAccountId) {
-        setSelectedAccountId(res.data[0].id);
+      const first = res.data[0] ?? shared[0];
+      if (first && !selectedAccountId) {
+        setSelectedAccountId(first.id);
       }
     }
     setLoading(false);
@@ -230,6 +249,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
     if (res.success && res.data) {
       setAccounts([...accounts, res.data]);
       setSelectedAccountId(res.data.id);
+      setShowApiToken(prev => ({...prev, [res.data!.id]: true}));
       setCreateModalOpen(false);
       setNewAccountName('');
     }
@@ -248,17 +268,14 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
     }
   };
 
-  const handleRegenerateToken = async (id: number) => {
-      const res = await api.accounts.regenerateToken(token, id);
-      if(res.success && res.data) {
-          setAccounts(accounts.map(acc => acc.id === id ? res.data! : acc));
-      }
-  }
-
   const selectedAccount = useMemo(() => 
-    accounts.find(a => a.id === selectedAccountId), 
-    [accounts, selectedAccountId]
+    accounts.find(a => a.id === selectedAccountId) ?? sharedAccounts.find(a => a.id === selectedAccountId), 
+    [accounts, sharedAccounts, selectedAccountId]
   );
+  const isOwner = !selectedAccount?.access_role;
+  const canWrite = hasPermission(user, 'accounts:write');
+  const canManage = canWrite && (isOwner || selectedAccount?.access_role === 'manager');
+  const canDelete = isOwner && hasPermission(user, 'accounts:delete');
 
   // --- Logic for Unique Daily Stats (Last record per day) ---
   const dailyStatsMap = useMemo(() => {
@@ -375,6 +392,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                     </button>
                 ))}
                 
+                {canWrite && (
                 <button
                     onClick={() => {
                         setCreateModalOpen(true);
@@ -385,8 +403,62 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                     <Plus className="w-4 h-4 group-hover:scale-110 transition-transform" />
                     <span>New Account</span>
                 </button>
+                )}
+                </div>
+            </div>
+
+            {sharedAccounts.length > 0 && (
+            <div>
+                <div className="flex items-center justify-between text-xs font-bold text-slate-500 uppercase tracking-widest mb-3 px-2">
+                    <span>Shared With Me</span>
+                    <span className="bg-slate-800 text-slate-300 px-1.5 py-0.5 rounded-md text-[10px]">{sharedAccounts.length}</span>
+                </div>
+                <div className="space-y-1">
+                {sharedAccounts.map(account => (
+                    <button
+                    key={account.id}
+                    onClick={() => {
+                        setSelectedAccountId(account.id);
+                        setSidebarOpen(false);
+                    }}
+                    className={`w-full flex items-center justify-between px-3 py-3 rounded-xl transition-all duration-200 text-sm font-medium border ${
+                        selectedAccountId === account.id
+                        ? 'bg-primary/10 text-primary border-primary/20'
+                        : 'text-slate-400 border-transparent hover:bg-white/5 hover:text-white'
+                    }`}
+                    >
+                        <div className="flex items-center gap-3 min-w-0">
+                            <Users className="w-4 h-4 shrink-0" />
+                            <span className="truncate">{account.name}</span>
+                        </div>
+                        <span className="text-[10px] uppercase text-slate-500">{account.access_role}</span>
+                    </button>
+                ))}
                 </div>
             </div>
+            )}
+
+            <button
+                onClick={() => {
+                    setOrgsModalOpen(true);
+                    setSidebarOpen(false);
+                }}
+                className="w-full flex items-center gap-3 px-3 py-3 rounded-xl text-slate-400 hover:text-white hover:bg-white/5 transition-colors text-sm font-medium"
+            >
+                <Building2 className="w-4 h-4" />
+                <span>Organizations</span>
+            </button>
+
+            <button
+                onClick={() => {
+                    setPatModalOpen(true);
+                    setSidebarOpen(false);
+                }}
+                className="w-full flex items-center gap-3 px-3 py-3 rounded-xl text-slate-400 hover:text-white hover:bg-white/5 transition-colors text-sm font-medium"
+            >
+                <KeyRound className="w-4 h-4" />
+                <span>Access Tokens</span>
+            </button>
         </div>
 
         <div className="p-4 border-t border-white/5 flex-shrink-0">
@@ -424,6 +496,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                     <p className="text-slate-400 mb-8 leading-relaxed">
                         Create your first trading portfolio to begin tracking real-time analytics.
                     </p>
+                    {canWrite && (
                     <button 
                         onClick={() => setCreateModalOpen(true)}
                         className="bg-primary hover:bg-indigo-500 text-white px-8 py-4 rounded-xl font-bold flex items-center gap-3 transition-all transform hover:-translate-y-1"
@@ -431,6 +504,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                         <Plus className="w-5 h-5" />
                         Create Portfolio
                     </button>
+                    )}
                 </div>
             ) : (
                 <MotionDiv initial={{ opacity: 0 }} animate={{ opacity: 1 }} transition={{ duration: 0.5 }}>
@@ -441,24 +515,51 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                                 {selectedAccount?.name}
                             </h1>
                             <div className="flex flex-wrap items-center gap-3 text-sm text-slate-400">
-                                <div className="flex items-center gap-2 bg-surfaceLight/50 px-3 py-1.5 rounded-lg border border-white/5">
-                                    <Key className="w-3 h-3 text-primary" /> 
-                                    <span className="font-mono text-xs">
-                                        {showApiToken[selectedAccountId!] ? selectedAccount?.api_token : '••••••••••••••••••••••••'}
-                                    </span>
-                                    <button 
-                                        onClick={() => setShowApiToken(prev => ({...prev, [selectedAccountId!]: !prev[selectedAccountId!]}))}
-                                        className="text-slate-500 hover:text-white transition-colors ml-1"
-                                    >
-                                        {showApiToken[selectedAccountId!] ? <EyeOff className="w-3 h-3"/> : <Eye className="w-3 h-3"/>}
-                                    </button>
-                                </div>
+                                {/* The initial key is only known right after the account was created */}
+                                {selectedAccount?.api_token && (
+                                    <div className="flex items-center gap-2 bg-surfaceLight/50 px-3 py-1.5 rounded-lg border border-white/5">
+                                        <Key className="w-3 h-3 text-primary" /> 
+                                        <span className="font-mono text-xs">
+                                            {showApiToken[selectedAccountId!] ? selectedAccount.api_token : '••••••••••••••••••••••••'}
+                                        </span>
+                                        <button 
+                                            onClick={() => setShowApiToken(prev => ({...prev, [selectedAccountId!]: !prev[selectedAccountId!]}))}
+                                            className="text-slate-500 hover:text-white transition-colors ml-1"
+                                        >
+                                            {showApiToken[selectedAccountId!] ? <EyeOff className="w-3 h-3"/> : <Eye className="w-3 h-3"/>}
+                                        </button>
+                                        <span className="text-xs text-amber-400 ml-1">Copy this key now, it won't be shown again</span>
+                                    </div>
+                                )}
+                                {canManage && (
                                 <button 
-                                    onClick={() => handleRegenerateToken(selectedAccountId!)}
-                                    className="text-xs text-primary hover:text-indigo-300 transition-colors font-medium"
+                                    onClick={() => setTokensModalOpen(true)}
+                                    className="flex items-center gap-1 text-xs text-primary hover:text-indigo-300 transition-colors font-medium"
                                 >
-                                    Rotate Key
+                                    <Key className="w-3 h-3" /> Manage API Keys
                                 </button>
+                                )}
+                                {isOwner && canWrite && (
+                                <>
+                                <button 
+                                    onClick={() => setShareModalOpen(true)}
+                                    className="flex items-center gap-1 text-xs text-primary hover:text-indigo-300 transition-colors font-medium"
+                                >
+                                    <Share2 className="w-3 h-3" /> Share
+                                </button>
+                                <button 
+                                    onClick={() => setMembersModalOpen(true)}
+                                    className="flex items-center gap-1 text-xs text-primary hover:text-indigo-300 transition-colors font-medium"
+                                >
+                                    <Users className="w-3 h-3" /> Members
+                                </button>
+                                </>
+                                )}
+                                {!isOwner && (
+                                    <span className="text-xs text-slate-500">
+                                        Shared by {selectedAccount?.user?.username ?? 'another user'} &middot; {selectedAccount?.access_role}
+                                    </span>
+                                )}
                             </div>
                         </div>
                         
@@ -484,6 +585,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                                 </button>
                             </div>
 
+                            {canDelete && (
                              <button 
                                  onClick={() => handleDeleteAccount(selectedAccountId!)}
                                  className="p-3 text-slate-400 hover:text-white hover:bg-red-500/20 rounded-xl transition-all border border-transparent hover:border-red-500/20"
@@ -491,6 +593,7 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
                             >
                                 <Trash2 className="w-5 h-5" />
                             </button>
+                            )}
                             <button 
                                 onClick={() => selectedAccountId && fetchStats(selectedAccountId, filterRange)}
                                 className={`flex items-center gap-2 px-4 py-3 bg-white/5 hover:bg-white/10 text-white rounded-xl transition-all border border-white/5 font-medium ${refreshing ? 'opacity-70' : ''}`}
@@ -713,6 +816,44 @@ export const Dashboard: React.FC<DashboardProps> = ({ user, token, onLogout }) =
             </MotionDiv>
         </MotionDiv>
       )}
+      {tokensModalOpen && selectedAccount && (
+        <AccountTokens
+            token={token}
+            accountId={selectedAccount.id}
+            accountName={selectedAccount.name}
+            onClose={() => setTokensModalOpen(false)}
+        />
+      )}
+      {shareModalOpen && selectedAccount && (
+        <ShareLinks
+            token={token}
+            accountId={selectedAccount.id}
+            accountName={selectedAccount.name}
+            onClose={() => setShareModalOpen(false)}
+        />
+      )}
+      {membersModalOpen && selectedAccount && (
+        <AccountMembers
+            token={token}
+            accountId={selectedAccount.id}
+            accountName={selectedAccount.name}
+            onClose={() => setMembersModalOpen(false)}
+        />
+      )}
+      {orgsModalOpen && (
+        <Organizations
+            token={token}
+            onAccountCreated={fetchAccounts}
+            onClose={() => setOrgsModalOpen(false)}
+        />
+      )}
+      {patModalOpen && (
+        <PersonalAccessTokens
+            token={token}
+            user={user}
+            onClose={() => setPatModalOpen(false)}
+        />
+      )}
       </AnimatePresence>
     </div>
   );
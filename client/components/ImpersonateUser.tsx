@@ -0,0 +1,115 @@
+import * as React from 'react';
+import { useState } from 'react';
+import { Eye, X } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { ImpersonationResponse, User } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+// Durations offered, in minutes; the server allows at most an hour
+const DURATIONS = [15, 30, 60];
+
+interface ImpersonateUserProps {
+  token: string;
+  target: User;
+  onStart: (data: ImpersonationResponse) => void;
+  onClose: () => void;
+}
+
+// Asks for a reason before starting to see the app as another user
+export const ImpersonateUser: React.FC<ImpersonateUserProps> = ({ token, target, onStart, onClose }) => {
+  const [reason, setReason] = useState('');
+  const [minutes, setMinutes] = useState(DURATIONS[0]);
+  const [allowWrite, setAllowWrite] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    setLoading(true);
+    const res = await api.users.impersonate(token, target.id, { reason, minutes, allow_write: allowWrite });
+    setLoading(false);
+    if (res.success && res.data) {
+      onStart(res.data);
+    } else {
+      setError(res.error || 'Failed to start impersonation');
+    }
+  };
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-md shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <Eye className="w-5 h-5 text-amber-400" /> View as {target.username}
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        <p className="text-sm text-slate-400 mb-6">
+          You'll see the app exactly as {target.username} does. Every request you make is recorded in the audit log.
+        </p>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        <form onSubmit={handleSubmit} className="space-y-4">
+          <div>
+            <label className="block text-xs font-bold text-slate-500 uppercase tracking-widest mb-2">Reason</label>
+            <input
+              type="text"
+              value={reason}
+              onChange={e => setReason(e.target.value)}
+              className="w-full bg-[#02040a] border border-slate-800 rounded-lg px-4 py-3 text-white focus:outline-none focus:border-indigo-500 transition-colors"
+              placeholder="e.g. Ticket #123, dashboard shows wrong balance"
+              maxLength={255}
+              required
+            />
+          </div>
+          <div>
+            <label className="block text-xs font-bold text-slate-500 uppercase tracking-widest mb-2">Duration</label>
+            <select
+              value={minutes}
+              onChange={e => setMinutes(Number(e.target.value))}
+              className="w-full bg-[#02040a] border border-slate-800 rounded-lg px-4 py-3 text-white focus:outline-none focus:border-indigo-500 transition-colors appearance-none"
+            >
+              {DURATIONS.map(d => <option key={d} value={d}>{d} minutes</option>)}
+            </select>
+          </div>
+          <label className="flex items-center gap-3 text-sm text-slate-300">
+            <input type="checkbox" checked={allowWrite} onChange={e => setAllowWrite(e.target.checked)} />
+            Allow changes on their behalf
+          </label>
+
+          <div className="flex justify-end gap-3 mt-6">
+            <button type="button" onClick={onClose} className="px-4 py-2 text-slate-400 hover:text-white font-medium text-sm">
+              Cancel
+            </button>
+            <button
+              type="submit"
+              disabled={loading}
+              className="px-6 py-2 bg-amber-500 hover:bg-amber-400 text-slate-950 rounded-lg font-bold text-sm transition-colors disabled:opacity-50"
+            >
+              Start Viewing
+            </button>
+          </div>
+        </form>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
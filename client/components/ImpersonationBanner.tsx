@@ -0,0 +1,37 @@
+import * as React from 'react';
+import { useEffect } from 'react';
+import { Eye, LogOut } from 'lucide-react';
+import { ImpersonationSession, User } from '../types';
+
+interface ImpersonationBannerProps {
+  user: User;
+  impersonation: ImpersonationSession;
+  onStop: () => void;
+}
+
+// Always-visible reminder that an admin is seeing the app as another user
+export const ImpersonationBanner: React.FC<ImpersonationBannerProps> = ({ user, impersonation, onStop }) => {
+  // The token can't be refreshed, so return to the admin's own login when it expires
+  useEffect(() => {
+    const remaining = new Date(impersonation.expires_at).getTime() - Date.now();
+    const timer = setTimeout(onStop, Math.max(remaining, 0));
+    return () => clearTimeout(timer);
+  }, [impersonation.expires_at]);
+
+  return (
+    <div className="fixed top-0 inset-x-0 z-[100] h-10 bg-amber-500 text-slate-950 text-sm font-bold flex items-center justify-center gap-4 px-4 shadow-lg">
+      <Eye className="w-4 h-4 shrink-0" />
+      <span className="truncate">
+        {impersonation.impersonator} viewing as {user.username}
+        {impersonation.read_only ? ' (read-only)' : ' (changes allowed)'}
+        {' '}&bull; ends {new Date(impersonation.expires_at).toLocaleTimeString()}
+      </span>
+      <button
+        onClick={onStop}
+        className="flex items-center gap-1 px-3 py-1 rounded-md bg-slate-950/15 hover:bg-slate-950/25 transition-colors shrink-0"
+      >
+        <LogOut className="w-3 h-3" /> Stop
+      </button>
+    </div>
+  );
+};
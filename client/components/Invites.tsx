@@ -0,0 +1,216 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { Ban, Copy, Plus } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { Invite, Role } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+// Default expiry offered for a new invite
+const DEFAULT_EXPIRY_DAYS = 7;
+
+const toDateInput = (date: Date) => date.toISOString().slice(0, 10);
+
+const inviteState = (invite: Invite) => {
+  if (invite.revoked_at) return { label: 'Revoked', className: 'bg-red-500/10 text-red-400 border-red-500/20' };
+  if (new Date(invite.expires_at) <= new Date()) return { label: 'Expired', className: 'bg-slate-500/10 text-slate-400 border-slate-500/20' };
+  if (invite.use_count >= invite.max_uses) return { label: 'Used up', className: 'bg-slate-500/10 text-slate-400 border-slate-500/20' };
+  return { label: 'Active', className: 'bg-green-500/10 text-green-400 border-green-500/20' };
+};
+
+interface InvitesProps {
+  token: string;
+  roles: Role[];
+}
+
+// Creates and revokes registration invites and shows who registered through each
+export const Invites: React.FC<InvitesProps> = ({ token, roles }) => {
+  const [invites, setInvites] = useState<Invite[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  // Link of an invite that was just created; it can't be fetched again
+  const [issued, setIssued] = useState<Invite | null>(null);
+
+  const [label, setLabel] = useState('');
+  const [role, setRole] = useState('user');
+  const [maxUses, setMaxUses] = useState(1);
+  const [expiresOn, setExpiresOn] = useState(toDateInput(new Date(Date.now() + DEFAULT_EXPIRY_DAYS * 86400000)));
+
+  const fetchInvites = async () => {
+    setLoading(true);
+    const res = await api.invites.getAll(token);
+    if (res.success && res.data) {
+      setInvites(res.data);
+    } else {
+      setError(res.error || 'Failed to load invites');
+    }
+    setLoading(false);
+  };
+
+  useEffect(() => {
+    fetchInvites();
+  }, []);
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const res = await api.invites.create(token, {
+      label,
+      role,
+      max_uses: maxUses,
+      expires_at: new Date(`${expiresOn}T23:59:59`).toISOString(),
+    });
+    if (res.success && res.data) {
+      setIssued(res.data);
+      setLabel('');
+      fetchInvites();
+    } else {
+      setError(res.error || 'Failed to create invite');
+    }
+  };
+
+  const handleRevoke = async (id: number) => {
+    if (confirm('Revoke this invite? Nobody will be able to register with it anymore.')) {
+      const res = await api.invites.revoke(token, id);
+      if (res.success) {
+        fetchInvites();
+      } else {
+        setError(res.error || 'Failed to revoke invite');
+      }
+    }
+  };
+
+  const inputClass = 'bg-[#02040a] border border-indigo-900/30 rounded-lg px-3 py-2 text-sm text-white focus:outline-none focus:border-indigo-500';
+
+  return (
+    <MotionDiv initial={{ opacity: 0, y: 10 }} animate={{ opacity: 1, y: 0 }} className="bg-[#0a0f1c] rounded-2xl border border-indigo-900/20 overflow-hidden">
+      <div className="p-6 border-b border-indigo-900/20">
+        <h2 className="text-lg font-bold text-white">Invites</h2>
+        <p className="text-xs text-slate-500 mt-1">Let people register themselves with a preset role</p>
+      </div>
+
+      <form onSubmit={handleCreate} className="p-6 border-b border-indigo-900/20 grid grid-cols-2 md:grid-cols-5 gap-3">
+        <input
+          type="text"
+          className={inputClass}
+          placeholder="Label, e.g. Q3 analysts"
+          value={label}
+          maxLength={100}
+          onChange={(e) => setLabel(e.target.value)}
+        />
+        <select value={role} onChange={(e) => setRole(e.target.value)} className={`${inputClass} appearance-none`} title="Role">
+          {roles.length === 0 && (
+            <>
+              <option value="user">User</option>
+              <option value="admin">Admin</option>
+            </>
+          )}
+          {roles.map(r => (
+            <option key={r.name} value={r.name}>{r.name}</option>
+          ))}
+        </select>
+        <input
+          type="number"
+          className={inputClass}
+          title="Usage limit"
+          min={1}
+          value={maxUses}
+          onChange={(e) => setMaxUses(Number(e.target.value))}
+          required
+        />
+        <input
+          type="date"
+          className={inputClass}
+          title="Expires on"
+          value={expiresOn}
+          min={toDateInput(new Date())}
+          onChange={(e) => setExpiresOn(e.target.value)}
+          required
+        />
+        <button type="submit" className="bg-indigo-600 hover:bg-indigo-500 text-white rounded-lg text-sm font-bold flex items-center justify-center gap-2 transition-colors">
+          <Plus className="w-4 h-4" /> Create Invite
+        </button>
+      </form>
+
+      {error && (
+        <div className="m-6 bg-red-500/10 border border-red-500/20 text-red-400 p-4 rounded-xl">{error}</div>
+      )}
+
+      {issued?.url && (
+        <div className="m-6 p-4 rounded-xl bg-amber-500/10 border border-amber-500/20">
+          <p className="text-sm text-amber-300 mb-2">Share this link{issued.label ? ` for "${issued.label}"` : ''} now, it won't be shown again.</p>
+          <div className="flex items-center gap-2">
+            <code className="flex-1 font-mono text-xs text-white bg-slate-900 px-3 py-2 rounded-lg break-all">{issued.url}</code>
+            <button
+              onClick={() => navigator.clipboard.writeText(issued.url!)}
+              className="text-slate-400 hover:text-white transition-colors"
+            >
+              <Copy className="w-4 h-4" />
+            </button>
+          </div>
+        </div>
+      )}
+
+      <div className="overflow-x-auto">
+        <table className="w-full text-left text-sm text-slate-400">
+          <thead className="bg-[#02040a] text-xs uppercase font-bold text-slate-500 tracking-wider">
+            <tr>
+              <th className="px-6 py-4">Invite</th>
+              <th className="px-6 py-4">Role</th>
+              <th className="px-6 py-4">Uses</th>
+              <th className="px-6 py-4">Expires</th>
+              <th className="px-6 py-4">Status</th>
+              <th className="px-6 py-4">Registered Users</th>
+              <th className="px-6 py-4 text-right">Actions</th>
+            </tr>
+          </thead>
+          <tbody className="divide-y divide-indigo-900/20">
+            {loading ? (
+              <tr><td colSpan={7} className="px-6 py-8 text-center">Loading invites...</td></tr>
+            ) : invites.length === 0 ? (
+              <tr><td colSpan={7} className="px-6 py-8 text-center">No invites yet</td></tr>
+            ) : invites.map(invite => {
+              const state = inviteState(invite);
+              return (
+                <tr key={invite.id} className="hover:bg-white/5 transition-colors align-top">
+                  <td className="px-6 py-4">
+                    <div className="text-white">{invite.label || <span className="text-slate-600 italic">unlabeled</span>}</div>
+                    <div className="font-mono text-xs text-slate-500">{invite.code_prefix}…</div>
+                  </td>
+                  <td className="px-6 py-4 font-mono text-xs text-indigo-300">{invite.role}</td>
+                  <td className="px-6 py-4">{invite.use_count} / {invite.max_uses}</td>
+                  <td className="px-6 py-4 text-slate-500 whitespace-nowrap">{new Date(invite.expires_at).toLocaleDateString()}</td>
+                  <td className="px-6 py-4">
+                    <span className={`px-2 py-1 rounded text-xs font-bold border ${state.className}`}>{state.label}</span>
+                  </td>
+                  <td className="px-6 py-4">
+                    {(invite.users ?? []).length === 0
+                      ? <span className="text-slate-600">—</span>
+                      : invite.users!.map(u => (
+                        <div key={u.id} className="text-white">
+                          {u.username}
+                          <span className="text-xs text-slate-500"> {new Date(u.created_at).toLocaleDateString()}</span>
+                        </div>
+                      ))}
+                  </td>
+                  <td className="px-6 py-4 text-right">
+                    {!invite.revoked_at && (
+                      <button
+                        onClick={() => handleRevoke(invite.id)}
+                        className="p-2 hover:bg-red-500/10 rounded-lg text-slate-400 hover:text-red-400 transition-colors"
+                        title="Revoke"
+                      >
+                        <Ban className="w-4 h-4" />
+                      </button>
+                    )}
+                  </td>
+                </tr>
+              );
+            })}
+          </tbody>
+        </table>
+      </div>
+    </MotionDiv>
+  );
+};
@@ -1,8 +1,8 @@
 import * as React from 'react';
-import { useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { api } from '../services/api';
-import { AuthResponse } from '../types';
-import { LayoutDashboard, Lock, User as UserIcon, AlertCircle, ArrowRight, Activity, ShieldCheck } from 'lucide-react';
+import { AuthResponse, PasswordPolicy, TwoFactorChallenge } from '../types';
+import { LayoutDashboard, Lock, User as UserIcon, AlertCircle, ArrowRight, Activity, ShieldCheck, KeyRound } from 'lucide-react';
 import { motion } from 'framer-motion';
 
 const MotionDiv = motion.div as any;
@@ -16,6 +16,74 @@ export const Login: React.FC<LoginProps> = ({ onLoginSuccess }) => {
   const [password, setPassword] = useState('');
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  // Set when the account must choose a new password before continuing
+  const [pendingAuth, setPendingAuth] = useState<AuthResponse | null>(null);
+  const [newPassword, setNewPassword] = useState('');
+  const [passwordPolicy, setPasswordPolicy] = useState<PasswordPolicy | null>(null);
+  // Set when the password was accepted but a two-factor code is still needed
+  const [challengeToken, setChallengeToken] = useState<string | null>(null);
+  const [code, setCode] = useState('');
+  // Identity provider name when single sign-on is enabled
+  const [ssoProvider, setSsoProvider] = useState<string | null>(null);
+  const ssoCallbackHandled = useRef(false);
+
+  useEffect(() => {
+    if (pendingAuth && !passwordPolicy) {
+      api.auth.passwordPolicy().then(res => {
+        if (res.success && res.data) setPasswordPolicy(res.data);
+      });
+    }
+  }, [pendingAuth]);
+
+  useEffect(() => {
+    api.auth.oidcConfig().then(res => {
+      if (res.success && res.data?.enabled) {
+        setSsoProvider(res.data.provider_name);
+      }
+    });
+
+    // Returning from the identity provider to /login/sso
+    const params = new URLSearchParams(window.location.search);
+    const ssoCode = params.get('code');
+    const ssoState = params.get('state');
+    const ssoError = params.get('error_description') || params.get('error');
+    if (ssoError) {
+      setError(ssoError);
+    } else if (ssoCode && ssoState && !ssoCallbackHandled.current) {
+      // The state can only be redeemed once
+      ssoCallbackHandled.current = true;
+      window.history.replaceState(null, '', window.location.pathname);
+      setIsLoading(true);
+      api.auth.oidcCallback(ssoCode, ssoState).then(res => {
+        setIsLoading(false);
+        if (res.success && res.data) {
+          completeLogin(res.data);
+        } else {
+          setError(res.error || 'Single sign-on failed');
+        }
+      });
+    }
+  }, []);
+
+  const handleSso = async () => {
+    setError(null);
+    setIsLoading(true);
+    const res = await api.auth.oidcAuthorize();
+    if (res.success && res.data) {
+      window.location.href = res.data.authorization_url;
+    } else {
+      setIsLoading(false);
+      setError(res.error || 'Single sign-on is unavailable');
+    }
+  };
+
+  const completeLogin = (data: AuthResponse) => {
+    if (data.user.must_change_password) {
+      setPendingAuth(data);
+    } else {
+      onLoginSuccess(data);
+    }
+  };
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -23,9 +91,37 @@ export const Login: React.FC<LoginProps> = ({ onLoginSuccess }) => {
     setIsLoading(true);
 
     try {
+      if (pendingAuth) {
+        const result = await api.auth.changePassword(pendingAuth.token, password, newPassword);
+        if (result.success && result.data) {
+          onLoginSuccess({
+            ...pendingAuth,
+            token: result.data.token,
+            refresh_token: result.data.refresh_token,
+            expires_at: result.data.expires_at,
+            user: { ...pendingAuth.user, must_change_password: false },
+          });
+        } else {
+          setError(result.error || 'Password change failed');
+        }
+        return;
+      }
+
+      if (challengeToken) {
+        const result = await api.auth.loginTwoFactor(challengeToken, code);
+        if (result.success && result.data) {
+          completeLogin(result.data);
+        } else {
+          setError(result.error || 'Invalid two-factor code');
+        }
+        return;
+      }
+
       const result = await api.auth.login(username, password);
-      if (result.success && result.data) {
-        onLoginSuccess(result.data);
+      if (result.success && (result.data as TwoFactorChallenge)?.two_factor_required) {
+        setChallengeToken((result.data as TwoFactorChallenge).challenge_token);
+      } else if (result.success && result.data) {
+        completeLogin(result.data as AuthResponse);
       } else {
         setError(result.error || 'Login failed');
       }
@@ -124,6 +220,57 @@ export const Login: React.FC<LoginProps> = ({ onLoginSuccess }) => {
               </div>
             </div>
 
+            {challengeToken && !pendingAuth && (
+              <div className="space-y-2">
+                <label className="text-xs font-bold text-slate-400 uppercase tracking-widest ml-1">
+                  Authentication Code
+                </label>
+                <p className="text-sm text-slate-400 ml-1">Enter the code from your authenticator app or a recovery code.</p>
+                <div className="relative group">
+                  <div className="absolute inset-y-0 left-0 pl-4 flex items-center pointer-events-none">
+                    <ShieldCheck className="h-5 w-5 text-slate-500 group-focus-within:text-primary transition-colors" />
+                  </div>
+                  <input
+                    type="text"
+                    inputMode="numeric"
+                    autoComplete="one-time-code"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    className="w-full pl-12 pr-4 py-4 bg-slate-900/50 border border-slate-700/50 rounded-xl text-white placeholder-slate-600 focus:outline-none focus:ring-2 focus:ring-primary/50 focus:border-primary transition-all duration-300"
+                    placeholder="123456"
+                    required
+                  />
+                </div>
+              </div>
+            )}
+
+            {pendingAuth && (
+              <div className="space-y-2">
+                <label className="text-xs font-bold text-slate-400 uppercase tracking-widest ml-1">
+                  New Passcode
+                </label>
+                <p className="text-sm text-slate-400 ml-1">
+                  You must choose a new password before continuing.
+                  {passwordPolicy && passwordPolicy.history_count > 0 &&
+                    ` It can't be one of your last ${passwordPolicy.history_count} passwords.`}
+                </p>
+                <div className="relative group">
+                  <div className="absolute inset-y-0 left-0 pl-4 flex items-center pointer-events-none">
+                    <Lock className="h-5 w-5 text-slate-500 group-focus-within:text-primary transition-colors" />
+                  </div>
+                  <input
+                    type="password"
+                    value={newPassword}
+                    onChange={(e) => setNewPassword(e.target.value)}
+                    className="w-full pl-12 pr-4 py-4 bg-slate-900/50 border border-slate-700/50 rounded-xl text-white placeholder-slate-600 focus:outline-none focus:ring-2 focus:ring-primary/50 focus:border-primary transition-all duration-300"
+                    placeholder={`At least ${passwordPolicy?.min_length ?? 8} characters`}
+                    minLength={passwordPolicy?.min_length ?? 8}
+                    required
+                  />
+                </div>
+              </div>
+            )}
+
             <button
               type="submit"
               disabled={isLoading}
@@ -133,12 +280,24 @@ export const Login: React.FC<LoginProps> = ({ onLoginSuccess }) => {
                 <div className="w-6 h-6 border-2 border-white/30 border-t-white rounded-full animate-spin" />
               ) : (
                 <>
-                  <span className="relative z-10">Access Dashboard</span>
+                  <span className="relative z-10">{pendingAuth ? 'Change Password' : challengeToken ? 'Verify Code' : 'Access Dashboard'}</span>
                   <ArrowRight className="w-5 h-5 relative z-10 group-hover:translate-x-1 transition-transform" />
                 </>
               )}
             </button>
           </form>
+
+          {ssoProvider && !pendingAuth && !challengeToken && (
+            <button
+              type="button"
+              onClick={handleSso}
+              disabled={isLoading}
+              className="w-full mt-4 flex items-center justify-center gap-3 py-4 px-4 rounded-xl border border-slate-700 text-slate-300 hover:text-white hover:bg-white/5 font-bold transition-all disabled:opacity-50"
+            >
+              <KeyRound className="w-5 h-5" />
+              Sign in with {ssoProvider}
+            </button>
+          )}
         </div>
         
         <div className="mt-8 text-center text-slate-600 text-sm">
@@ -0,0 +1,304 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { Building2, Plus, Trash2, X, Wallet } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { Organization, OrganizationDashboard, OrganizationMember, OrganizationRole } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+const ROLES: OrganizationRole[] = ['owner', 'manager', 'analyst', 'viewer'];
+const ROLE_RANK: Record<OrganizationRole, number> = { viewer: 1, analyst: 2, manager: 3, owner: 4 };
+
+const formatMoney = (value: number) => value.toLocaleString(undefined, { style: 'currency', currency: 'USD' });
+
+interface OrganizationsProps {
+  token: string;
+  onAccountCreated: () => void;
+  onClose: () => void;
+}
+
+// Lists the current user's organizations with their dashboard, members and accounts
+export const Organizations: React.FC<OrganizationsProps> = ({ token, onAccountCreated, onClose }) => {
+  const [orgs, setOrgs] = useState<Organization[]>([]);
+  const [selected, setSelected] = useState<Organization | null>(null);
+  const [dashboard, setDashboard] = useState<OrganizationDashboard | null>(null);
+  const [members, setMembers] = useState<OrganizationMember[]>([]);
+  const [error, setError] = useState<string | null>(null);
+
+  const [newOrgName, setNewOrgName] = useState('');
+  const [username, setUsername] = useState('');
+  const [role, setRole] = useState<OrganizationRole>('viewer');
+  const [accountName, setAccountName] = useState('');
+
+  const rank = selected?.role ? ROLE_RANK[selected.role] : 0;
+
+  const fetchOrgs = async () => {
+    const res = await api.organizations.getMine(token);
+    if (res.success && res.data) {
+      setOrgs(res.data);
+    }
+  };
+
+  const fetchDetails = async (org: Organization) => {
+    const [dashRes, membersRes] = await Promise.all([
+      api.organizations.getDashboard(token, org.id),
+      api.organizations.listMembers(token, org.id),
+    ]);
+    if (dashRes.success && dashRes.data) setDashboard(dashRes.data);
+    if (membersRes.success && membersRes.data) setMembers(membersRes.data);
+  };
+
+  useEffect(() => {
+    fetchOrgs();
+  }, []);
+
+  useEffect(() => {
+    setDashboard(null);
+    setMembers([]);
+    if (selected) fetchDetails(selected);
+  }, [selected?.id]);
+
+  const run = async (action: Promise<{ success: boolean; error?: string }>, fallback: string) => {
+    setError(null);
+    const res = await action;
+    if (!res.success) {
+      setError(res.error || fallback);
+    }
+    return res.success;
+  };
+
+  const handleCreateOrg = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (await run(api.organizations.create(token, newOrgName), 'Failed to create organization')) {
+      setNewOrgName('');
+      fetchOrgs();
+    }
+  };
+
+  const handleDeleteOrg = async () => {
+    if (selected && confirm(`Delete ${selected.name}? It must not have any accounts left.`)) {
+      if (await run(api.organizations.delete(token, selected.id), 'Failed to delete organization')) {
+        setSelected(null);
+        fetchOrgs();
+      }
+    }
+  };
+
+  const handleSetMember = async (name: string, newRole: OrganizationRole) => {
+    if (selected && await run(api.organizations.setMember(token, selected.id, name, newRole), 'Failed to save member')) {
+      setUsername('');
+      fetchDetails(selected);
+    }
+  };
+
+  const handleRemoveMember = async (userId: number) => {
+    if (selected && confirm('Remove this member from the organization?')) {
+      if (await run(api.organizations.removeMember(token, selected.id, userId), 'Failed to remove member')) {
+        fetchDetails(selected);
+      }
+    }
+  };
+
+  const handleCreateAccount = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (selected && await run(api.organizations.createAccount(token, selected.id, accountName), 'Failed to create account')) {
+      setAccountName('');
+      fetchDetails(selected);
+      onAccountCreated();
+    }
+  };
+
+  const inputClass = 'bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent transition-all';
+  const buttonClass = 'flex items-center justify-center gap-2 bg-primary hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-bold transition-all shadow-lg shadow-primary/25';
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-4xl max-h-[90vh] overflow-y-auto shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <Building2 className="w-5 h-5 text-primary" /> Organizations
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        <div className="grid grid-cols-1 md:grid-cols-3 gap-6">
+          <div className="space-y-2">
+            {orgs.length === 0 && <p className="text-sm text-slate-500">You're not in any organization yet.</p>}
+            {orgs.map(org => (
+              <button
+                key={org.id}
+                onClick={() => setSelected(org)}
+                className={`w-full flex items-center justify-between px-3 py-3 rounded-xl text-sm font-medium border transition-all ${
+                  selected?.id === org.id
+                    ? 'bg-primary/10 text-primary border-primary/20'
+                    : 'text-slate-400 border-transparent hover:bg-white/5 hover:text-white'
+                }`}
+              >
+                <span className="truncate">{org.name}</span>
+                <span className="text-[10px] uppercase text-slate-500">{org.role}</span>
+              </button>
+            ))}
+            <form onSubmit={handleCreateOrg} className="flex flex-col gap-2 pt-4 border-t border-white/5">
+              <input
+                type="text"
+                className={inputClass}
+                placeholder="New organization"
+                value={newOrgName}
+                onChange={(e) => setNewOrgName(e.target.value)}
+                maxLength={100}
+                required
+              />
+              <button type="submit" className={buttonClass}>
+                <Plus className="w-4 h-4" /> Create
+              </button>
+            </form>
+          </div>
+
+          <div className="md:col-span-2 space-y-6">
+            {!selected && <p className="text-sm text-slate-500">Select an organization.</p>}
+
+            {selected && dashboard && (
+              <div className="grid grid-cols-2 gap-3">
+                <div className="bg-slate-900/50 border border-slate-800 rounded-xl p-4">
+                  <p className="text-xs font-bold text-slate-400 uppercase tracking-widest mb-1">Total Balance</p>
+                  <p className="text-lg font-bold text-white">{formatMoney(dashboard.total_balance)}</p>
+                </div>
+                <div className="bg-slate-900/50 border border-slate-800 rounded-xl p-4">
+                  <p className="text-xs font-bold text-slate-400 uppercase tracking-widest mb-1">Today P/L</p>
+                  <p className={`text-lg font-bold ${dashboard.today_pl >= 0 ? 'text-emerald-400' : 'text-red-400'}`}>
+                    {formatMoney(dashboard.today_pl)}
+                  </p>
+                </div>
+                <div className="bg-slate-900/50 border border-slate-800 rounded-xl p-4">
+                  <p className="text-xs font-bold text-slate-400 uppercase tracking-widest mb-1">Accounts</p>
+                  <p className="text-lg font-bold text-white">
+                    {dashboard.account_count}{selected.max_accounts > 0 && ` / ${selected.max_accounts}`}
+                  </p>
+                </div>
+                <div className="bg-slate-900/50 border border-slate-800 rounded-xl p-4">
+                  <p className="text-xs font-bold text-slate-400 uppercase tracking-widest mb-1">Members</p>
+                  <p className="text-lg font-bold text-white">
+                    {dashboard.member_count}{selected.max_members > 0 && ` / ${selected.max_members}`}
+                  </p>
+                </div>
+              </div>
+            )}
+
+            {selected && dashboard?.accounts && (
+              <div className="space-y-2">
+                <h4 className="text-xs font-bold text-slate-400 uppercase tracking-widest">Accounts</h4>
+                {dashboard.accounts.length === 0 && <p className="text-sm text-slate-500">No accounts yet.</p>}
+                {dashboard.accounts.map(a => (
+                  <div key={a.account_id} className="flex items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3 text-sm">
+                    <span className="flex items-center gap-2 text-white truncate"><Wallet className="w-4 h-4 text-slate-500" />{a.name}</span>
+                    <span className="text-slate-300">{formatMoney(a.balance)}</span>
+                    <span className={a.daily_pl >= 0 ? 'text-emerald-400' : 'text-red-400'}>{formatMoney(a.daily_pl)}</span>
+                  </div>
+                ))}
+                {rank >= ROLE_RANK.manager && (
+                  <form onSubmit={handleCreateAccount} className="flex gap-2 pt-2">
+                    <input
+                      type="text"
+                      className={`flex-1 ${inputClass}`}
+                      placeholder="New account name"
+                      value={accountName}
+                      onChange={(e) => setAccountName(e.target.value)}
+                      required
+                    />
+                    <button type="submit" className={buttonClass}>
+                      <Plus className="w-4 h-4" /> Add
+                    </button>
+                  </form>
+                )}
+              </div>
+            )}
+
+            {selected && (
+              <div className="space-y-2">
+                <h4 className="text-xs font-bold text-slate-400 uppercase tracking-widest">Members</h4>
+                {members.map(m => (
+                  <div key={m.id} className="flex items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3">
+                    <span className="text-white font-medium truncate">{m.user?.username ?? `User #${m.user_id}`}</span>
+                    {rank >= ROLE_RANK.manager ? (
+                      <div className="flex items-center gap-3 shrink-0">
+                        <select
+                          value={m.role}
+                          onChange={(e) => handleSetMember(m.user?.username ?? '', e.target.value as OrganizationRole)}
+                          className="bg-slate-900 border border-slate-700 rounded-lg px-2 py-1 text-sm text-white capitalize"
+                        >
+                          {ROLES.map(r => <option key={r} value={r}>{r}</option>)}
+                        </select>
+                        <button
+                          onClick={() => handleRemoveMember(m.user_id)}
+                          className="text-red-400 hover:text-red-300 transition-colors"
+                          title="Remove member"
+                        >
+                          <Trash2 className="w-4 h-4" />
+                        </button>
+                      </div>
+                    ) : (
+                      <span className="text-xs uppercase text-slate-500">{m.role}</span>
+                    )}
+                  </div>
+                ))}
+                {rank >= ROLE_RANK.manager && (
+                  <form
+                    onSubmit={(e) => { e.preventDefault(); handleSetMember(username, role); }}
+                    className="flex flex-col md:flex-row gap-2 pt-2"
+                  >
+                    <input
+                      type="text"
+                      className={`flex-1 ${inputClass}`}
+                      placeholder="Username"
+                      value={username}
+                      onChange={(e) => setUsername(e.target.value)}
+                      required
+                    />
+                    <select
+                      value={role}
+                      onChange={(e) => setRole(e.target.value as OrganizationRole)}
+                      className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white capitalize"
+                    >
+                      {ROLES.map(r => <option key={r} value={r}>{r}</option>)}
+                    </select>
+                    <button type="submit" className={buttonClass}>
+                      <Plus className="w-4 h-4" /> Add
+                    </button>
+                  </form>
+                )}
+              </div>
+            )}
+
+            {selected?.role === 'owner' && (
+              <div className="flex justify-end border-t border-white/5 pt-4">
+                <button
+                  onClick={handleDeleteOrg}
+                  className="flex items-center gap-1 text-xs text-red-400 hover:text-red-300 transition-colors font-medium"
+                >
+                  <Trash2 className="w-3 h-3" /> Delete organization
+                </button>
+              </div>
+            )}
+          </div>
+        </div>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
@@ -0,0 +1,192 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { KeyRound, Plus, Trash2, X, Copy } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { Permission, PersonalAccessToken, User } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+// Default expiry offered for a new token
+const DEFAULT_EXPIRY_DAYS = 90;
+
+const toDateInput = (date: Date) => date.toISOString().slice(0, 10);
+
+interface PersonalAccessTokensProps {
+  token: string;
+  user: User;
+  onClose: () => void;
+}
+
+// Manages the current user's personal access tokens for scripts and notebooks
+export const PersonalAccessTokens: React.FC<PersonalAccessTokensProps> = ({ token, user, onClose }) => {
+  const [tokens, setTokens] = useState<PersonalAccessToken[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  // Secret of a token that was just issued; it can't be fetched again
+  const [issued, setIssued] = useState<PersonalAccessToken | null>(null);
+
+  const available = user.permissions ?? [];
+  const [name, setName] = useState('');
+  const [scopes, setScopes] = useState<Permission[]>(available.includes('accounts:read') ? ['accounts:read'] : []);
+  const [expiresOn, setExpiresOn] = useState(toDateInput(new Date(Date.now() + DEFAULT_EXPIRY_DAYS * 86400000)));
+
+  const fetchTokens = async () => {
+    const res = await api.users.listTokens(token);
+    if (res.success && res.data) {
+      setTokens(res.data);
+    }
+  };
+
+  useEffect(() => {
+    fetchTokens();
+  }, []);
+
+  const toggleScope = (scope: Permission) => {
+    setScopes(prev => prev.includes(scope) ? prev.filter(s => s !== scope) : [...prev, scope]);
+  };
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const res = await api.users.createToken(token, {
+      name,
+      scopes,
+      expires_at: new Date(`${expiresOn}T23:59:59`).toISOString(),
+    });
+    if (res.success && res.data) {
+      setIssued(res.data);
+      setName('');
+      fetchTokens();
+    } else {
+      setError(res.error || 'Failed to create token');
+    }
+  };
+
+  const handleRevoke = async (id: number) => {
+    if (confirm('Revoke this token? Scripts using it will stop working immediately.')) {
+      const res = await api.users.revokeToken(token, id);
+      if (res.success) {
+        setTokens(tokens.filter(t => t.id !== id));
+      }
+    }
+  };
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-3xl max-h-[90vh] overflow-y-auto shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <KeyRound className="w-5 h-5 text-primary" /> Personal Access Tokens
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        <p className="text-sm text-slate-400 mb-6">
+          Use a token in place of logging in, sent as <code className="font-mono text-xs text-slate-300">Authorization: Bearer &lt;token&gt;</code>.
+        </p>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        {issued?.token && (
+          <div className="mb-6 p-4 rounded-xl bg-amber-500/10 border border-amber-500/20">
+            <p className="text-sm text-amber-300 mb-2">Copy the token for "{issued.name}" now, it won't be shown again.</p>
+            <div className="flex items-center gap-2">
+              <code className="flex-1 font-mono text-xs text-white bg-slate-900 px-3 py-2 rounded-lg break-all">{issued.token}</code>
+              <button
+                onClick={() => navigator.clipboard.writeText(issued.token!)}
+                className="text-slate-400 hover:text-white transition-colors"
+              >
+                <Copy className="w-4 h-4" />
+              </button>
+            </div>
+          </div>
+        )}
+
+        <div className="space-y-3 mb-8">
+          {tokens.length === 0 && <p className="text-sm text-slate-500">No tokens yet.</p>}
+          {tokens.map(t => (
+            <div key={t.id} className="flex flex-col md:flex-row md:items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3">
+              <div className="min-w-0">
+                <div className="flex items-center gap-2">
+                  <span className="text-white font-medium truncate">{t.name}</span>
+                  <span className="font-mono text-xs text-slate-500">{t.token_prefix}…</span>
+                </div>
+                <div className="text-xs text-slate-500 mt-1 flex flex-wrap gap-x-3">
+                  <span>{t.scopes.join(', ')}</span>
+                  <span className={new Date(t.expires_at) < new Date() ? 'text-red-400' : ''}>
+                    Expires {new Date(t.expires_at).toLocaleString()}
+                  </span>
+                  <span>
+                    {t.last_used_at ? `Last used ${new Date(t.last_used_at).toLocaleString()} from ${t.last_used_ip}` : 'Never used'}
+                  </span>
+                </div>
+              </div>
+              <button
+                onClick={() => handleRevoke(t.id)}
+                className="flex items-center gap-1 text-xs text-red-400 hover:text-red-300 transition-colors font-medium shrink-0"
+              >
+                <Trash2 className="w-3 h-3" /> Revoke
+              </button>
+            </div>
+          ))}
+        </div>
+
+        <form onSubmit={handleCreate} className="space-y-4 border-t border-white/5 pt-6">
+          <h4 className="text-xs font-bold text-slate-400 uppercase tracking-widest">New Token</h4>
+          <input
+            type="text"
+            className="w-full bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent transition-all"
+            placeholder="e.g. Research notebook"
+            value={name}
+            onChange={(e) => setName(e.target.value)}
+            maxLength={100}
+            required
+          />
+          <div className="flex flex-wrap gap-3">
+            {available.map(scope => (
+              <label key={scope} className="flex items-center gap-2 text-sm text-slate-300 font-mono">
+                <input type="checkbox" checked={scopes.includes(scope)} onChange={() => toggleScope(scope)} />
+                {scope}
+              </label>
+            ))}
+          </div>
+          <div className="flex flex-col md:flex-row md:items-center gap-2 text-sm text-slate-400">
+            <span>Expires on</span>
+            <input
+              type="date"
+              className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white"
+              value={expiresOn}
+              min={toDateInput(new Date())}
+              max={toDateInput(new Date(Date.now() + 365 * 86400000))}
+              onChange={(e) => setExpiresOn(e.target.value)}
+              required
+            />
+          </div>
+          <div className="flex justify-end">
+            <button
+              type="submit"
+              disabled={scopes.length === 0}
+              className="flex items-center gap-2 bg-primary hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-bold transition-all shadow-lg shadow-primary/25 disabled:opacity-50"
+            >
+              <Plus className="w-4 h-4" /> Create Token
+            </button>
+          </div>
+        </form>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
@@ -0,0 +1,188 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { Share2, Plus, Trash2, X, Copy } from 'lucide-react';
+import { motion } from 'framer-motion';
+import { ShareLink } from '../types';
+import { api } from '../services/api';
+
+const MotionDiv = motion.div as any;
+
+interface ShareLinksProps {
+  token: string;
+  accountId: number;
+  accountName: string;
+  onClose: () => void;
+}
+
+// Manages public read-only investor links for one account
+export const ShareLinks: React.FC<ShareLinksProps> = ({ token, accountId, accountName, onClose }) => {
+  const [links, setLinks] = useState<ShareLink[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  // URL of a link that was just created; it can't be fetched again
+  const [created, setCreated] = useState<ShareLink | null>(null);
+
+  const [label, setLabel] = useState('');
+  const [showAccountName, setShowAccountName] = useState(true);
+  const [showBalances, setShowBalances] = useState(false);
+  const [showEquityCurve, setShowEquityCurve] = useState(true);
+  const [showDrawdown, setShowDrawdown] = useState(true);
+  const [expiresAt, setExpiresAt] = useState('');
+
+  const fetchLinks = async () => {
+    const res = await api.shareLinks.list(token, accountId);
+    if (res.success && res.data) {
+      setLinks(res.data);
+    }
+  };
+
+  useEffect(() => {
+    fetchLinks();
+  }, [accountId]);
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const res = await api.shareLinks.create(token, accountId, {
+      label,
+      show_account_name: showAccountName,
+      show_balances: showBalances,
+      show_equity_curve: showEquityCurve,
+      show_drawdown: showDrawdown,
+      expires_at: expiresAt ? new Date(expiresAt).toISOString() : undefined,
+    });
+    if (res.success && res.data) {
+      setCreated(res.data);
+      setLabel('');
+      setExpiresAt('');
+      fetchLinks();
+    } else {
+      setError(res.error || 'Failed to create share link');
+    }
+  };
+
+  const handleRevoke = async (id: number) => {
+    if (confirm('Revoke this link? Anyone using it will lose access immediately.')) {
+      const res = await api.shareLinks.revoke(token, accountId, id);
+      if (res.success) {
+        setLinks(links.filter(l => l.id !== id));
+      }
+    }
+  };
+
+  const visibility = (l: ShareLink) => [
+    l.show_account_name && 'name',
+    l.show_balances ? 'balances' : 'percentages only',
+    l.show_equity_curve && 'equity curve',
+    l.show_drawdown && 'drawdown',
+  ].filter(Boolean).join(', ');
+
+  return (
+    <MotionDiv
+      initial={{ opacity: 0 }}
+      animate={{ opacity: 1 }}
+      exit={{ opacity: 0 }}
+      className="fixed inset-0 z-[60] flex items-center justify-center p-4 bg-black/80 backdrop-blur-sm"
+    >
+      <MotionDiv
+        initial={{ scale: 0.9, y: 20 }}
+        animate={{ scale: 1, y: 0 }}
+        exit={{ scale: 0.9, y: 20 }}
+        className="glass-card rounded-2xl p-8 w-full max-w-2xl max-h-[90vh] overflow-y-auto shadow-2xl border-t border-white/10"
+      >
+        <div className="flex justify-between items-center mb-6">
+          <h3 className="text-xl font-bold text-white flex items-center gap-2">
+            <Share2 className="w-5 h-5 text-primary" /> Share Links &middot; {accountName}
+          </h3>
+          <button onClick={onClose} className="text-slate-400 hover:text-white transition-colors">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        {error && (
+          <div className="mb-4 p-3 rounded-xl bg-red-500/10 border border-red-500/20 text-sm text-red-400">{error}</div>
+        )}
+
+        {created?.url && (
+          <div className="mb-6 p-4 rounded-xl bg-amber-500/10 border border-amber-500/20">
+            <p className="text-sm text-amber-300 mb-2">Copy this link now, it won't be shown again.</p>
+            <div className="flex items-center gap-2">
+              <code className="flex-1 font-mono text-xs text-white bg-slate-900 px-3 py-2 rounded-lg break-all">{created.url}</code>
+              <button
+                onClick={() => navigator.clipboard.writeText(created.url!)}
+                className="text-slate-400 hover:text-white transition-colors"
+              >
+                <Copy className="w-4 h-4" />
+              </button>
+            </div>
+          </div>
+        )}
+
+        <div className="space-y-3 mb-8">
+          {links.length === 0 && <p className="text-sm text-slate-500">No share links yet.</p>}
+          {links.map(l => (
+            <div key={l.id} className="flex items-center justify-between gap-3 bg-slate-900/50 border border-slate-800 rounded-xl px-4 py-3">
+              <div className="min-w-0">
+                <div className="flex items-center gap-2">
+                  <span className="text-white font-medium truncate">{l.label || 'Untitled link'}</span>
+                  <span className="font-mono text-xs text-slate-500">{l.token_prefix}…</span>
+                </div>
+                <div className="text-xs text-slate-500 mt-1 flex flex-wrap gap-x-3">
+                  <span>{visibility(l)}</span>
+                  {l.expires_at && <span>Expires {new Date(l.expires_at).toLocaleString()}</span>}
+                  <span>{l.last_viewed_at ? `Last viewed ${new Date(l.last_viewed_at).toLocaleString()}` : 'Never viewed'}</span>
+                </div>
+              </div>
+              <button
+                onClick={() => handleRevoke(l.id)}
+                className="flex items-center gap-1 text-xs text-red-400 hover:text-red-300 transition-colors font-medium shrink-0"
+              >
+                <Trash2 className="w-3 h-3" /> Revoke
+              </button>
+            </div>
+          ))}
+        </div>
+
+        <form onSubmit={handleCreate} className="space-y-4 border-t border-white/5 pt-6">
+          <h4 className="text-xs font-bold text-slate-400 uppercase tracking-widest">New Link</h4>
+          <input
+            type="text"
+            className="w-full bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent transition-all"
+            placeholder="e.g. Prop desk review Q3"
+            value={label}
+            onChange={(e) => setLabel(e.target.value)}
+            maxLength={100}
+          />
+          <div className="flex flex-wrap gap-4 text-sm text-slate-300">
+            <label className="flex items-center gap-2">
+              <input type="checkbox" checked={showAccountName} onChange={(e) => setShowAccountName(e.target.checked)} /> Account name
+            </label>
+            <label className="flex items-center gap-2">
+              <input type="checkbox" checked={showBalances} onChange={(e) => setShowBalances(e.target.checked)} /> Absolute balances
+            </label>
+            <label className="flex items-center gap-2">
+              <input type="checkbox" checked={showEquityCurve} onChange={(e) => setShowEquityCurve(e.target.checked)} /> Equity curve
+            </label>
+            <label className="flex items-center gap-2">
+              <input type="checkbox" checked={showDrawdown} onChange={(e) => setShowDrawdown(e.target.checked)} /> Drawdown
+            </label>
+          </div>
+          <input
+            type="datetime-local"
+            className="bg-slate-900 border border-slate-700 rounded-xl px-4 py-3 text-white"
+            value={expiresAt}
+            onChange={(e) => setExpiresAt(e.target.value)}
+            title="Optional expiry"
+          />
+          <div className="flex justify-end">
+            <button
+              type="submit"
+              className="flex items-center gap-2 bg-primary hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-bold transition-all shadow-lg shadow-primary/25"
+            >
+              <Plus className="w-4 h-4" /> Create Link
+            </button>
+          </div>
+        </form>
+      </MotionDiv>
+    </MotionDiv>
+  );
+};
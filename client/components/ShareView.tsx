@@ -0,0 +1,126 @@
+import * as React from 'react';
+import { useEffect, useState } from 'react';
+import { useParams } from 'react-router-dom';
+import { AreaChart, Area, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer } from 'recharts';
+import { Activity, AlertCircle, TrendingUp, TrendingDown } from 'lucide-react';
+import { SharedAccountView } from '../types';
+import { api } from '../services/api';
+
+const formatPercent = (value: number) => `${value >= 0 ? '+' : ''}${value.toFixed(2)}%`;
+const formatMoney = (value: number) => value.toLocaleString(undefined, { style: 'currency', currency: 'USD' });
+
+// Public read-only performance view behind an investor share link
+export const ShareView: React.FC = () => {
+  const { shareToken } = useParams<{ shareToken: string }>();
+  const [view, setView] = useState<SharedAccountView | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    if (!shareToken) return;
+    api.shareLinks.view(shareToken).then(res => {
+      if (res.success && res.data) {
+        setView(res.data);
+      } else {
+        setError(res.error || 'This link is invalid or has expired');
+      }
+    });
+  }, [shareToken]);
+
+  const Stat = ({ label, value, sub }: { label: string; value: string; sub?: string }) => (
+    <div className="glass-card rounded-2xl p-5">
+      <p className="text-xs font-bold text-slate-400 uppercase tracking-widest mb-2">{label}</p>
+      <p className="text-2xl font-bold text-white">{value}</p>
+      {sub && <p className="text-sm text-slate-500 mt-1">{sub}</p>}
+    </div>
+  );
+
+  return (
+    <div className="min-h-screen bg-background p-4 md:p-10">
+      <div className="max-w-5xl mx-auto">
+        <div className="flex items-center gap-3 mb-8">
+          <div className="w-10 h-10 rounded-xl bg-gradient-to-tr from-primary to-accent flex items-center justify-center">
+            <Activity className="w-5 h-5 text-white" />
+          </div>
+          <div>
+            <h1 className="text-2xl font-bold text-white">{view?.account_name || 'Shared Performance'}</h1>
+            {view?.label && <p className="text-slate-400 text-sm">{view.label}</p>}
+          </div>
+        </div>
+
+        {error && (
+          <div className="p-4 rounded-xl bg-red-500/10 border border-red-500/20 flex items-center gap-3">
+            <AlertCircle className="w-5 h-5 text-red-500" />
+            <p className="text-sm text-red-400">{error}</p>
+          </div>
+        )}
+
+        {view && !view.has_data && <p className="text-slate-400">No performance data has been recorded yet.</p>}
+
+        {view && view.has_data && (
+          <>
+            <div className="grid grid-cols-2 lg:grid-cols-4 gap-4 mb-8">
+              <Stat
+                label="Total Return"
+                value={formatPercent(view.total_return_percent)}
+                sub={view.current_balance !== undefined ? `Balance ${formatMoney(view.current_balance)}` : undefined}
+              />
+              <Stat
+                label="Today"
+                value={formatPercent(view.today_pl_percent)}
+                sub={view.today_pl !== undefined ? formatMoney(view.today_pl) : undefined}
+              />
+              {view.max_drawdown_percent !== undefined && (
+                <Stat
+                  label="Max Drawdown"
+                  value={`${view.max_drawdown_percent.toFixed(2)}%`}
+                  sub={`Current ${view.drawdown_percent!.toFixed(2)}%`}
+                />
+              )}
+              <Stat
+                label="Trading Days"
+                value={String(view.trading_days)}
+                sub={view.latest_update ? `Updated ${new Date(view.latest_update).toLocaleString()}` : undefined}
+              />
+            </div>
+
+            {view.equity_curve && view.equity_curve.length > 0 && (
+              <div className="glass-card rounded-2xl p-6 mb-8">
+                <h2 className="text-lg font-semibold text-white mb-4 flex items-center gap-2">
+                  {view.total_return_percent >= 0
+                    ? <TrendingUp className="w-5 h-5 text-emerald-400" />
+                    : <TrendingDown className="w-5 h-5 text-red-400" />}
+                  Equity Curve
+                </h2>
+                <div className="h-72">
+                  <ResponsiveContainer width="100%" height="100%">
+                    <AreaChart data={view.equity_curve}>
+                      <CartesianGrid strokeDasharray="3 3" stroke="#1e293b" />
+                      <XAxis dataKey="date" stroke="#64748b" fontSize={12} tickLine={false} axisLine={false} />
+                      <YAxis stroke="#64748b" fontSize={12} tickLine={false} axisLine={false} unit="%" />
+                      <Tooltip contentStyle={{ backgroundColor: '#0f172a', border: '1px solid #1e293b' }} />
+                      <Area type="monotone" dataKey="return_percent" name="Return %" stroke="#6366f1" strokeWidth={2} fill="#6366f1" fillOpacity={0.15} />
+                    </AreaChart>
+                  </ResponsiveContainer>
+                </div>
+                {view.equity_curve[0].drawdown_percent !== undefined && (
+                  <div className="h-40 mt-6">
+                    <ResponsiveContainer width="100%" height="100%">
+                      <AreaChart data={view.equity_curve.map(p => ({ ...p, drawdown: -(p.drawdown_percent ?? 0) }))}>
+                        <XAxis dataKey="date" stroke="#64748b" fontSize={12} tickLine={false} axisLine={false} />
+                        <YAxis stroke="#64748b" fontSize={12} tickLine={false} axisLine={false} unit="%" />
+                        <Tooltip contentStyle={{ backgroundColor: '#0f172a', border: '1px solid #1e293b' }} />
+                        <Area type="monotone" dataKey="drawdown" name="Drawdown %" stroke="#ef4444" strokeWidth={2} fill="#ef4444" fillOpacity={0.15} />
+                      </AreaChart>
+                    </ResponsiveContainer>
+                  </div>
+                )}
+              </div>
+            )}
+          </>
+        )}
+
+        <div className="text-center text-slate-600 text-sm">Read-only view &bull; X-Track Systems</div>
+      </div>
+    </div>
+  );
+};
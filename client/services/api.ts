@@ -1,4 +1,4 @@
-import { Account, ApiResponse, AuthResponse, OverallSummary, Statistic, TodaySummary, User } from '../types';
+import { Account, AccountMember, AuditEvent, AuditEventFilter, AccountToken, AccountTokenScope, ApiResponse, AuthResponse, ImpersonationResponse, Invite, Organization, OrganizationDashboard, OrganizationMember, OrganizationRole, OverallSummary, PasswordPolicy, Permission, PersonalAccessToken, RegisterRequest, Role, SharedAccountView, ShareLink, Statistic, TodaySummary, TwoFactorChallenge, User, UserStatus } from '../types';
 
 const BASE_URL = 'https://xtrack-be.vercel.app/api';
 
@@ -13,10 +13,64 @@ const getHeaders = (token?: string | null) => {
   return headers;
 };
 
-// Generic fetch wrapper
-async function fetchAPI<T>(endpoint: string, options: RequestInit = {}): Promise<ApiResponse<T>> {
+// Shared in-flight refresh so concurrent 401s rotate the refresh token only once
+let refreshPromise: Promise<string | null> | null = null;
+
+// Access tokens replaced by a refresh, so callers still holding the old one use the new one
+const renewedTokens = new Map<string, string>();
+
+const refreshSession = (): Promise<string | null> => {
+  if (!refreshPromise) {
+    refreshPromise = (async () => {
+      const refreshToken = localStorage.getItem('xtrack_refresh_token');
+      if (!refreshToken) return null;
+      try {
+        const response = await fetch(`${BASE_URL}/auth/refresh`, {
+          method: 'POST',
+          headers: getHeaders(),
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        const data = (await response.json()) as ApiResponse<AuthResponse>;
+        if (!data.success || !data.data) return null;
+        localStorage.setItem('xtrack_token', data.data.token);
+        localStorage.setItem('xtrack_refresh_token', data.data.refresh_token);
+        return data.data.token;
+      } catch {
+        return null;
+      } finally {
+        refreshPromise = null;
+      }
+    })();
+  }
+  return refreshPromise;
+};
+
+// Follow a chain of refreshes from the token the caller holds to the newest one
+const currentToken = (token: string): string => {
+  let latest = token;
+  while (renewedTokens.has(latest)) {
+    latest = renewedTokens.get(latest)!;
+  }
+  return latest;
+};
+
+// Generic fetch wrapper; transparently refreshes an expired access token once
+async function fetchAPI<T>(endpoint: string, options: RequestInit = {}, retry: boolean = true): Promise<ApiResponse<T>> {
   try {
-    const response = await fetch(`${BASE_URL}${endpoint}`, options);
+    const headers = { ...(options.headers as Record<string, string>) };
+    const authToken = headers['Authorization']?.replace('Bearer ', '');
+    if (authToken) {
+      headers['Authorization'] = `Bearer ${currentToken(authToken)}`;
+    }
+
+    const response = await fetch(`${BASE_URL}${endpoint}`, { ...options, headers });
+    if (response.status === 401 && retry && authToken) {
+      const newToken = await refreshSession();
+      if (newToken) {
+        renewedTokens.set(currentToken(authToken), newToken);
+        return fetchAPI<T>(endpoint, options, false);
+      }
+    }
     const data = await response.json();
     return data as ApiResponse<T>;
   } catch (error) {
@@ -27,18 +81,112 @@ async function fetchAPI<T>(endpoint: string, options: RequestInit = {}): Promise
   }
 }
 
+// Query string of the non-empty audit filters
+const auditQuery = (filter: AuditEventFilter) =>
+  new URLSearchParams(Object.entries(filter).filter(([, v]) => v) as [string, string][]).toString();
+
 export const api = {
   auth: {
-    login: async (username: string, password: string): Promise<ApiResponse<AuthResponse>> => {
-      return fetchAPI<AuthResponse>('/auth/login', {
+    passwordPolicy: async (): Promise<ApiResponse<PasswordPolicy>> => {
+      return fetchAPI<PasswordPolicy>('/auth/password-policy', {
+        method: 'GET',
+        headers: getHeaders(),
+      });
+    },
+    oidcConfig: async (): Promise<ApiResponse<{ enabled: boolean; provider_name: string }>> => {
+      return fetchAPI<{ enabled: boolean; provider_name: string }>('/auth/oidc', {
+        method: 'GET',
+        headers: getHeaders(),
+      });
+    },
+    oidcAuthorize: async (): Promise<ApiResponse<{ authorization_url: string }>> => {
+      return fetchAPI<{ authorization_url: string }>('/auth/oidc/authorize', {
+        method: 'GET',
+        headers: getHeaders(),
+      });
+    },
+    oidcCallback: async (code: string, state: string): Promise<ApiResponse<AuthResponse>> => {
+      return fetchAPI<AuthResponse>('/auth/oidc/callback', {
+        method: 'POST',
+        headers: getHeaders(),
+        body: JSON.stringify({ code, state }),
+      });
+    },
+    login: async (username: string, password: string): Promise<ApiResponse<AuthResponse | TwoFactorChallenge>> => {
+      return fetchAPI<AuthResponse | TwoFactorChallenge>('/auth/login', {
         method: 'POST',
         headers: getHeaders(),
         body: JSON.stringify({ username, password }),
       });
     },
+    register: async (request: RegisterRequest): Promise<ApiResponse<AuthResponse>> => {
+      return fetchAPI<AuthResponse>('/auth/register', {
+        method: 'POST',
+        headers: getHeaders(),
+        body: JSON.stringify(request),
+      });
+    },
+    loginTwoFactor: async (challengeToken: string, code: string): Promise<ApiResponse<AuthResponse>> => {
+      return fetchAPI<AuthResponse>('/auth/login/2fa', {
+        method: 'POST',
+        headers: getHeaders(),
+        body: JSON.stringify({ challenge_token: challengeToken, code }),
+      });
+    },
+    logout: async (token: string): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>('/auth/logout', {
+        method: 'POST',
+        headers: getHeaders(token),
+      }, false);
+    },
+    changePassword: async (token: string, currentPassword: string, newPassword: string): Promise<ApiResponse<Omit<AuthResponse, 'user'>>> => {
+      return fetchAPI<Omit<AuthResponse, 'user'>>('/auth/password', {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+      });
+    },
+    endImpersonation: async (token: string): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>('/auth/impersonation/end', {
+        method: 'POST',
+        headers: getHeaders(token),
+      }, false);
+    },
+    logoutAll: async (token: string): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>('/auth/logout-all', {
+        method: 'POST',
+        headers: getHeaders(token),
+      });
+    },
   },
   users: {
-    // Admin Only - Full Access
+    getMyPermissions: async (token: string): Promise<ApiResponse<{ role: string; permissions: Permission[] }>> => {
+      return fetchAPI<{ role: string; permissions: Permission[] }>('/users/me/permissions', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    // Personal access tokens; need an interactive login
+    listTokens: async (token: string): Promise<ApiResponse<PersonalAccessToken[]>> => {
+      return fetchAPI<PersonalAccessToken[]>('/users/me/tokens', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    createToken: async (token: string, data: { name: string; scopes: Permission[]; expires_at: string }): Promise<ApiResponse<PersonalAccessToken>> => {
+      return fetchAPI<PersonalAccessToken>('/users/me/tokens', {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify(data),
+      });
+    },
+    revokeToken: async (token: string, id: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/users/me/tokens/${id}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+    // Require users:read / users:manage
     getAll: async (token: string): Promise<ApiResponse<User[]>> => {
       return fetchAPI<User[]>('/users', {
         method: 'GET',
@@ -70,6 +218,21 @@ export const api = {
         method: 'DELETE',
         headers: getHeaders(token),
       });
+    },
+    // Requires users:impersonate
+    impersonate: async (token: string, id: number, request: { reason: string; minutes: number; allow_write: boolean }): Promise<ApiResponse<ImpersonationResponse>> => {
+      return fetchAPI<ImpersonationResponse>(`/users/${id}/impersonate`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify(request),
+      });
+    },
+    setStatus: async (token: string, id: number, status: UserStatus): Promise<ApiResponse<User>> => {
+      return fetchAPI<User>(`/users/${id}/status`, {
+        method: 'PUT',
+        headers: getHeaders(token),
+        body: JSON.stringify({ status }),
+      });
     }
   },
   accounts: {
@@ -87,6 +250,13 @@ export const api = {
         headers: getHeaders(token),
       });
     },
+    // Accounts other users shared with the current user
+    getShared: async (token: string): Promise<ApiResponse<Account[]>> => {
+      return fetchAPI<Account[]>('/accounts/shared', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
     getById: async (token: string, id: number): Promise<ApiResponse<Account>> => {
       return fetchAPI<Account>(`/accounts/${id}`, {
         method: 'GET',
@@ -113,12 +283,194 @@ export const api = {
         headers: getHeaders(token),
       });
     },
-    regenerateToken: async (token: string, accountId: number): Promise<ApiResponse<Account>> => {
-      return fetchAPI<Account>(`/accounts/${accountId}/regenerate-token`, {
+  },
+  accountTokens: {
+    list: async (token: string, accountId: number): Promise<ApiResponse<AccountToken[]>> => {
+      return fetchAPI<AccountToken[]>(`/accounts/${accountId}/tokens`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    create: async (
+      token: string,
+      accountId: number,
+      data: { name: string; scopes: AccountTokenScope[]; expires_at?: string; allowed_ips?: string[] }
+    ): Promise<ApiResponse<AccountToken>> => {
+      return fetchAPI<AccountToken>(`/accounts/${accountId}/tokens`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify(data),
+      });
+    },
+    rotate: async (token: string, accountId: number, tokenId: number, gracePeriodMinutes: number): Promise<ApiResponse<AccountToken>> => {
+      return fetchAPI<AccountToken>(`/accounts/${accountId}/tokens/${tokenId}/rotate`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify({ grace_period_minutes: gracePeriodMinutes }),
+      });
+    },
+    revoke: async (token: string, accountId: number, tokenId: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/accounts/${accountId}/tokens/${tokenId}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+  },
+  accountMembers: {
+    list: async (token: string, accountId: number): Promise<ApiResponse<AccountMember[]>> => {
+      return fetchAPI<AccountMember[]>(`/accounts/${accountId}/members`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    grant: async (token: string, accountId: number, username: string, role: 'viewer' | 'manager'): Promise<ApiResponse<AccountMember>> => {
+      return fetchAPI<AccountMember>(`/accounts/${accountId}/members`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify({ username, role }),
+      });
+    },
+    revoke: async (token: string, accountId: number, userId: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/accounts/${accountId}/members/${userId}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+  },
+  invites: {
+    // Require users:manage
+    getAll: async (token: string): Promise<ApiResponse<Invite[]>> => {
+      return fetchAPI<Invite[]>('/invites', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    create: async (token: string, invite: { label: string; role: string; max_uses: number; expires_at: string }): Promise<ApiResponse<Invite>> => {
+      return fetchAPI<Invite>('/invites', {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify(invite),
+      });
+    },
+    revoke: async (token: string, id: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/invites/${id}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+  },
+  roles: {
+    getAll: async (token: string): Promise<ApiResponse<Role[]>> => {
+      return fetchAPI<Role[]>('/roles', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+  },
+  organizations: {
+    getMine: async (token: string): Promise<ApiResponse<Organization[]>> => {
+      return fetchAPI<Organization[]>('/organizations/me', {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    create: async (token: string, name: string): Promise<ApiResponse<Organization>> => {
+      return fetchAPI<Organization>('/organizations', {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify({ name }),
+      });
+    },
+    delete: async (token: string, orgId: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/organizations/${orgId}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+    getDashboard: async (token: string, orgId: number): Promise<ApiResponse<OrganizationDashboard>> => {
+      return fetchAPI<OrganizationDashboard>(`/organizations/${orgId}/dashboard`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    listMembers: async (token: string, orgId: number): Promise<ApiResponse<OrganizationMember[]>> => {
+      return fetchAPI<OrganizationMember[]>(`/organizations/${orgId}/members`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    setMember: async (token: string, orgId: number, username: string, role: OrganizationRole): Promise<ApiResponse<OrganizationMember>> => {
+      return fetchAPI<OrganizationMember>(`/organizations/${orgId}/members`, {
         method: 'POST',
         headers: getHeaders(token),
+        body: JSON.stringify({ username, role }),
+      });
+    },
+    removeMember: async (token: string, orgId: number, userId: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/organizations/${orgId}/members/${userId}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
       });
     },
+    createAccount: async (token: string, orgId: number, name: string): Promise<ApiResponse<Account>> => {
+      return fetchAPI<Account>(`/organizations/${orgId}/accounts`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify({ name }),
+      });
+    },
+  },
+  shareLinks: {
+    list: async (token: string, accountId: number): Promise<ApiResponse<ShareLink[]>> => {
+      return fetchAPI<ShareLink[]>(`/accounts/${accountId}/share-links`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    create: async (
+      token: string,
+      accountId: number,
+      data: { label: string; show_account_name: boolean; show_balances: boolean; show_equity_curve: boolean; show_drawdown: boolean; expires_at?: string }
+    ): Promise<ApiResponse<ShareLink>> => {
+      return fetchAPI<ShareLink>(`/accounts/${accountId}/share-links`, {
+        method: 'POST',
+        headers: getHeaders(token),
+        body: JSON.stringify(data),
+      });
+    },
+    revoke: async (token: string, accountId: number, linkId: number): Promise<ApiResponse<void>> => {
+      return fetchAPI<void>(`/accounts/${accountId}/share-links/${linkId}`, {
+        method: 'DELETE',
+        headers: getHeaders(token),
+      });
+    },
+    // Public, no login required
+    view: async (shareToken: string): Promise<ApiResponse<SharedAccountView>> => {
+      return fetchAPI<SharedAccountView>(`/public/share/${encodeURIComponent(shareToken)}`, {
+        method: 'GET',
+        headers: getHeaders(),
+      });
+    },
+  },
+  audit: {
+    // Require audit:read
+    getEvents: async (token: string, filter: AuditEventFilter, page: number = 1, pageSize: number = 50): Promise<ApiResponse<AuditEvent[]>> => {
+      const query = auditQuery(filter);
+      return fetchAPI<AuditEvent[]>(`/audit-events?page=${page}&page_size=${pageSize}${query ? `&${query}` : ''}`, {
+        method: 'GET',
+        headers: getHeaders(token),
+      });
+    },
+    exportCsv: async (token: string, filter: AuditEventFilter): Promise<Blob | null> => {
+      try {
+        const response = await fetch(`${BASE_URL}/audit-events/export?${auditQuery(filter)}`, {
+          headers: getHeaders(currentToken(token)),
+        });
+        return response.ok ? await response.blob() : null;
+      } catch {
+        return null;
+      }
+    },
   },
   statistics: {
     getAll: async (token: string, accountId: number, page: number = 1, pageSize: number = 20): Promise<ApiResponse<Statistic[]>> => {
@@ -0,0 +1,9 @@
+import { Permission, User } from '../types';
+
+// Whether the user's role grants a permission; the API enforces the same check
+export const hasPermission = (user: User | null | undefined, permission: Permission): boolean =>
+  !!user?.permissions?.includes(permission);
+
+// Landing page for a user: staff who can see users go to the admin console
+export const homePath = (user: User): string =>
+  hasPermission(user, 'users:read') ? '/admin' : '/dashboard';
@@ -15,30 +15,271 @@ export interface ApiResponse<T> {
 }
 
 // User Models
+export type Permission =
+  | 'accounts:read'
+  | 'accounts:write'
+  | 'accounts:delete'
+  | 'accounts:manage_all'
+  | 'users:read'
+  | 'users:manage'
+  | 'users:impersonate'
+  | 'roles:manage'
+  | 'organizations:write'
+  | 'organizations:manage_all'
+  | 'settings:manage'
+  | 'audit:read';
+
+export interface Role {
+  name: string;
+  description: string;
+  permissions: Permission[];
+  built_in: boolean;
+}
+
+export type UserStatus = 'active' | 'suspended' | 'deactivated';
+
 export interface User {
   id: number;
   username: string;
-  role: 'admin' | 'user';
+  role: string; // 'admin', 'user' or a custom role
+  status?: UserStatus;
+  status_changed_at?: string | null;
+  permissions?: Permission[]; // Effective permissions, present on the logged-in user
+  email?: string;
+  email_verified_at?: string | null;
+  digest_enabled?: boolean;
+  must_change_password?: boolean;
+  totp_enabled?: boolean;
   created_at: string;
   updated_at: string;
 }
 
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_at: string;
   user: User;
 }
 
+// Token an admin uses to act as another user; it can't be refreshed
+export interface ImpersonationResponse {
+  token: string;
+  expires_at: string;
+  read_only: boolean;
+  impersonator: string;
+  user: User;
+}
+
+// Running impersonation, with the admin's own login to return to
+export interface ImpersonationSession {
+  read_only: boolean;
+  expires_at: string;
+  impersonator: string;
+  admin: {
+    token: string;
+    refresh_token: string;
+    user: User;
+  };
+}
+
+export interface PasswordPolicy {
+  min_length: number;
+  max_length: number;
+  history_count: number;
+}
+
+export interface TwoFactorChallenge {
+  two_factor_required: boolean;
+  challenge_token: string;
+}
+
 // Account Models
 export interface Account {
   id: number;
   user_id: number;
   name: string;
-  api_token: string;
+  api_token?: string; // Initial token, only present right after creation
+  access_role?: 'viewer' | 'manager'; // Present on accounts shared with the current user
+  organization_id?: number | null;
   created_at: string;
   updated_at: string;
   user?: User; // Present in Admin GET /api/accounts
 }
 
+export type AccountTokenScope = 'statistics:ingest' | 'trades:ingest' | 'heartbeat:ingest' | 'read';
+
+export interface AccountToken {
+  id: number;
+  account_id: number;
+  name: string;
+  token_prefix: string;
+  scopes: AccountTokenScope[];
+  allowed_ips: string[] | null;
+  expires_at: string | null;
+  last_used_at: string | null;
+  last_used_ip: string;
+  token?: string; // Secret, only present right after creation or rotation
+  created_at: string;
+  updated_at: string;
+}
+
+// Invite code that lets people register with a preset role
+export interface Invite {
+  id: number;
+  created_by_id: number;
+  label: string;
+  role: string;
+  code_prefix: string;
+  max_uses: number;
+  use_count: number;
+  expires_at: string;
+  revoked_at: string | null;
+  code?: string; // Only present right after creation
+  url?: string; // Only present right after creation
+  users?: User[]; // Users who registered with this invite
+  created_at: string;
+}
+
+export interface RegisterRequest {
+  invite_code: string;
+  username: string;
+  email?: string;
+  password: string;
+}
+
+// Append-only record of a security- or data-relevant action
+export interface AuditEvent {
+  id: number;
+  actor_id: number | null;
+  actor_name: string;
+  impersonator_id?: number; // Admin acting as the actor
+  impersonator_name?: string;
+  personal_access_token_id?: number; // Token the actor used instead of a login
+  action: string;
+  target_type: string;
+  target_id: number;
+  before?: Record<string, unknown>;
+  after?: Record<string, unknown>;
+  ip: string;
+  request_id: string;
+  created_at: string;
+}
+
+export interface AuditEventFilter {
+  actor_id?: string;
+  impersonator_id?: string;
+  action?: string;
+  target_type?: string;
+  target_id?: string;
+  request_id?: string;
+  from?: string;
+  to?: string;
+}
+
+// Personal access token for scripts, sent as a Bearer token; scopes are permissions
+export interface PersonalAccessToken {
+  id: number;
+  user_id: number;
+  name: string;
+  token_prefix: string;
+  scopes: Permission[];
+  expires_at: string;
+  last_used_at: string | null;
+  last_used_ip: string;
+  token?: string; // Secret, only present right after creation
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AccountMember {
+  id: number;
+  account_id: number;
+  user_id: number;
+  role: 'viewer' | 'manager';
+  granted_by_id: number;
+  user?: User;
+  created_at: string;
+}
+
+export type OrganizationRole = 'owner' | 'manager' | 'analyst' | 'viewer';
+
+export interface Organization {
+  id: number;
+  name: string;
+  max_accounts: number; // 0 means unlimited
+  max_members: number; // 0 means unlimited
+  role?: OrganizationRole; // Current user's role
+  created_at: string;
+  updated_at: string;
+}
+
+export interface OrganizationMember {
+  id: number;
+  organization_id: number;
+  user_id: number;
+  role: OrganizationRole;
+  user?: User;
+  created_at: string;
+}
+
+export interface OrganizationAccountState {
+  account_id: number;
+  name: string;
+  balance: number;
+  daily_pl: number;
+  latest_update: string | null;
+}
+
+// Accounts are only listed for analysts and above
+export interface OrganizationDashboard {
+  organization: Organization;
+  account_count: number;
+  member_count: number;
+  total_balance: number;
+  today_pl: number;
+  accounts?: OrganizationAccountState[];
+}
+
+export interface ShareLink {
+  id: number;
+  account_id: number;
+  created_by_id: number;
+  label: string;
+  token_prefix: string;
+  show_account_name: boolean;
+  show_balances: boolean;
+  show_equity_curve: boolean;
+  show_drawdown: boolean;
+  expires_at: string | null;
+  last_viewed_at: string | null;
+  url?: string; // Only present right after creation
+  created_at: string;
+}
+
+// Public read-only view behind a share link; amounts are absent unless the owner shows balances
+export interface SharedAccountView {
+  account_name?: string;
+  label?: string;
+  has_data: boolean;
+  starting_balance?: number;
+  current_balance?: number;
+  total_return_percent: number;
+  today_pl?: number;
+  today_pl_percent: number;
+  drawdown_percent?: number;
+  max_drawdown_percent?: number;
+  trading_days: number;
+  latest_update: string | null;
+  equity_curve?: {
+    date: string;
+    balance?: number;
+    daily_pl?: number;
+    daily_pl_percent: number;
+    return_percent: number;
+    drawdown_percent?: number;
+  }[];
+}
+
 // Statistic Models
 export interface Statistic {
   id: number;
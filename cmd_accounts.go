@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"x-track/service"
+)
+
+// runCreateAccount creates a trading account and prints it with its initial API token
+func runCreateAccount(args []string) error {
+	const synopsis = "-user <id or username> -name <name>"
+	fs := newFlagSet("create-account")
+	ref := fs.String("user", "", "ID or username of the owner, required")
+	name := fs.String("name", "", "account name, required")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *ref == "" || *name == "" {
+		return flagUsage(fs, synopsis, errors.New("-user and -name are required"))
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(service.NewUserService(db), *ref)
+	if err != nil {
+		return err
+	}
+	account, err := service.NewAccountService(db).CreateAccount(service.SystemActor(), user.ID, *name, nil)
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]interface{}{"account": account})
+}
+
+// runRotateToken replaces an account API token with a new one. The token can be left
+// out when the account has only one.
+func runRotateToken(args []string) error {
+	const synopsis = "-account <id> [-token <id>] [-grace <duration>]"
+	fs := newFlagSet("rotate-token")
+	accountID := fs.Uint("account", 0, "account ID, required")
+	tokenID := fs.Uint("token", 0, "token ID, required when the account has several tokens")
+	grace := fs.Duration("grace", 0, "how long the old token keeps working, e.g. 24h")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *accountID == 0 {
+		return flagUsage(fs, synopsis, errors.New("-account is required"))
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	if _, err := service.NewAccountService(db).GetAccountByID(*accountID); err != nil {
+		return fmt.Errorf("account %d not found", *accountID)
+	}
+	tokenService := service.NewAccountTokenService(db)
+
+	if *tokenID == 0 {
+		tokens, err := tokenService.ListTokens(*accountID)
+		if err != nil {
+			return err
+		}
+		if len(tokens) != 1 {
+			return fmt.Errorf("account %d has %d tokens, choose one with -token", *accountID, len(tokens))
+		}
+		*tokenID = tokens[0].ID
+	}
+
+	token, err := tokenService.RotateToken(service.SystemActor(), *accountID, *tokenID, *grace)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(map[string]interface{}{"token": token, "replaced_token_id": *tokenID})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strconv"
+	"x-track/migrations"
+)
+
+const migrateUsage = `usage: x-track migrate <command>
+
+commands:
+  up [version]   apply pending migrations, up to and including version if given
+  down [steps]   revert the last applied migration, or the last steps migrations
+  status         list migrations and whether they are applied`
+
+// runMigrate applies, reverts or lists schema migrations
+func runMigrate(args []string) error {
+	if len(args) == 0 || len(args) > 2 || (args[0] == "status" && len(args) > 1) {
+		return usageError(migrateUsage)
+	}
+
+	// The argument of up is a version, the one of down a number of steps
+	var n int64
+	switch args[0] {
+	case "up":
+	case "down":
+		n = 1
+	case "status":
+	default:
+		return usageError(migrateUsage)
+	}
+	if len(args) == 2 {
+		var err error
+		if n, err = strconv.ParseInt(args[1], 10, 64); err != nil || n < 1 {
+			return usageError(migrateUsage)
+		}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	migrator := migrations.NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(n)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"applied": nonNil(applied)})
+	case "down":
+		reverted, err := migrator.Down(int(n))
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"reverted": nonNil(reverted)})
+	default:
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"migrations": statuses})
+	}
+}
+
+// nonNil keeps empty results as [] rather than null in the output
+func nonNil(list []migrations.Migration) []migrations.Migration {
+	if list == nil {
+		return []migrations.Migration{}
+	}
+	return list
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"x-track/models"
+	"x-track/service"
+)
+
+// statisticRecord is a statistic in import and export files, named like the ingest API
+type statisticRecord struct {
+	Timestamp    time.Time `json:"timestamp"`
+	DailyPL      float64   `json:"daily_profit_loss"`
+	TradesToday  int       `json:"total_trades_today"`
+	TotalBalance float64   `json:"total_balance"`
+}
+
+// statisticsFile is the JSON import and export format
+type statisticsFile struct {
+	AccountID  uint              `json:"account_id"`
+	Statistics []statisticRecord `json:"statistics"`
+}
+
+var statisticsCSVHeader = []string{"timestamp", "daily_profit_loss", "total_trades_today", "total_balance"}
+
+// runImport imports statistics exported from this or another instance into an account.
+// Timestamps the account already has are skipped, so an import can be repeated.
+func runImport(args []string) error {
+	const synopsis = "-account <id> [-file <path>] [-format json|csv]"
+	fs := newFlagSet("import")
+	accountID := fs.Uint("account", 0, "account ID, required")
+	file := fs.String("file", "-", "file to read, - for stdin")
+	format := fs.String("format", "", "json or csv, by default taken from the file extension or json")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *accountID == 0 {
+		return flagUsage(fs, synopsis, errors.New("-account is required"))
+	}
+	if *format == "" {
+		*format = formatFromPath(*file)
+	}
+	if *format != "json" && *format != "csv" {
+		return flagUsage(fs, synopsis, fmt.Errorf("unknown format %q", *format))
+	}
+
+	in := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var records []statisticRecord
+	var err error
+	if *format == "csv" {
+		records, err = readStatisticsCSV(in)
+	} else {
+		records, err = readStatisticsJSON(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	statistics := make([]models.Statistic, len(records))
+	for i, record := range records {
+		statistics[i] = models.Statistic{
+			Timestamp:    record.Timestamp,
+			DailyPL:      record.DailyPL,
+			TradesToday:  record.TradesToday,
+			TotalBalance: record.TotalBalance,
+		}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	imported, err := service.NewStatisticService(db).ImportStatistics(*accountID, statistics)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(map[string]interface{}{
+		"account_id": *accountID,
+		"read":       len(records),
+		"imported":   imported,
+		"skipped":    len(records) - imported,
+	})
+}
+
+// runExport writes the statistics of an account in chronological order
+func runExport(args []string) error {
+	const synopsis = "-account <id> [-from <date>] [-to <date>] [-file <path>] [-format json|csv]"
+	fs := newFlagSet("export")
+	accountID := fs.Uint("account", 0, "account ID, required")
+	from := fs.String("from", "", "first date or RFC3339 time to include")
+	to := fs.String("to", "", "date or RFC3339 time to stop before")
+	file := fs.String("file", "-", "file to write, - for stdout")
+	format := fs.String("format", "", "json or csv, by default taken from the file extension or json")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *accountID == 0 {
+		return flagUsage(fs, synopsis, errors.New("-account is required"))
+	}
+	if *format == "" {
+		*format = formatFromPath(*file)
+	}
+	if *format != "json" && *format != "csv" {
+		return flagUsage(fs, synopsis, fmt.Errorf("unknown format %q", *format))
+	}
+
+	start, end := time.Unix(0, 0), time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	var err error
+	if *from != "" {
+		if start, err = parseExportTime(*from); err != nil {
+			return flagUsage(fs, synopsis, fmt.Errorf("invalid -from: %w", err))
+		}
+	}
+	if *to != "" {
+		if end, err = parseExportTime(*to); err != nil {
+			return flagUsage(fs, synopsis, fmt.Errorf("invalid -to: %w", err))
+		}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	statistics, err := service.NewStatisticService(db).ExportStatistics(*accountID, start, end)
+	if err != nil {
+		return err
+	}
+
+	records := make([]statisticRecord, len(statistics))
+	for i, statistic := range statistics {
+		records[i] = statisticRecord{
+			Timestamp:    statistic.Timestamp.UTC(),
+			DailyPL:      statistic.DailyPL,
+			TradesToday:  statistic.TradesToday,
+			TotalBalance: statistic.TotalBalance,
+		}
+	}
+
+	out := os.Stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *format == "csv" {
+		err = writeStatisticsCSV(out, records)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(statisticsFile{AccountID: *accountID, Statistics: records})
+	}
+	if err != nil {
+		return err
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"account_id": *accountID, "exported": len(records), "file": *file})
+	}
+	return nil
+}
+
+// formatFromPath picks the file format from the extension, defaulting to JSON
+func formatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// parseExportTime accepts a date, as midnight UTC, or an RFC3339 time
+func parseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func readStatisticsJSON(r io.Reader) ([]statisticRecord, error) {
+	var file statisticsFile
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return file.Statistics, nil
+}
+
+func readStatisticsCSV(r io.Reader) ([]statisticRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(statisticsCSVHeader)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if strings.Join(header, ",") != strings.Join(statisticsCSVHeader, ",") {
+		return nil, fmt.Errorf("invalid CSV header, expected %s", strings.Join(statisticsCSVHeader, ","))
+	}
+
+	var records []statisticRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		var record statisticRecord
+		if record.Timestamp, err = time.Parse(time.RFC3339, row[0]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp, use RFC3339", line)
+		}
+		if record.DailyPL, err = strconv.ParseFloat(row[1], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid daily_profit_loss", line)
+		}
+		if record.TradesToday, err = strconv.Atoi(row[2]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid total_trades_today", line)
+		}
+		if record.TotalBalance, err = strconv.ParseFloat(row[3], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid total_balance", line)
+		}
+		records = append(records, record)
+	}
+}
+
+func writeStatisticsCSV(w io.Writer, records []statisticRecord) error {
+	writer := csv.NewWriter(w)
+	writer.Write(statisticsCSVHeader)
+	for _, record := range records {
+		writer.Write([]string{
+			record.Timestamp.Format(time.RFC3339Nano),
+			strconv.FormatFloat(record.DailyPL, 'f', -1, 64),
+			strconv.Itoa(record.TradesToday),
+			strconv.FormatFloat(record.TotalBalance, 'f', -1, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"x-track/models"
+	"x-track/service"
+)
+
+// runCreateUser creates a user. The password is read from stdin or generated, and
+// must be changed on first login either way.
+func runCreateUser(args []string) error {
+	const synopsis = "-username <name> [-email <address>] [-role <role>] [-password-stdin] [-if-not-exists]"
+	fs := newFlagSet("create-user")
+	username := fs.String("username", "", "username, required")
+	email := fs.String("email", "", "email address, a verification link is sent to it")
+	role := fs.String("role", models.RoleUser, "built-in or custom role")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	ifNotExists := fs.Bool("if-not-exists", false, "succeed without changes when the username is taken")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return flagUsage(fs, synopsis, errors.New("-username is required"))
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	userService := service.NewUserService(db)
+
+	if *ifNotExists {
+		if user, err := userService.GetUserByUsername(*username); err == nil {
+			return printJSON(map[string]interface{}{"created": false, "user": user})
+		}
+	}
+
+	password, generated, err := readPassword(db, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	user, err := userService.CreateUser(service.SystemActor(), *username, *email, password, *role)
+	if err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{"created": true, "user": user}
+	if generated {
+		result["password"] = password
+	}
+	return printJSON(result)
+}
+
+// runResetPassword sets a new password for a user, ends their sessions and unlocks
+// them if failed logins locked them out
+func runResetPassword(args []string) error {
+	const synopsis = "-user <id or username> [-password-stdin]"
+	fs := newFlagSet("reset-password")
+	ref := fs.String("user", "", "ID or username of the user, required")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	if err := parseFlags(fs, synopsis, args); err != nil {
+		return err
+	}
+	if *ref == "" {
+		return flagUsage(fs, synopsis, errors.New("-user is required"))
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	userService := service.NewUserService(db)
+
+	user, err := findUser(userService, *ref)
+	if err != nil {
+		return err
+	}
+	password, generated, err := readPassword(db, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	if user, err = userService.UpdateUser(service.SystemActor(), user.ID, "", "", password, ""); err != nil {
+		return err
+	}
+
+	unlocked := false
+	if user.LockedUntil != nil || user.FailedLoginCount > 0 {
+		if err := service.NewLoginProtectionService(db).Unlock(service.SystemActor(), user.ID); err != nil {
+			return errors.New("password was reset but unlocking failed: " + err.Error())
+		}
+		user.LockedUntil = nil
+		unlocked = true
+	}
+
+	result := map[string]interface{}{"user": user, "unlocked": unlocked}
+	if generated {
+		result["password"] = password
+	}
+	return printJSON(result)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,12 +15,26 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
-	Admin    AdminConfig
+	SMTP     SMTPConfig
+	Digest   DigestConfig
+	Alerts   AlertsConfig
+	Events   EventsConfig
+	Login    LoginConfig
+	Password PasswordConfig
+	OIDC     OIDCConfig
+	CORS     CORSConfig
 }
 
 type ServerConfig struct {
 	Port    string
 	GinMode string
+	BaseURL string
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed; none by default, so
+	// clients can't choose the IP that lockouts, allowlists and the audit log see
+	TrustedProxies []string
+	// Header carrying the client IP set by the hosting platform, e.g. X-Vercel-Forwarded-For
+	// or CF-Connecting-IP. Only set it when the platform overwrites the header.
+	TrustedPlatform string
 }
 
 type DatabaseConfig struct {
@@ -27,17 +43,94 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	DBName   string
-	SSLMode	 string
+	SSLMode  string
+	// MigrateOnStart applies pending schema migrations when the server starts
+	MigrateOnStart bool
 }
 
 type JWTConfig struct {
-	Secret          string
-	ExpirationHours int
+	Algorithm          string // RS256 or EdDSA, used for newly generated signing keys
+	Issuer             string
+	Audience           string
+	KeyRotationDays    int    // how long a signing key signs tokens before its successor takes over
+	KeyEncryptionKey   string // encrypts private signing keys stored in the database, required in release mode
+	AccessTokenMinutes int
+	RefreshTokenDays   int
 }
 
-type AdminConfig struct {
+type SMTPConfig struct {
+	Host     string
+	Port     string
 	Username string
 	Password string
+	From     string
+}
+
+type LoginConfig struct {
+	MaxFailures    int // failures per user before lockout
+	LockoutMinutes int
+	MaxIPFailures  int // failures per IP within the window before blocking
+	WindowMinutes  int
+}
+
+type PasswordConfig struct {
+	MinLength         int
+	CheckCommon       bool   // reject passwords on the bundled common-password list
+	HistoryCount      int    // recent passwords, including the current one, that can't be reused; 0 allows reuse
+	HashAlgorithm     string // bcrypt or argon2id
+	BcryptCost        int
+	Argon2Memory      int // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+// OIDCConfig configures single sign-on with an OpenID Connect identity provider
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string // empty for public clients, which rely on PKCE alone
+	RedirectURL   string // client page that receives the authorization code
+	Scopes        []string
+	ProviderName  string // shown on the login button
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMapping   []OIDCRoleMapping // first matching group wins
+	DefaultRole   string            // role of provisioned users no group maps to
+	AutoProvision bool              // create users on first login
+	LinkBy        []string          // existing-user matching: email (verified only) and/or username
+	// Skip X-Track two-factor authentication for SSO logins; only set when the provider enforces MFA itself
+	IdPEnforcesMFA bool
+}
+
+// OIDCRoleMapping maps an identity provider group to an X-Track role
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
+}
+
+// CORSConfig controls which browser origins may call the API
+type CORSConfig struct {
+	AllowedOrigins      []string // exact origins or glob patterns such as https://*.vercel.app; * allows any origin
+	AllowedMethods      []string
+	AllowedHeaders      []string
+	ExposedHeaders      []string
+	AllowCredentials    bool
+	MaxAge              int  // seconds browsers may cache a preflight response
+	IngestAllowBrowsers bool // allow browser origins on the ingest routes, which are meant for servers
+}
+
+type EventsConfig struct {
+	Backend string // memory or postgres
+}
+
+type DigestConfig struct {
+	Time     string // HH:MM, end of trading day
+	Timezone string
+}
+
+type AlertsConfig struct {
+	// Drawdown from the peak balance, in percent, that raises an alert; 0 turns it off
+	DrawdownPercent int
 }
 
 var AppConfig *Config
@@ -49,38 +142,184 @@ func LoadConfig() (*Config, error) {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	expirationHours, err := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
-	if err != nil {
-		expirationHours = 24
-	}
-
 	config := &Config{
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:            getEnv("PORT", "8080"),
+			GinMode:         getEnv("GIN_MODE", "debug"),
+			BaseURL:         getEnv("APP_BASE_URL", "http://localhost:5173"),
+			TrustedProxies:  getEnvList("TRUSTED_PROXIES", nil),
+			TrustedPlatform: getEnv("TRUSTED_PLATFORM_HEADER", ""),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "xtrack"),
-			SSLMode:   getEnv("SSLMODE", "require"),
+			Host:           getEnv("DB_HOST", "localhost"),
+			Port:           getEnv("DB_PORT", "5432"),
+			User:           getEnv("DB_USER", "postgres"),
+			Password:       getEnv("DB_PASSWORD", "postgres"),
+			DBName:         getEnv("DB_NAME", "xtrack"),
+			SSLMode:        getEnv("SSLMODE", "require"),
+			MigrateOnStart: getEnvBool("MIGRATE_ON_START", false),
 		},
 		JWT: JWTConfig{
-			Secret:          getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
-			ExpirationHours: expirationHours,
+			Algorithm:          getEnv("JWT_ALGORITHM", "RS256"),
+			Issuer:             getEnv("JWT_ISSUER", "x-track"),
+			Audience:           getEnv("JWT_AUDIENCE", "x-track"),
+			KeyRotationDays:    getEnvInt("JWT_KEY_ROTATION_DAYS", 30),
+			KeyEncryptionKey:   getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+			AccessTokenMinutes: getEnvInt("JWT_ACCESS_TOKEN_MINUTES", 15),
+			RefreshTokenDays:   getEnvInt("JWT_REFRESH_TOKEN_DAYS", 30),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "X-Track <no-reply@x-track.local>"),
+		},
+		Digest: DigestConfig{
+			Time:     getEnv("DIGEST_TIME", "22:00"),
+			Timezone: getEnv("DIGEST_TIMEZONE", "UTC"),
+		},
+		Alerts: AlertsConfig{
+			DrawdownPercent: getEnvInt("ALERT_DRAWDOWN_PERCENT", 0),
+		},
+		Events: EventsConfig{
+			Backend: getEnv("EVENT_BUS", "memory"),
 		},
-		Admin: AdminConfig{
-			Username: getEnv("ADMIN_USERNAME", "admin"),
-			Password: getEnv("ADMIN_PASSWORD", "admin123"),
+		Login: LoginConfig{
+			MaxFailures:    getEnvInt("LOGIN_MAX_FAILURES", 5),
+			LockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+			MaxIPFailures:  getEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+			WindowMinutes:  getEnvInt("LOGIN_WINDOW_MINUTES", 15),
+		},
+		Password: PasswordConfig{
+			MinLength:         getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			CheckCommon:       getEnvBool("PASSWORD_CHECK_COMMON", true),
+			HistoryCount:      getEnvInt("PASSWORD_HISTORY", 5),
+			HashAlgorithm:     getEnv("PASSWORD_HASH", "bcrypt"),
+			BcryptCost:        getEnvInt("PASSWORD_BCRYPT_COST", 12),
+			Argon2Memory:      getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+			Argon2Iterations:  getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: getEnvInt("PASSWORD_ARGON2_PARALLELISM", 4),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:      strings.TrimSuffix(getEnv("OIDC_ISSUER_URL", ""), "/"),
+			ClientID:       getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:    getEnv("OIDC_REDIRECT_URL", getEnv("APP_BASE_URL", "http://localhost:5173")+"/login/sso"),
+			Scopes:         getEnvList("OIDC_SCOPES", []string{"openid", "profile", "email"}),
+			ProviderName:   getEnv("OIDC_PROVIDER_NAME", "SSO"),
+			UsernameClaim:  getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+			GroupsClaim:    getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			RoleMapping:    parseRoleMapping(getEnv("OIDC_ROLE_MAPPING", "")),
+			DefaultRole:    getEnv("OIDC_DEFAULT_ROLE", "user"),
+			AutoProvision:  getEnvBool("OIDC_AUTO_PROVISION", true),
+			LinkBy:         getEnvList("OIDC_LINK_BY", []string{"email"}),
+			IdPEnforcesMFA: getEnvBool("OIDC_IDP_ENFORCES_MFA", false),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:      getEnvList("CORS_ALLOWED_ORIGINS", []string{strings.TrimSuffix(getEnv("APP_BASE_URL", "http://localhost:5173"), "/")}),
+			AllowedMethods:      getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders:      getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Accept", "Cache-Control", "X-Requested-With", "X-API-Token", "X-Request-ID"}),
+			ExposedHeaders:      getEnvList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Content-Disposition", "Retry-After"}),
+			AllowCredentials:    getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:              getEnvInt("CORS_MAX_AGE", 600),
+			IngestAllowBrowsers: getEnvBool("CORS_INGEST_ALLOW_BROWSERS", false),
 		},
 	}
 
+	if config.JWT.Algorithm != "RS256" && config.JWT.Algorithm != "EdDSA" {
+		return nil, fmt.Errorf("JWT_ALGORITHM must be RS256 or EdDSA, got %q", config.JWT.Algorithm)
+	}
+	if config.JWT.KeyRotationDays < 1 {
+		return nil, fmt.Errorf("JWT_KEY_ROTATION_DAYS must be at least 1")
+	}
+	if config.JWT.KeyEncryptionKey == "" {
+		if config.Server.GinMode == "release" {
+			return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY is required in release mode, private signing keys would be stored unencrypted")
+		}
+		log.Println("JWT_KEY_ENCRYPTION_KEY is not set: private signing keys are stored unencrypted")
+	}
+	if err := config.Password.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.CORS.validate(); err != nil {
+		return nil, err
+	}
+	if os.Getenv("JWT_SECRET") != "" {
+		log.Println("JWT_SECRET is no longer used: tokens are signed with rotating keys, see JWT_ALGORITHM")
+	}
+	if os.Getenv("JWT_EXPIRATION_HOURS") != "" {
+		log.Println("JWT_EXPIRATION_HOURS is no longer used: see JWT_ACCESS_TOKEN_MINUTES and JWT_REFRESH_TOKEN_DAYS")
+	}
+
 	AppConfig = config
 	return config, nil
 }
 
+// Enabled reports whether an SMTP server has been configured
+func (c *SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Addr returns the SMTP server address
+func (c *SMTPConfig) Addr() string {
+	return c.Host + ":" + c.Port
+}
+
+// Enabled reports whether single sign-on has been configured
+func (c *OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
+
+// validate checks the password settings are usable
+func (c *PasswordConfig) validate() error {
+	if c.MinLength < 1 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at least 1")
+	}
+	switch c.HashAlgorithm {
+	case "bcrypt":
+		if c.BcryptCost < 10 || c.BcryptCost > 31 {
+			return fmt.Errorf("PASSWORD_BCRYPT_COST must be between 10 and 31")
+		}
+	case "argon2id":
+		if c.Argon2Memory < 8*1024 || c.Argon2Iterations < 1 || c.Argon2Parallelism < 1 || c.Argon2Parallelism > 255 {
+			return fmt.Errorf("PASSWORD_ARGON2_MEMORY must be at least 8192 KiB, PASSWORD_ARGON2_ITERATIONS at least 1 and PASSWORD_ARGON2_PARALLELISM between 1 and 255")
+		}
+	default:
+		return fmt.Errorf("PASSWORD_HASH must be bcrypt or argon2id, got %q", c.HashAlgorithm)
+	}
+	return nil
+}
+
+// validate checks the CORS settings are usable
+func (c *CORSConfig) validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" && c.AllowCredentials {
+			return fmt.Errorf("CORS_ALLOWED_ORIGINS can't contain * when CORS_ALLOW_CREDENTIALS is enabled")
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return fmt.Errorf("invalid CORS_ALLOWED_ORIGINS pattern %q: %w", origin, err)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("CORS_MAX_AGE must not be negative")
+	}
+	return nil
+}
+
+// parseRoleMapping parses "group=role,group=role" pairs
+func parseRoleMapping(value string) []OIDCRoleMapping {
+	var mappings []OIDCRoleMapping
+	for _, pair := range strings.Split(value, ",") {
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(role) == "" {
+			continue
+		}
+		mappings = append(mappings, OIDCRoleMapping{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+	}
+	return mappings
+}
+
 // GetDSN returns the database connection string
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
@@ -95,3 +334,34 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList reads a comma-separated list
+func getEnvList(key string, defaultValue []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
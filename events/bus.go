@@ -0,0 +1,40 @@
+package events
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBus is the process-wide event bus
+var DefaultBus Bus = NewMemoryBus()
+
+// Init configures the process-wide event bus for the given backend
+func Init(backend string, db *gorm.DB, dsn string) error {
+	switch backend {
+	case "", "memory":
+		DefaultBus = NewMemoryBus()
+	case "postgres":
+		DefaultBus = NewPostgresBus(db, dsn)
+	default:
+		return fmt.Errorf("unknown event bus backend %q, use 'memory' or 'postgres'", backend)
+	}
+
+	log.Printf("Event bus backend: %s", backend)
+	return nil
+}
+
+// Publish creates an event and publishes it on the default bus
+func Publish(eventType string, accountID uint, payload interface{}) error {
+	event, err := New(eventType, accountID, payload)
+	if err != nil {
+		return err
+	}
+	return DefaultBus.Publish(event)
+}
+
+// Subscribe registers a handler on the default bus
+func Subscribe(handler Handler) func() {
+	return DefaultBus.Subscribe(handler)
+}
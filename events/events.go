@@ -0,0 +1,86 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+	"x-track/utils"
+)
+
+// Domain event types
+const (
+	StatisticIngested   = "statistic.ingested"
+	HeartbeatReceived   = "heartbeat.received"
+	AlertRaised         = "alert.raised"
+	SessionRevoked      = "session.revoked"
+	UserSecurityChanged = "user.security_changed"
+	RoleChanged         = "role.changed"
+)
+
+// InstanceID identifies this process so subscribers can tell local events from remote ones
+var InstanceID = newInstanceID()
+
+// Event is a domain event published on the bus
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	AccountID uint            `json:"account_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Origin    string          `json:"origin"`
+	Time      time.Time       `json:"time"`
+}
+
+// Heartbeat is the payload of a HeartbeatReceived event
+type Heartbeat struct {
+	Time time.Time `json:"time"`
+}
+
+// Alert is the payload of an AlertRaised event
+type Alert struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Handler processes an event delivered by the bus
+type Handler func(Event)
+
+// Bus publishes domain events and delivers them to subscribers
+type Bus interface {
+	Publish(event Event) error
+	Subscribe(handler Handler) (unsubscribe func())
+	Close() error
+}
+
+// New creates an event originating from this instance
+func New(eventType string, accountID uint, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	id, err := utils.GenerateSecureToken(8)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:        id,
+		Type:      eventType,
+		AccountID: accountID,
+		Payload:   data,
+		Origin:    InstanceID,
+		Time:      time.Now(),
+	}, nil
+}
+
+// IsLocal reports whether the event was published by this instance
+func (e Event) IsLocal() bool {
+	return e.Origin == InstanceID
+}
+
+func newInstanceID() string {
+	id, err := utils.GenerateSecureToken(8)
+	if err != nil {
+		panic("failed to generate instance ID: " + err.Error())
+	}
+	return id
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"sync"
+)
+
+// MemoryBus delivers events to subscribers within a single process
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	nextID   int
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		handlers: make(map[int]Handler),
+	}
+}
+
+// Publish delivers an event to every subscriber
+func (b *MemoryBus) Publish(event Event) error {
+	b.dispatch(event)
+	return nil
+}
+
+// Subscribe registers a handler and returns a function that removes it
+func (b *MemoryBus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
+
+// Close removes all subscribers
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	b.handlers = make(map[int]Handler)
+	b.mu.Unlock()
+	return nil
+}
+
+// dispatch calls every handler; handlers must not block
+func (b *MemoryBus) dispatch(event Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
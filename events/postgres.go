@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// postgresChannel is the LISTEN/NOTIFY channel shared by all instances
+const postgresChannel = "xtrack_events"
+
+// maxNotifyPayload is the largest payload Postgres accepts in a notification
+const maxNotifyPayload = 7999
+
+// ErrEventTooLarge is returned for events too large to send as a Postgres notification
+var ErrEventTooLarge = errors.New("event is too large for the postgres event bus")
+
+// PostgresBus delivers events to every instance through Postgres LISTEN/NOTIFY.
+// Events published while an instance is reconnecting are not replayed to it.
+type PostgresBus struct {
+	db     *gorm.DB
+	dsn    string
+	local  *MemoryBus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPostgresBus starts listening for notifications on a dedicated connection
+func NewPostgresBus(db *gorm.DB, dsn string) *PostgresBus {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &PostgresBus{
+		db:     db,
+		dsn:    dsn,
+		local:  NewMemoryBus(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go b.listen(ctx)
+
+	return b
+}
+
+// Publish sends an event to all instances, including this one. Events published inside a
+// transaction are sent with the bus's own connection, so use Transaction and PublishWith
+// to hold them back until the transaction commits.
+func (b *PostgresBus) Publish(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("%w: %s event is %d bytes, the limit is %d", ErrEventTooLarge, event.Type, len(payload), maxNotifyPayload)
+	}
+	return b.db.Exec("SELECT pg_notify(?, ?)", postgresChannel, string(payload)).Error
+}
+
+// Subscribe registers a handler for events from every instance
+func (b *PostgresBus) Subscribe(handler Handler) func() {
+	return b.local.Subscribe(handler)
+}
+
+// Close stops listening and removes all subscribers
+func (b *PostgresBus) Close() error {
+	b.cancel()
+	<-b.done
+	return b.local.Close()
+}
+
+// listen keeps a LISTEN connection open, reconnecting with backoff until ctx is cancelled
+func (b *PostgresBus) listen(ctx context.Context) {
+	defer close(b.done)
+
+	backoff := time.Second
+	for {
+		if err := b.receive(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Event bus: listener disconnected: %v (retrying in %s)", err, backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// receive dispatches notifications from a single connection until it fails
+func (b *PostgresBus) receive(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		return err
+	}
+	log.Printf("Event bus: listening on %s", postgresChannel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("Event bus: dropping malformed event: %v", err)
+			continue
+		}
+		b.local.dispatch(event)
+	}
+}
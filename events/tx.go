@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// pendingKey is the context key of the events held back by a transaction
+type pendingKey struct{}
+
+// pendingEvents are events published inside a transaction that hasn't committed yet
+type pendingEvents struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Transaction runs fn in a database transaction. Events published with PublishWith on the
+// transaction's handle are held back until it commits, and dropped if it rolls back. A
+// transaction nested in another hands its events to the outer one once it succeeds.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	outer, nested := ctx.Value(pendingKey{}).(*pendingEvents)
+	pending := &pendingEvents{}
+
+	err := db.WithContext(context.WithValue(ctx, pendingKey{}, pending)).Transaction(fn)
+	if err != nil {
+		return err
+	}
+
+	if nested {
+		outer.mu.Lock()
+		outer.events = append(outer.events, pending.events...)
+		outer.mu.Unlock()
+		return nil
+	}
+
+	for _, event := range pending.events {
+		if err := DefaultBus.Publish(event); err != nil {
+			log.Printf("Failed to publish %s event: %v", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// PublishWith creates an event and publishes it on the default bus, or, when db belongs to
+// a Transaction, once that transaction commits
+func PublishWith(db *gorm.DB, eventType string, accountID uint, payload interface{}) error {
+	event, err := New(eventType, accountID, payload)
+	if err != nil {
+		return err
+	}
+
+	if ctx := db.Statement.Context; ctx != nil {
+		if pending, ok := ctx.Value(pendingKey{}).(*pendingEvents); ok {
+			pending.mu.Lock()
+			pending.events = append(pending.events, event)
+			pending.mu.Unlock()
+			return nil
+		}
+	}
+	return DefaultBus.Publish(event)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTransaction(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name string
+		// outer and inner return the error their transaction ends with
+		outer, inner error
+		want         []string
+	}{
+		{name: "committed", want: []string{"outer", "inner"}},
+		{name: "outer rolls back", outer: errRollback},
+		{name: "inner rolls back", inner: errRollback, want: []string{"outer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bus := NewMemoryBus()
+			previous := DefaultBus
+			DefaultBus = bus
+			defer func() { DefaultBus = previous }()
+
+			var published []string
+			bus.Subscribe(func(event Event) { published = append(published, event.Type) })
+
+			Transaction(db, func(tx *gorm.DB) error {
+				if err := PublishWith(tx, "outer", 0, nil); err != nil {
+					t.Fatal(err)
+				}
+				Transaction(tx, func(tx *gorm.DB) error {
+					if err := PublishWith(tx, "inner", 0, nil); err != nil {
+						t.Fatal(err)
+					}
+					return tt.inner
+				})
+				if len(published) != 0 {
+					t.Errorf("published %v before the outer transaction committed", published)
+				}
+				return tt.outer
+			})
+
+			if len(published) != len(tt.want) {
+				t.Fatalf("published %v, want %v", published, tt.want)
+			}
+			for i := range tt.want {
+				if published[i] != tt.want[i] {
+					t.Errorf("published %v, want %v", published, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
+	"x-track/middleware"
+	"x-track/models"
 	"x-track/service"
 	"x-track/utils"
 
@@ -48,17 +51,15 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	// Check authorization
-	role, _ := c.Get("role")
 	currentUserID, _ := c.Get("user_id")
 
-	// Only admin can create accounts for other users
-	if role != "admin" && req.UserID != currentUserID.(uint) {
+	// Only users allowed to manage all accounts can create accounts for other users
+	if req.UserID != currentUserID.(uint) && !middleware.Can(c, models.PermAccountsManageAll) {
 		utils.ErrorResponse(c, 403, "You can only create accounts for yourself")
 		return
 	}
 
-	account, err := h.accountService.CreateAccount(req.UserID, req.Name)
+	account, err := h.accountService.CreateAccount(requestActor(c), req.UserID, req.Name, nil)
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
@@ -67,9 +68,9 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 	utils.SuccessResponse(c, 201, "Account created successfully", account)
 }
 
-// GetAllAccounts retrieves all accounts (admin only)
+// GetAllAccounts retrieves all accounts (requires accounts:manage_all)
 // @Summary Get all accounts
-// @Description Retrieve all trading accounts (admin only)
+// @Description Retrieve all trading accounts (requires accounts:manage_all)
 // @Tags accounts
 // @Produce json
 // @Security BearerAuth
@@ -107,6 +108,27 @@ func (h *AccountHandler) GetMyAccounts(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "Accounts retrieved successfully", accounts)
 }
 
+// GetSharedAccounts retrieves accounts shared with the current user
+// @Summary Get accounts shared with me
+// @Description Retrieve accounts other users granted the current user viewer or manager access to
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/accounts/shared [get]
+func (h *AccountHandler) GetSharedAccounts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	accounts, err := h.accountService.GetSharedAccounts(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve accounts")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Accounts retrieved successfully", accounts)
+}
+
 // GetAccount retrieves an account by ID
 // @Summary Get account by ID
 // @Description Retrieve account details by ID
@@ -118,24 +140,9 @@ func (h *AccountHandler) GetMyAccounts(c *gin.Context) {
 // @Failure 404 {object} utils.Response
 // @Router /api/accounts/{id} [get]
 func (h *AccountHandler) GetAccount(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.ErrorResponse(c, 400, "Invalid account ID")
-		return
-	}
-
-	account, err := h.accountService.GetAccountByID(uint(id))
-	if err != nil {
-		utils.ErrorResponse(c, 404, "Account not found")
-		return
-	}
-
 	// Check authorization
-	role, _ := c.Get("role")
-	userID, _ := c.Get("user_id")
-
-	if role != "admin" && account.UserID != userID.(uint) {
-		utils.ErrorResponse(c, 403, "Access denied")
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessViewer)
+	if !ok {
 		return
 	}
 
@@ -155,24 +162,9 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 // @Failure 400 {object} utils.Response
 // @Router /api/accounts/{id} [put]
 func (h *AccountHandler) UpdateAccount(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.ErrorResponse(c, 400, "Invalid account ID")
-		return
-	}
-
 	// Check authorization
-	account, err := h.accountService.GetAccountByID(uint(id))
-	if err != nil {
-		utils.ErrorResponse(c, 404, "Account not found")
-		return
-	}
-
-	role, _ := c.Get("role")
-	userID, _ := c.Get("user_id")
-
-	if role != "admin" && account.UserID != userID.(uint) {
-		utils.ErrorResponse(c, 403, "Access denied")
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessManager)
+	if !ok {
 		return
 	}
 
@@ -182,7 +174,7 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err = h.accountService.UpdateAccount(uint(id), req.Name)
+	account, err := h.accountService.UpdateAccount(requestActor(c), account.ID, req.Name)
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
@@ -191,83 +183,54 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "Account updated successfully", account)
 }
 
-// RegenerateToken regenerates API token for an account
-// @Summary Regenerate API token
-// @Description Generate a new API token for an account
+// DeleteAccount deletes an account
+// @Summary Delete account
+// @Description Delete a trading account
 // @Tags accounts
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Account ID"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
-// @Router /api/accounts/{id}/regenerate-token [post]
-func (h *AccountHandler) RegenerateToken(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.ErrorResponse(c, 400, "Invalid account ID")
-		return
-	}
-
+// @Router /api/accounts/{id} [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 	// Check authorization
-	account, err := h.accountService.GetAccountByID(uint(id))
-	if err != nil {
-		utils.ErrorResponse(c, 404, "Account not found")
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
 		return
 	}
 
-	role, _ := c.Get("role")
-	userID, _ := c.Get("user_id")
-
-	if role != "admin" && account.UserID != userID.(uint) {
-		utils.ErrorResponse(c, 403, "Access denied")
-		return
-	}
-
-	account, err = h.accountService.RegenerateToken(uint(id))
-	if err != nil {
-		utils.ErrorResponse(c, 400, "Failed to regenerate token")
+	if err := h.accountService.DeleteAccount(requestActor(c), account.ID); err != nil {
+		utils.ErrorResponse(c, 400, "Failed to delete account")
 		return
 	}
 
-	utils.SuccessResponse(c, 200, "Token regenerated successfully", account)
+	utils.SuccessResponse(c, 200, "Account deleted successfully", nil)
 }
 
-// DeleteAccount deletes an account
-// @Summary Delete account
-// @Description Delete a trading account
-// @Tags accounts
-// @Produce json
-// @Security BearerAuth
-// @Param id path int true "Account ID"
-// @Success 200 {object} utils.Response
-// @Failure 400 {object} utils.Response
-// @Router /api/accounts/{id} [delete]
-func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+// authorizeAccount parses the account ID from the path and checks the current user
+// has at least the required access to it, writing the error response if not
+func authorizeAccount(c *gin.Context, accountService *service.AccountService, required service.AccountAccess) (*models.Account, bool) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.ErrorResponse(c, 400, "Invalid account ID")
-		return
-	}
-
-	// Check authorization
-	account, err := h.accountService.GetAccountByID(uint(id))
-	if err != nil {
-		utils.ErrorResponse(c, 404, "Account not found")
-		return
+		return nil, false
 	}
 
-	role, _ := c.Get("role")
 	userID, _ := c.Get("user_id")
 
-	if role != "admin" && account.UserID != userID.(uint) {
+	account, err := accountService.CheckAccess(uint(id), userID.(uint), middleware.Can(c, models.PermAccountsManageAll), required)
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound):
+		utils.ErrorResponse(c, 404, "Account not found")
+		return nil, false
+	case errors.Is(err, service.ErrAccountAccessDenied):
 		utils.ErrorResponse(c, 403, "Access denied")
-		return
+		return nil, false
+	case err != nil:
+		utils.ErrorResponse(c, 500, "Failed to check account access")
+		return nil, false
 	}
 
-	if err := h.accountService.DeleteAccount(uint(id)); err != nil {
-		utils.ErrorResponse(c, 400, "Failed to delete account")
-		return
-	}
-
-	utils.SuccessResponse(c, 200, "Account deleted successfully", nil)
+	return account, true
 }
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"strconv"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountMemberHandler struct {
+	memberService  *service.AccountMemberService
+	accountService *service.AccountService
+}
+
+func NewAccountMemberHandler(db *gorm.DB) *AccountMemberHandler {
+	return &AccountMemberHandler{
+		memberService:  service.NewAccountMemberService(db),
+		accountService: service.NewAccountService(db),
+	}
+}
+
+// GrantAccountAccessRequest represents the grant account access request
+type GrantAccountAccessRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required,oneof=viewer manager"`
+}
+
+// ListMembers lists the users an account is shared with
+// @Summary List account members
+// @Description List users granted viewer or manager access to an account (owner only)
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/accounts/{id}/members [get]
+func (h *AccountMemberHandler) ListMembers(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	members, err := h.memberService.ListMembers(account.ID)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve members")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Members retrieved successfully", members)
+}
+
+// GrantAccess shares an account with another user
+// @Summary Share account with a user
+// @Description Grant a user viewer (read-only) or manager (read, rename, API tokens) access, or change their role (owner only)
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param member body GrantAccountAccessRequest true "User and role"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/accounts/{id}/members [post]
+func (h *AccountMemberHandler) GrantAccess(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	var req GrantAccountAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	member, err := h.memberService.GrantAccess(requestActor(c), account.ID, req.Username, req.Role)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Access granted successfully", member)
+}
+
+// RevokeAccess removes a user's access to an account
+// @Summary Revoke account access
+// @Description Stop sharing an account with a user (owner only)
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/accounts/{id}/members/{user_id} [delete]
+func (h *AccountMemberHandler) RevokeAccess(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	if err := h.memberService.RevokeAccess(requestActor(c), account.ID, uint(memberID)); err != nil {
+		utils.ErrorResponse(c, 404, "Member not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Access revoked successfully", nil)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountTokenHandler struct {
+	tokenService   *service.AccountTokenService
+	accountService *service.AccountService
+}
+
+func NewAccountTokenHandler(db *gorm.DB) *AccountTokenHandler {
+	return &AccountTokenHandler{
+		tokenService:   service.NewAccountTokenService(db),
+		accountService: service.NewAccountService(db),
+	}
+}
+
+// CreateAccountTokenRequest represents the create account token request
+type CreateAccountTokenRequest struct {
+	Name       string     `json:"name" binding:"required,max=100"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	AllowedIPs []string   `json:"allowed_ips"`
+}
+
+// RotateAccountTokenRequest represents the rotate account token request
+type RotateAccountTokenRequest struct {
+	GracePeriodMinutes *int `json:"grace_period_minutes" binding:"omitempty,min=0"`
+}
+
+// defaultTokenRotationGrace is how long a rotated token keeps working when no grace period is given
+const defaultTokenRotationGrace = 24 * time.Hour
+
+// ListTokens lists the API tokens of an account
+// @Summary List account API tokens
+// @Description List API tokens of an account; secrets are never included
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/accounts/{id}/tokens [get]
+func (h *AccountTokenHandler) ListTokens(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessManager)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.tokenService.ListTokens(account.ID)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve tokens")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Tokens retrieved successfully", tokens)
+}
+
+// CreateToken issues a new API token for an account
+// @Summary Create account API token
+// @Description Issue a named, scoped API token. The secret is only returned in this response.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param token body CreateAccountTokenRequest true "Token details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/accounts/{id}/tokens [post]
+func (h *AccountTokenHandler) CreateToken(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessManager)
+	if !ok {
+		return
+	}
+
+	var req CreateAccountTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(requestActor(c), account.ID, req.Name, req.Scopes, req.ExpiresAt, req.AllowedIPs)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Token created successfully", token)
+}
+
+// RotateToken replaces an API token, keeping the old one valid for a grace period
+// @Summary Rotate account API token
+// @Description Issue a replacement token with the same settings. The old token keeps working for the grace period (default 24 hours, 0 revokes it immediately).
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param token_id path int true "Token ID"
+// @Param rotation body RotateAccountTokenRequest false "Grace period"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/accounts/{id}/tokens/{token_id}/rotate [post]
+func (h *AccountTokenHandler) RotateToken(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessManager)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid token ID")
+		return
+	}
+
+	var req RotateAccountTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	grace := defaultTokenRotationGrace
+	if req.GracePeriodMinutes != nil {
+		grace = time.Duration(*req.GracePeriodMinutes) * time.Minute
+	}
+
+	token, err := h.tokenService.RotateToken(requestActor(c), account.ID, uint(tokenID), grace)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Token rotated successfully", token)
+}
+
+// RevokeToken deletes an API token
+// @Summary Revoke account API token
+// @Description Revoke an API token immediately
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param token_id path int true "Token ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/accounts/{id}/tokens/{token_id} [delete]
+func (h *AccountTokenHandler) RevokeToken(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessManager)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid token ID")
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(requestActor(c), account.ID, uint(tokenID)); err != nil {
+		utils.ErrorResponse(c, 404, "Token not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Token revoked successfully", nil)
+}
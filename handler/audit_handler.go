@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{
+		auditService: service.NewAuditService(db),
+	}
+}
+
+// requestActor identifies the caller of a request for the audit log; it has no user
+// on public routes such as login
+func requestActor(c *gin.Context) service.Actor {
+	actor := service.Actor{
+		Username:  c.GetString("username"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString("request_id"),
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		actor.UserID = userID.(uint)
+	}
+	if permissions, ok := c.Get("permissions"); ok {
+		actor.Permissions, _ = permissions.(models.PermissionSet)
+	}
+	if impersonatorID, ok := c.Get("impersonator_id"); ok {
+		actor.ImpersonatorID = impersonatorID.(uint)
+		actor.ImpersonatorName = c.GetString("impersonator_username")
+	}
+	if tokenID, ok := c.Get("personal_access_token_id"); ok {
+		actor.PersonalAccessTokenID = tokenID.(uint)
+	}
+	return actor
+}
+
+// GetEvents retrieves audit events (requires audit:read)
+// @Summary Get audit events
+// @Description Retrieve audit events, newest first, optionally filtered
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "Actor user ID"
+// @Param impersonator_id query int false "Impersonating admin user ID"
+// @Param personal_access_token_id query int false "Personal access token ID"
+// @Param action query string false "Action, e.g. user.update"
+// @Param target_type query string false "Target type, e.g. user"
+// @Param target_id query int false "Target ID"
+// @Param request_id query string false "Request ID"
+// @Param from query string false "Start time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "End time (RFC 3339 or YYYY-MM-DD, inclusive)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.Response
+// @Router /api/audit-events [get]
+func (h *AuditHandler) GetEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	// Parse pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	events, pagination, err := h.auditService.GetEvents(filter, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve audit events")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, 200, "Audit events retrieved successfully", events, *pagination)
+}
+
+// ExportEvents downloads audit events as CSV (requires audit:read)
+// @Summary Export audit events
+// @Description Download every audit event matching the filters as CSV, oldest first
+// @Tags audit
+// @Produce text/csv
+// @Security BearerAuth
+// @Param actor_id query int false "Actor user ID"
+// @Param impersonator_id query int false "Impersonating admin user ID"
+// @Param personal_access_token_id query int false "Personal access token ID"
+// @Param action query string false "Action, e.g. user.update"
+// @Param target_type query string false "Target type, e.g. user"
+// @Param target_id query int false "Target ID"
+// @Param request_id query string false "Request ID"
+// @Param from query string false "Start time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "End time (RFC 3339 or YYYY-MM-DD, inclusive)"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Router /api/audit-events/export [get]
+func (h *AuditHandler) ExportEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-events-%s.csv"`, time.Now().UTC().Format("20060102-150405")))
+
+	writer := csv.NewWriter(c.Writer)
+	header := []string{"id", "time", "actor_id", "actor_name", "action", "target_type", "target_id", "before", "after", "ip", "request_id", "impersonator_id", "impersonator_name", "personal_access_token_id"}
+	if err := writer.Write(header); err != nil {
+		return
+	}
+
+	err = h.auditService.ExportEvents(filter, func(events []models.AuditEvent) error {
+		for _, event := range events {
+			actorID, impersonatorID, tokenID := "", "", ""
+			if event.ActorID != nil {
+				actorID = strconv.FormatUint(uint64(*event.ActorID), 10)
+			}
+			if event.ImpersonatorID != nil {
+				impersonatorID = strconv.FormatUint(uint64(*event.ImpersonatorID), 10)
+			}
+			if event.PersonalAccessTokenID != nil {
+				tokenID = strconv.FormatUint(uint64(*event.PersonalAccessTokenID), 10)
+			}
+			record := []string{
+				strconv.FormatUint(uint64(event.ID), 10),
+				event.CreatedAt.UTC().Format(time.RFC3339),
+				actorID,
+				csvSafe(event.ActorName),
+				event.Action,
+				event.TargetType,
+				strconv.FormatUint(uint64(event.TargetID), 10),
+				csvJSON(event.Before),
+				csvJSON(event.After),
+				event.IP,
+				event.RequestID,
+				impersonatorID,
+				csvSafe(event.ImpersonatorName),
+				tokenID,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		// Headers are already sent; a truncated file is all the client can get
+		c.Error(err)
+		return
+	}
+
+	writer.Flush()
+}
+
+// parseAuditFilter reads audit event filters from the query string
+func parseAuditFilter(c *gin.Context) (repository.AuditEventFilter, error) {
+	filter := repository.AuditEventFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		RequestID:  c.Query("request_id"),
+	}
+
+	for name, dest := range map[string]**uint{"actor_id": &filter.ActorID, "impersonator_id": &filter.ImpersonatorID, "personal_access_token_id": &filter.PersonalAccessTokenID, "target_id": &filter.TargetID} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			parsed := uint(id)
+			*dest = &parsed
+		}
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, _, err := parseAuditTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from, use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseAuditTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to, use RFC 3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			// Include the whole day
+			to = to.Add(24*time.Hour - time.Nanosecond)
+		}
+		filter.To = &to
+	}
+
+	return filter, nil
+}
+
+// parseAuditTime parses an RFC 3339 time or a date, reporting which it was
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
+
+// csvJSON encodes a snapshot for a CSV cell
+func csvJSON(snapshot map[string]interface{}) string {
+	if snapshot == nil {
+		return ""
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return ""
+	}
+	return csvSafe(string(data))
+}
+
+// csvSafe keeps spreadsheet applications from evaluating user-controlled text as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
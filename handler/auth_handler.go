@@ -1,7 +1,12 @@
 package handler
 
 import (
-	"x-track/config"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"time"
+	"x-track/models"
 	"x-track/service"
 	"x-track/utils"
 
@@ -10,12 +15,32 @@ import (
 )
 
 type AuthHandler struct {
-	userService *service.UserService
+	userService      *service.UserService
+	emailService     *service.EmailService
+	sessionService   *service.SessionService
+	passwordService  *service.PasswordService
+	twoFactorService *service.TwoFactorService
+	loginProtection  *service.LoginProtectionService
+	roleService      *service.RoleService
+	oidcService      *service.OIDCService
+	passwordPolicy   *service.PasswordPolicyService
+	inviteService    *service.InviteService
+	impersonation    *service.ImpersonationService
 }
 
 func NewAuthHandler(db *gorm.DB) *AuthHandler {
 	return &AuthHandler{
-		userService: service.NewUserService(db),
+		userService:      service.NewUserService(db),
+		emailService:     service.NewEmailService(db),
+		sessionService:   service.NewSessionService(db),
+		passwordService:  service.NewPasswordService(db),
+		twoFactorService: service.NewTwoFactorService(db),
+		loginProtection:  service.NewLoginProtectionService(db),
+		roleService:      service.NewRoleService(db),
+		oidcService:      service.NewOIDCService(db),
+		passwordPolicy:   service.NewPasswordPolicyService(db),
+		inviteService:    service.NewInviteService(db),
+		impersonation:    service.NewImpersonationService(db),
 	}
 }
 
@@ -27,20 +52,94 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  interface{} `json:"user"`
+	service.TokenPair
+	User interface{} `json:"user"`
+}
+
+// TwoFactorChallengeResponse is returned by login when a second factor is required
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// LoginTwoFactorRequest represents the second login step
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents a request confirmed by a two-factor code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the disable 2FA request
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RefreshRequest represents the token refresh request
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ChangePasswordRequest represents the password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ResetPasswordRequest represents the password reset request
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// OIDCCallbackRequest carries the authorization code the identity provider returned to the client
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// RegisterRequest represents the invite registration request payload
+type RegisterRequest struct {
+	InviteCode string `json:"invite_code" binding:"required"`
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Password   string `json:"password" binding:"required"`
+}
+
+// ImpersonateRequest represents the start impersonation request payload
+type ImpersonateRequest struct {
+	Reason     string `json:"reason" binding:"required,max=255"`
+	Minutes    int    `json:"minutes" binding:"omitempty,min=1"`
+	AllowWrite bool   `json:"allow_write"`
+}
+
+// ImpersonationResponse is the token an admin uses to act as a user
+type ImpersonationResponse struct {
+	*service.Impersonation
+	User interface{} `json:"user"`
+}
+
+// VerifyEmailRequest represents the email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 // Login handles user authentication
 // @Summary User login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return an access token and refresh token, or a challenge when 2FA is enabled
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param login body LoginRequest true "Login credentials"
 // @Success 200 {object} utils.Response{data=LoginResponse}
+// @Success 202 {object} utils.Response{data=TwoFactorChallengeResponse}
 // @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
+// @Failure 429 {object} utils.Response
 // @Router /api/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
@@ -49,34 +148,603 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse locked users and noisy IPs before checking the password
+	if err := h.loginProtection.CheckAllowed(req.Username, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		h.loginBlocked(c, err)
+		return
+	}
+
 	// Authenticate user
 	user, err := h.userService.AuthenticateUser(req.Username, req.Password)
+	if service.IsInactiveUserError(err) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		h.loginProtection.RecordFailure(req.Username, c.ClientIP(), c.Request.UserAgent(), service.LoginReasonInvalidCredentials)
+		utils.ErrorResponse(c, 401, err.Error())
+		return
+	}
+
+	// Password is correct, but a second factor is still needed
+	if user.TOTPEnabled {
+		h.twoFactorRequired(c, user)
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// twoFactorRequired answers a login with a challenge to complete with a second factor
+func (h *AuthHandler) twoFactorRequired(c *gin.Context, user *models.User) {
+	challenge, err := h.twoFactorService.CreateChallenge(user)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to generate token")
+		return
+	}
+
+	utils.SuccessResponse(c, 202, "Two-factor authentication required", TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	})
+}
+
+// Register creates an account by redeeming an invite
+// @Summary Register with invite
+// @Description Create a user with the role of the given invite and log them in
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param registration body RegisterRequest true "Invite code and user details"
+// @Success 200 {object} utils.Response{data=LoginResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	user, err := h.inviteService.Register(requestActor(c), req.InviteCode, req.Username, req.Email, req.Password)
+	if errors.Is(err, service.ErrInviteInvalid) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
 	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code
+// @Summary Complete two-factor login
+// @Description Exchange a login challenge and a TOTP or recovery code for an access token and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body LoginTwoFactorRequest true "Challenge token and code"
+// @Success 200 {object} utils.Response{data=LoginResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 429 {object} utils.Response
+// @Router /api/auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	user, err := h.twoFactorService.ParseChallenge(req.ChallengeToken)
+	if err != nil {
+		utils.ErrorResponse(c, 401, err.Error())
+		return
+	}
+
+	// Codes are guessable too, so they count towards the same limits as passwords
+	if err := h.loginProtection.CheckAllowed(user.Username, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		h.loginBlocked(c, err)
+		return
+	}
+
+	if err := h.twoFactorService.Verify(user, req.Code); err != nil {
+		h.loginProtection.RecordFailure(user.Username, c.ClientIP(), c.Request.UserAgent(), service.LoginReasonInvalid2FA)
 		utils.ErrorResponse(c, 401, err.Error())
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(
-		user.ID,
-		user.Username,
-		user.Role,
-		config.AppConfig.JWT.Secret,
-		config.AppConfig.JWT.ExpirationHours,
-	)
+	h.completeLogin(c, user)
+}
+
+// loginBlocked writes the response for a login refused by brute-force protection
+func (h *AuthHandler) loginBlocked(c *gin.Context, err error) {
+	var blocked *service.LoginBlockedError
+	if errors.As(err, &blocked) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		utils.ErrorResponse(c, 429, blocked.Error())
+		return
+	}
+	utils.ErrorResponse(c, 500, "Failed to check login attempts")
+}
+
+// GetPasswordPolicy returns the rules new passwords must follow
+// @Summary Get password policy
+// @Description Minimum and maximum length of new passwords and how many recent passwords can't be reused
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response{data=service.PasswordPolicy}
+// @Router /api/auth/password-policy [get]
+func (h *AuthHandler) GetPasswordPolicy(c *gin.Context) {
+	utils.SuccessResponse(c, 200, "Password policy retrieved", h.passwordPolicy.Policy())
+}
+
+// GetOIDCConfig tells the client whether single sign-on is available
+// @Summary Get single sign-on configuration
+// @Description Whether OpenID Connect single sign-on is enabled and the provider name to show
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Router /api/auth/oidc [get]
+func (h *AuthHandler) GetOIDCConfig(c *gin.Context) {
+	utils.SuccessResponse(c, 200, "Single sign-on configuration retrieved", gin.H{
+		"enabled":       h.oidcService.Enabled(),
+		"provider_name": h.oidcService.ProviderName(),
+	})
+}
+
+// AuthorizeOIDC starts a single sign-on login
+// @Summary Start single sign-on
+// @Description Return the identity provider URL to send the browser to (authorization code flow with PKCE)
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /api/auth/oidc/authorize [get]
+func (h *AuthHandler) AuthorizeOIDC(c *gin.Context) {
+	authURL, err := h.oidcService.BeginLogin()
+	if errors.Is(err, service.ErrOIDCDisabled) {
+		utils.ErrorResponse(c, 404, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to start single sign-on: %v", err)
+		utils.ErrorResponse(c, 502, "Identity provider is unavailable")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Redirect to the identity provider", gin.H{"authorization_url": authURL})
+}
+
+// OIDCCallback completes a single sign-on login. Lockouts apply as for password logins,
+// and users with 2FA enabled get a challenge unless the identity provider is configured
+// as enforcing MFA itself (OIDC_IDP_ENFORCES_MFA).
+// @Summary Complete single sign-on
+// @Description Exchange the authorization code returned by the identity provider and return an access token and refresh token, or a challenge when 2FA is enabled. Unknown identities are linked to existing users or provisioned as configured.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param callback body OIDCCallbackRequest true "Code and state from the identity provider redirect"
+// @Success 200 {object} utils.Response{data=LoginResponse}
+// @Success 202 {object} utils.Response{data=TwoFactorChallengeResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 429 {object} utils.Response
+// @Router /api/auth/oidc/callback [post]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	// The identity provider vouched for the user, but locks set here still apply, and
+	// are checked before the user is linked, provisioned or changed
+	var blocked error
+	checkAllowed := func(username string) error {
+		blocked = h.loginProtection.CheckAllowed(username, c.ClientIP(), c.Request.UserAgent())
+		return blocked
+	}
+
+	user, err := h.oidcService.CompleteLogin(requestActor(c), req.Code, req.State, checkAllowed)
+	switch {
+	case blocked != nil:
+		h.loginBlocked(c, blocked)
+		return
+	case errors.Is(err, service.ErrOIDCDisabled):
+		utils.ErrorResponse(c, 404, err.Error())
+		return
+	case errors.Is(err, service.ErrOIDCInvalidState):
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	case err != nil:
+		log.Printf("Single sign-on failed: %v", err)
+		utils.ErrorResponse(c, 401, "Single sign-on failed: "+err.Error())
+		return
+	}
+
+	if h.oidcService.RequiresTwoFactor(user) {
+		h.twoFactorRequired(c, user)
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// completeLogin starts a session for an authenticated user and writes the login response
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	// Start session and generate tokens
+	tokens, err := h.sessionService.CreateSession(requestActor(c), user)
+	if service.IsInactiveUserError(err) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, 500, "Failed to generate token")
 		return
 	}
 
+	h.loginProtection.RecordSuccess(user, c.ClientIP(), c.Request.UserAgent())
+
 	response := LoginResponse{
-		Token: token,
-		User: map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role,
-		},
+		TokenPair: *tokens,
+		User:      h.loginUser(user),
 	}
 
 	utils.SuccessResponse(c, 200, "Login successful", response)
 }
+
+// loginUser is the user returned with a token pair, including the permissions
+// their role grants so clients can hide actions they can't take
+func (h *AuthHandler) loginUser(user *models.User) map[string]interface{} {
+	permissions := []string{}
+	if set, err := h.roleService.Permissions(user.Role); err == nil {
+		permissions = set.List()
+	}
+
+	return map[string]interface{}{
+		"id":                   user.ID,
+		"username":             user.Username,
+		"role":                 user.Role,
+		"permissions":          permissions,
+		"must_change_password": user.MustChangePassword,
+		"totp_enabled":         user.TOTPEnabled,
+	}
+}
+
+// Refresh exchanges a refresh token for a new token pair
+// @Summary Refresh access token
+// @Description Rotate a refresh token and return a new access token and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body RefreshRequest true "Refresh token"
+// @Success 200 {object} utils.Response{data=LoginResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	tokens, user, err := h.sessionService.Refresh(req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		utils.ErrorResponse(c, 401, err.Error())
+		return
+	}
+
+	response := LoginResponse{
+		TokenPair: *tokens,
+		User:      h.loginUser(user),
+	}
+
+	utils.SuccessResponse(c, 200, "Token refreshed successfully", response)
+}
+
+// Impersonate starts acting as a user (requires users:impersonate)
+// @Summary Impersonate user
+// @Description Issue a short-lived token that acts as the user, read-only unless allow_write is set. Every request made with it is recorded in the audit log. The token can't be refreshed.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param impersonation body ImpersonateRequest true "Reason and duration"
+// @Success 200 {object} utils.Response{data=ImpersonationResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/users/{id}/impersonate [post]
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	duration := service.DefaultImpersonationDuration
+	if req.Minutes > 0 {
+		duration = time.Duration(req.Minutes) * time.Minute
+	}
+
+	impersonation, err := h.impersonation.Start(requestActor(c), c.GetString("session_id"), uint(id), req.Reason, duration, req.AllowWrite)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, 404, "User not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Impersonation started", ImpersonationResponse{
+		Impersonation: impersonation,
+		User:          h.loginUser(impersonation.User),
+	})
+}
+
+// EndImpersonation ends the impersonation the token belongs to
+// @Summary End impersonation
+// @Description Revoke the impersonation token used for this request
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/impersonation/end [post]
+func (h *AuthHandler) EndImpersonation(c *gin.Context) {
+	err := h.impersonation.End(requestActor(c), c.GetString("session_id"))
+	if errors.Is(err, service.ErrNotImpersonating) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to end impersonation")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Impersonation ended", nil)
+}
+
+// Logout revokes the current session
+// @Summary Logout
+// @Description Revoke the current session and its refresh tokens
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, _ := c.Get("session_id")
+
+	if err := h.sessionService.Logout(requestActor(c), sessionID.(string)); err != nil {
+		utils.ErrorResponse(c, 500, "Failed to logout")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Logged out successfully", nil)
+}
+
+// LogoutAll revokes every session of the current user
+// @Summary Logout everywhere
+// @Description Revoke all sessions of the current user on every device
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.sessionService.RevokeAllForUser(requestActor(c), userID.(uint)); err != nil {
+		utils.ErrorResponse(c, 500, "Failed to logout")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Logged out from all sessions successfully", nil)
+}
+
+// VerifyEmail confirms a user's email address
+// @Summary Verify email
+// @Description Confirm an email address using the token from the verification link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verification body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	user, err := h.emailService.VerifyEmail(req.Token)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Email verified successfully", user)
+}
+
+// ChangePassword changes the current user's password
+// @Summary Change password
+// @Description Change the current user's password; all other sessions are revoked and new tokens are returned
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param password body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} utils.Response{data=service.TokenPair}
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	tokens, err := h.passwordService.ChangePassword(requestActor(c), userID.(uint), sessionID.(string), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Password changed successfully", tokens)
+}
+
+// ResetPassword sets a new password using an admin-issued reset token
+// @Summary Reset password
+// @Description Set a new password using a single-use reset token; all sessions are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(requestActor(c), req.Token, req.NewPassword); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Password reset successfully", nil)
+}
+
+// SetupTwoFactor starts TOTP enrolment for the current user
+// @Summary Start 2FA setup
+// @Description Generate a TOTP secret and provisioning URI for an authenticator app
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=service.TwoFactorSetup}
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	setup, err := h.twoFactorService.BeginSetup(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Scan the provisioning URI with your authenticator app", setup)
+}
+
+// EnableTwoFactor confirms TOTP enrolment with a code
+// @Summary Enable 2FA
+// @Description Confirm the TOTP secret with a code; returns single-use recovery codes and fresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} utils.Response{data=service.TwoFactorEnabled}
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/2fa/enable [post]
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	result, err := h.twoFactorService.Enable(requestActor(c), userID.(uint), sessionID.(string), req.Code)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Two-factor authentication enabled", result)
+}
+
+// DisableTwoFactor turns off TOTP for the current user
+// @Summary Disable 2FA
+// @Description Disable two-factor authentication; requires the password and a TOTP or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DisableTwoFactorRequest true "Password and code"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := h.twoFactorService.Disable(requestActor(c), userID.(uint), req.Password, req.Code); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+// @Summary Regenerate recovery codes
+// @Description Invalidate existing recovery codes and issue new ones
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(requestActor(c), userID.(uint), req.Code)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Recovery codes regenerated", map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InviteHandler struct {
+	inviteService *service.InviteService
+}
+
+func NewInviteHandler(db *gorm.DB) *InviteHandler {
+	return &InviteHandler{
+		inviteService: service.NewInviteService(db),
+	}
+}
+
+// CreateInviteRequest represents the create invite request
+type CreateInviteRequest struct {
+	Label     string    `json:"label" binding:"max=100"`
+	Role      string    `json:"role" binding:"required"`
+	MaxUses   int       `json:"max_uses" binding:"required,min=1"`
+	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+}
+
+// GetInvites lists all invites (requires users:manage)
+// @Summary List invites
+// @Description List registration invites with the users who registered through each; codes are never included
+// @Tags invites
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.Invite}
+// @Router /api/invites [get]
+func (h *InviteHandler) GetInvites(c *gin.Context) {
+	invites, err := h.inviteService.ListInvites()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve invites")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Invites retrieved successfully", invites)
+}
+
+// CreateInvite creates a registration invite (requires users:manage)
+// @Summary Create invite
+// @Description Create an invite code granting a role, valid until it expires or reaches its usage limit. The code and link are only returned in this response.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param invite body CreateInviteRequest true "Invite details"
+// @Success 201 {object} utils.Response{data=models.Invite}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/invites [post]
+func (h *InviteHandler) CreateInvite(c *gin.Context) {
+	var req CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	invite, err := h.inviteService.CreateInvite(requestActor(c), req.Label, req.Role, req.MaxUses, req.ExpiresAt)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Invite created successfully", invite)
+}
+
+// RevokeInvite revokes an invite (requires users:manage)
+// @Summary Revoke invite
+// @Description Stop an invite from being redeemed; users who already registered are not affected
+// @Tags invites
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invite ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/invites/{id} [delete]
+func (h *InviteHandler) RevokeInvite(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid invite ID")
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(requestActor(c), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, 404, "Invite not found")
+			return
+		}
+		utils.ErrorResponse(c, 500, "Failed to revoke invite")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Invite revoked successfully", nil)
+}
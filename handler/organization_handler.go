@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"x-track/middleware"
+	"x-track/models"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+func NewOrganizationHandler(db *gorm.DB) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: service.NewOrganizationService(db),
+	}
+}
+
+// OrganizationRequest represents the create and update organization request
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// OrganizationQuotaRequest represents the update organization quotas request
+type OrganizationQuotaRequest struct {
+	MaxAccounts *int `json:"max_accounts" binding:"required,min=0"`
+	MaxMembers  *int `json:"max_members" binding:"required,min=0"`
+}
+
+// OrganizationMemberRequest represents the add or update organization member request
+type OrganizationMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required,oneof=owner manager analyst viewer"`
+}
+
+// CreateOrganization creates an organization owned by the current user
+// @Summary Create organization
+// @Description Create an organization; the caller becomes its owner
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param organization body OrganizationRequest true "Organization details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	org, err := h.orgService.CreateOrganization(req.Name, userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Organization created successfully", org)
+}
+
+// GetMyOrganizations retrieves the current user's organizations
+// @Summary Get my organizations
+// @Description Retrieve the organizations the current user belongs to, with their role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/organizations/me [get]
+func (h *OrganizationHandler) GetMyOrganizations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	orgs, err := h.orgService.GetOrganizationsForUser(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve organizations")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Organizations retrieved successfully", orgs)
+}
+
+// GetAllOrganizations retrieves all organizations (requires organizations:manage_all)
+// @Summary Get all organizations
+// @Description Retrieve all organizations (requires organizations:manage_all)
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/organizations [get]
+func (h *OrganizationHandler) GetAllOrganizations(c *gin.Context) {
+	orgs, err := h.orgService.GetAllOrganizations()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve organizations")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Organizations retrieved successfully", orgs)
+}
+
+// GetOrganization retrieves an organization
+// @Summary Get organization by ID
+// @Description Retrieve an organization the caller belongs to
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleViewer)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Organization retrieved successfully", org)
+}
+
+// UpdateOrganization renames an organization
+// @Summary Update organization
+// @Description Rename an organization (owner only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param organization body OrganizationRequest true "Organization details"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	org, err := h.orgService.UpdateOrganization(org, req.Name)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Organization updated successfully", org)
+}
+
+// UpdateQuotas sets the account and member limits of an organization (requires organizations:manage_all)
+// @Summary Update organization quotas
+// @Description Set how many accounts and members an organization may have; 0 means unlimited (requires organizations:manage_all)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param quotas body OrganizationQuotaRequest true "Quotas"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id}/quotas [put]
+func (h *OrganizationHandler) UpdateQuotas(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req OrganizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	org, err := h.orgService.UpdateQuotas(org, *req.MaxAccounts, *req.MaxMembers)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Quotas updated successfully", org)
+}
+
+// DeleteOrganization deletes an organization without accounts
+// @Summary Delete organization
+// @Description Delete an organization once it has no accounts (owner only)
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.DeleteOrganization(org.ID); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Organization deleted successfully", nil)
+}
+
+// GetDashboard retrieves the aggregated dashboard of an organization
+// @Summary Get organization dashboard
+// @Description Total balance and today's P/L across the organization's accounts; analysts and above also get per-account rows
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/organizations/{id}/dashboard [get]
+func (h *OrganizationHandler) GetDashboard(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleViewer)
+	if !ok {
+		return
+	}
+
+	dashboard, err := h.orgService.GetDashboard(org)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to build dashboard")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Dashboard retrieved successfully", dashboard)
+}
+
+// ListMembers lists the members of an organization
+// @Summary List organization members
+// @Description List members of an organization with their roles
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/organizations/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgService.ListMembers(org.ID)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve members")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Members retrieved successfully", members)
+}
+
+// SetMember adds a user to an organization or changes their role
+// @Summary Add or update organization member
+// @Description Add a user with a role or change their role. Managers can't grant or take away the owner role.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param member body OrganizationMemberRequest true "User and role"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id}/members [post]
+func (h *OrganizationHandler) SetMember(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleManager)
+	if !ok {
+		return
+	}
+
+	var req OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	member, err := h.orgService.SetMember(requestActor(c), org, req.Username, req.Role)
+	if errors.Is(err, service.ErrOrganizationAccessDenied) {
+		utils.ErrorResponse(c, 403, "Only owners can manage owners")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Member saved successfully", member)
+}
+
+// RemoveMember removes a user from an organization
+// @Summary Remove organization member
+// @Description Remove a user from an organization. Managers can't remove owners and the last owner can't be removed.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id}/members/{user_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleManager)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	err = h.orgService.RemoveMember(requestActor(c), org, uint(userID))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ErrorResponse(c, 404, "Member not found")
+		return
+	case errors.Is(err, service.ErrOrganizationAccessDenied):
+		utils.ErrorResponse(c, 403, "Only owners can manage owners")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Member removed successfully", nil)
+}
+
+// ListAccounts lists the accounts of an organization
+// @Summary List organization accounts
+// @Description List the accounts of an organization (analysts and above)
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/organizations/{id}/accounts [get]
+func (h *OrganizationHandler) ListAccounts(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleAnalyst)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.orgService.ListAccounts(org.ID)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve accounts")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Accounts retrieved successfully", accounts)
+}
+
+// CreateAccount creates an account in an organization
+// @Summary Create organization account
+// @Description Create a trading account in an organization within its account quota (managers and above)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param account body UpdateAccountRequest true "Account details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/organizations/{id}/accounts [post]
+func (h *OrganizationHandler) CreateAccount(c *gin.Context) {
+	org, ok := h.authorizeOrganization(c, models.OrgRoleManager)
+	if !ok {
+		return
+	}
+
+	var req UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	account, err := h.orgService.CreateAccount(requestActor(c), org, userID.(uint), req.Name)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Account created successfully", account)
+}
+
+// authorizeOrganization parses the organization ID from the path and checks the current
+// user has at least the given role in it, writing the error response if not
+func (h *OrganizationHandler) authorizeOrganization(c *gin.Context, minRole string) (*models.Organization, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid organization ID")
+		return nil, false
+	}
+
+	userID, _ := c.Get("user_id")
+
+	org, err := h.orgService.CheckRole(uint(id), userID.(uint), middleware.Can(c, models.PermOrganizationsManageAll), minRole)
+	switch {
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		utils.ErrorResponse(c, 404, "Organization not found")
+		return nil, false
+	case errors.Is(err, service.ErrOrganizationAccessDenied):
+		utils.ErrorResponse(c, 403, "Access denied")
+		return nil, false
+	case err != nil:
+		utils.ErrorResponse(c, 500, "Failed to check organization access")
+		return nil, false
+	}
+
+	return org, true
+}
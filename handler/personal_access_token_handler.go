@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenHandler struct {
+	tokenService *service.PersonalAccessTokenService
+	userService  *service.UserService
+}
+
+func NewPersonalAccessTokenHandler(db *gorm.DB) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		tokenService: service.NewPersonalAccessTokenService(db),
+		userService:  service.NewUserService(db),
+	}
+}
+
+// CreatePersonalAccessTokenRequest represents the create personal access token request
+type CreatePersonalAccessTokenRequest struct {
+	Name      string    `json:"name" binding:"required,max=100"`
+	Scopes    []string  `json:"scopes" binding:"required"`
+	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+}
+
+// ListTokens lists the current user's personal access tokens
+// @Summary List personal access tokens
+// @Description List the current user's personal access tokens; secrets are never included
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Router /api/users/me/tokens [get]
+func (h *PersonalAccessTokenHandler) ListTokens(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tokens, err := h.tokenService.ListTokens(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve tokens")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Tokens retrieved successfully", tokens)
+}
+
+// CreateToken issues a personal access token for the current user
+// @Summary Create personal access token
+// @Description Issue a token for scripts, sent as "Authorization: Bearer <token>". Scopes are permissions the caller's role grants; the expiry is required and at most one year away. The secret is only returned in this response.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body CreatePersonalAccessTokenRequest true "Token details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/users/me/tokens [post]
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 404, "User not found")
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(requestActor(c), user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Token created successfully", token)
+}
+
+// RevokeToken deletes a personal access token of the current user
+// @Summary Revoke personal access token
+// @Description Revoke one of the current user's personal access tokens immediately
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param token_id path int true "Token ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/users/me/tokens/{token_id} [delete]
+func (h *PersonalAccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid token ID")
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(requestActor(c), userID.(uint), uint(tokenID)); err != nil {
+		utils.ErrorResponse(c, 404, "Token not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Token revoked successfully", nil)
+}
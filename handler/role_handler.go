@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"x-track/models"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{
+		roleService: service.NewRoleService(db),
+	}
+}
+
+// CreateRoleRequest represents the create role request
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// UpdateRoleRequest represents the update role request
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// GetRoles retrieves all roles
+// @Summary Get roles
+// @Description Retrieve the built-in and custom roles with their permissions
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/roles [get]
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.roleService.GetAllRoles()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve roles")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Roles retrieved successfully", roles)
+}
+
+// GetPermissions retrieves every permission a role can hold
+// @Summary Get permissions
+// @Description Retrieve the permissions that can be assigned to custom roles
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Router /api/roles/permissions [get]
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	utils.SuccessResponse(c, 200, "Permissions retrieved successfully", models.Permissions)
+}
+
+// CreateRole creates a custom role
+// @Summary Create role
+// @Description Create a custom role from a set of permissions
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role body CreateRoleRequest true "Role details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/roles [post]
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	role, err := h.roleService.CreateRole(requestActor(c), req.Name, req.Description, req.Permissions)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Role created successfully", role)
+}
+
+// UpdateRole updates a custom role
+// @Summary Update role
+// @Description Replace the description and permissions of a custom role. Needs both its current and new permissions, and can't change your own role. Its users must refresh their access tokens to get the new permissions.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param role body UpdateRoleRequest true "Role details"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/roles/{name} [put]
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(requestActor(c), c.Param("name"), req.Description, req.Permissions)
+	if errors.Is(err, service.ErrRoleNotFound) {
+		utils.ErrorResponse(c, 404, "Role not found")
+		return
+	}
+	if errors.Is(err, service.ErrPermissionNotHeld) || errors.Is(err, service.ErrOwnRole) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Role updated successfully", role)
+}
+
+// DeleteRole deletes a custom role
+// @Summary Delete role
+// @Description Delete a custom role that isn't assigned to any user. Needs every permission of the role, and can't delete your own role.
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/roles/{name} [delete]
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	err := h.roleService.DeleteRole(requestActor(c), c.Param("name"))
+	if errors.Is(err, service.ErrRoleNotFound) {
+		utils.ErrorResponse(c, 404, "Role not found")
+		return
+	}
+	if errors.Is(err, service.ErrPermissionNotHeld) || errors.Is(err, service.ErrOwnRole) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Role deleted successfully", nil)
+}
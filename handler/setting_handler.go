@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SettingHandler struct {
+	settingService *service.SettingService
+}
+
+func NewSettingHandler(db *gorm.DB) *SettingHandler {
+	return &SettingHandler{
+		settingService: service.NewSettingService(db),
+	}
+}
+
+// UpdateSecuritySettingsRequest represents the update security settings request
+type UpdateSecuritySettingsRequest struct {
+	RequireAdmin2FA *bool `json:"require_admin_2fa" binding:"required"`
+}
+
+// GetSecuritySettings retrieves the security settings (requires settings:manage)
+// @Summary Get security settings
+// @Description Retrieve system-wide security settings
+// @Tags settings
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=service.SecuritySettings}
+// @Failure 500 {object} utils.Response
+// @Router /api/settings/security [get]
+func (h *SettingHandler) GetSecuritySettings(c *gin.Context) {
+	settings, err := h.settingService.GetSecuritySettings()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve settings")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Settings retrieved successfully", settings)
+}
+
+// UpdateSecuritySettings updates the security settings (requires settings:manage)
+// @Summary Update security settings
+// @Description Update system-wide security settings such as requiring 2FA for admins
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param settings body UpdateSecuritySettingsRequest true "Security settings"
+// @Success 200 {object} utils.Response{data=service.SecuritySettings}
+// @Failure 400 {object} utils.Response
+// @Router /api/settings/security [put]
+func (h *SettingHandler) UpdateSecuritySettings(c *gin.Context) {
+	var req UpdateSecuritySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	settings, err := h.settingService.UpdateSecuritySettings(requestActor(c), service.SecuritySettings{
+		RequireAdmin2FA: *req.RequireAdmin2FA,
+	})
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to update settings")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Settings updated successfully", settings)
+}
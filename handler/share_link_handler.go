@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+	"x-track/models"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ShareLinkHandler struct {
+	shareLinkService *service.ShareLinkService
+	accountService   *service.AccountService
+}
+
+func NewShareLinkHandler(db *gorm.DB) *ShareLinkHandler {
+	return &ShareLinkHandler{
+		shareLinkService: service.NewShareLinkService(db),
+		accountService:   service.NewAccountService(db),
+	}
+}
+
+// CreateShareLinkRequest represents the create share link request
+type CreateShareLinkRequest struct {
+	Label           string     `json:"label" binding:"max=100"`
+	ShowAccountName *bool      `json:"show_account_name"`
+	ShowBalances    bool       `json:"show_balances"`
+	ShowEquityCurve *bool      `json:"show_equity_curve"`
+	ShowDrawdown    *bool      `json:"show_drawdown"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// ListShareLinks lists the share links of an account
+// @Summary List share links
+// @Description List investor share links of an account
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/accounts/{id}/share-links [get]
+func (h *ShareLinkHandler) ListShareLinks(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	links, err := h.shareLinkService.ListLinks(account.ID)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve share links")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Share links retrieved successfully", links)
+}
+
+// CreateShareLink creates a public read-only share link for an account
+// @Summary Create share link
+// @Description Create a revocable, optionally expiring read-only link. Absolute balances are hidden unless show_balances is set. The URL is only returned in this response.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param link body CreateShareLinkRequest true "Share link details"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/accounts/{id}/share-links [post]
+func (h *ShareLinkHandler) CreateShareLink(c *gin.Context) {
+	// Publishing to outsiders is the owner's call, so managers can't
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	visibility := service.ShareVisibility{
+		ShowAccountName: req.ShowAccountName == nil || *req.ShowAccountName,
+		ShowBalances:    req.ShowBalances,
+		ShowEquityCurve: req.ShowEquityCurve == nil || *req.ShowEquityCurve,
+		ShowDrawdown:    req.ShowDrawdown == nil || *req.ShowDrawdown,
+	}
+
+	link, err := h.shareLinkService.CreateLink(requestActor(c), account.ID, req.Label, visibility, req.ExpiresAt)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Share link created successfully", link)
+}
+
+// RevokeShareLink deletes a share link
+// @Summary Revoke share link
+// @Description Revoke an investor share link immediately
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param link_id path int true "Share link ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/accounts/{id}/share-links/{link_id} [delete]
+func (h *ShareLinkHandler) RevokeShareLink(c *gin.Context) {
+	account, ok := authorizeAccount(c, h.accountService, service.AccountAccessOwner)
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.ParseUint(c.Param("link_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid share link ID")
+		return
+	}
+
+	if err := h.shareLinkService.RevokeLink(requestActor(c), account.ID, uint(linkID)); err != nil {
+		utils.ErrorResponse(c, 404, "Share link not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Share link revoked successfully", nil)
+}
+
+// GetSharedView retrieves the public view behind a share link
+// @Summary Get shared account view
+// @Description Public read-only summary, equity curve and drawdown of one account, limited to the fields the owner chose to show
+// @Tags public
+// @Produce json
+// @Param token path string true "Share link token"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/public/share/{token} [get]
+func (h *ShareLinkHandler) GetSharedView(c *gin.Context) {
+	// Get share link from context (set by share link middleware)
+	link, exists := c.Get("share_link")
+	if !exists {
+		utils.ErrorResponse(c, 404, "Share link not found")
+		return
+	}
+
+	view, err := h.shareLinkService.BuildView(link.(*models.ShareLink))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to build shared view")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	utils.SuccessResponse(c, 200, "Shared view retrieved successfully", view)
+}
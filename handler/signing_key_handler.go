@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SigningKeyHandler struct {
+	signingKeyService *service.SigningKeyService
+}
+
+func NewSigningKeyHandler(db *gorm.DB) *SigningKeyHandler {
+	return &SigningKeyHandler{
+		signingKeyService: service.NewSigningKeyService(db),
+	}
+}
+
+// GetJWKS publishes the public keys that verify X-Track access tokens
+// @Summary Get token verification keys
+// @Description JSON Web Key Set of the keys access tokens are signed with, including the next key ahead of a rotation. The response is a plain JWK set rather than the usual envelope, so standard JWT libraries can consume it.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} service.JWKSet
+// @Failure 500 {object} utils.Response
+// @Router /.well-known/jwks.json [get]
+func (h *SigningKeyHandler) GetJWKS(c *gin.Context) {
+	set, err := h.signingKeyService.JWKS()
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve signing keys")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, set)
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"strconv"
 	"time"
+	"x-track/middleware"
+	"x-track/models"
 	"x-track/service"
 	"x-track/utils"
 
@@ -10,15 +14,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// streamKeepAlive is how often an idle stream sends a comment to keep proxies from closing it
+const streamKeepAlive = 25 * time.Second
+
+// streamRevalidate is how often a stream checks that the caller may still receive its events
+const streamRevalidate = time.Minute
+
 type StatisticHandler struct {
 	statisticService *service.StatisticService
 	accountService   *service.AccountService
+	ticketService    *service.StreamTicketService
 }
 
 func NewStatisticHandler(db *gorm.DB) *StatisticHandler {
 	return &StatisticHandler{
 		statisticService: service.NewStatisticService(db),
 		accountService:   service.NewAccountService(db),
+		ticketService:    service.NewStreamTicketService(db),
 	}
 }
 
@@ -78,6 +90,136 @@ func (h *StatisticHandler) IngestStatistic(c *gin.Context) {
 	utils.SuccessResponse(c, 201, "Statistic ingested successfully", statistic)
 }
 
+// IngestTradesRequest represents the trade count request; the timestamp defaults to now
+type IngestTradesRequest struct {
+	Timestamp   string `json:"timestamp"`
+	TradesToday *int   `json:"total_trades_today" binding:"required,min=0"`
+}
+
+// IngestTrades reports the trade count of the current trading day (protected by API token)
+// @Summary Ingest trades
+// @Description Set the trade count of the account's latest statistic, for clients that report trades apart from statistics. Needs the trades:ingest scope.
+// @Tags statistics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param trades body IngestTradesRequest true "Trade count"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/ingest/trades [post]
+func (h *StatisticHandler) IngestTrades(c *gin.Context) {
+	var req IngestTradesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	// Get account ID from context (set by API token middleware)
+	accountID, exists := c.Get("account_id")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized")
+		return
+	}
+
+	timestamp := time.Now()
+	if req.Timestamp != "" {
+		var err error
+		if timestamp, err = time.Parse(time.RFC3339, req.Timestamp); err != nil {
+			utils.ErrorResponse(c, 400, "Invalid timestamp format, use RFC3339 (e.g., 2024-01-15T10:30:00Z)")
+			return
+		}
+	}
+
+	statistic, err := h.statisticService.RecordTrades(accountID.(uint), timestamp, *req.TradesToday)
+	if errors.Is(err, service.ErrNoStatisticForTradingDay) {
+		utils.ErrorResponse(c, 409, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to record trades")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Trades ingested successfully", statistic)
+}
+
+// IngestHeartbeatRequest represents the heartbeat request; the timestamp defaults to now
+type IngestHeartbeatRequest struct {
+	Timestamp string `json:"timestamp"`
+}
+
+// IngestHeartbeat reports that an automated client is running (protected by API token)
+// @Summary Ingest heartbeat
+// @Description Report that the client of an account is connected; live streams of the account receive it as a heartbeat event
+// @Tags statistics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param heartbeat body IngestHeartbeatRequest false "Heartbeat"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/ingest/heartbeat [post]
+func (h *StatisticHandler) IngestHeartbeat(c *gin.Context) {
+	var req IngestHeartbeatRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	// Get account ID from context (set by API token middleware)
+	accountID, exists := c.Get("account_id")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized")
+		return
+	}
+
+	timestamp := time.Now()
+	if req.Timestamp != "" {
+		var err error
+		if timestamp, err = time.Parse(time.RFC3339, req.Timestamp); err != nil {
+			utils.ErrorResponse(c, 400, "Invalid timestamp format, use RFC3339 (e.g., 2024-01-15T10:30:00Z)")
+			return
+		}
+	}
+
+	if err := h.statisticService.RecordHeartbeat(accountID.(uint), timestamp); err != nil {
+		utils.ErrorResponse(c, 500, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Heartbeat received", nil)
+}
+
+// GetTokenTodaySummary retrieves today's summary of the API token's account
+// @Summary Get today's summary (API token)
+// @Description Retrieve today's statistics summary for automated clients with the read scope
+// @Tags statistics
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/ingest/today [get]
+func (h *StatisticHandler) GetTokenTodaySummary(c *gin.Context) {
+	// Get account ID from context (set by API token middleware)
+	accountID, exists := c.Get("account_id")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized")
+		return
+	}
+
+	summary, err := h.statisticService.GetTodaySummary(accountID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve today's summary")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Today's summary retrieved successfully", summary)
+}
+
 // GetStatisticsByDateRange retrieves statistics by date range
 // @Summary Get statistics by date range
 // @Description Retrieve statistics within a date range
@@ -268,25 +410,161 @@ func (h *StatisticHandler) GetStatistics(c *gin.Context) {
 	utils.PaginatedSuccessResponse(c, 200, "Statistics retrieved successfully", statistics, *pagination)
 }
 
-// checkAccountAccess verifies if the user has access to the account
-func (h *StatisticHandler) checkAccountAccess(c *gin.Context, accountID uint) error {
-	role, _ := c.Get("role")
-	userID, _ := c.Get("user_id")
+// StreamTicketResponse is a single-use ticket for opening a live stream
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
 
-	// Admin can access all accounts
-	if role == "admin" {
-		return nil
+// CreateStreamTicket issues a ticket that opens a live stream in place of the access token
+// @Summary Create stream ticket
+// @Description Issue a single-use ticket, valid for 30 seconds, to pass as the ticket query parameter of a stream endpoint. Personal access tokens can set the Authorization header and get none.
+// @Tags statistics
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} utils.Response{data=StreamTicketResponse}
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/statistics/stream-ticket [post]
+func (h *StatisticHandler) CreateStreamTicket(c *gin.Context) {
+	if c.GetString("auth_method") == middleware.AuthMethodPersonalAccessToken {
+		utils.ErrorResponse(c, 403, "Stream tickets are only issued to interactive logins")
+		return
 	}
 
-	// Regular user can only access their own accounts
-	account, err := h.accountService.GetAccountByID(accountID)
+	claims, _ := c.Get("claims")
+	ticket, expiresAt, err := h.ticketService.IssueTicket(claims.(*utils.JWTClaims))
 	if err != nil {
-		return err
+		utils.ErrorResponse(c, 500, "Failed to create stream ticket")
+		return
 	}
 
-	if account.UserID != userID.(uint) {
-		return gorm.ErrRecordNotFound
+	utils.SuccessResponse(c, 201, "Stream ticket created", StreamTicketResponse{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+// StreamAccount streams live updates for an account
+// @Summary Stream account updates
+// @Description Server-Sent Events stream of new snapshots, heartbeats and alerts for an account. Ends with a revoked event once the login or access to the account is revoked.
+// @Tags statistics
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param account_id path int true "Account ID"
+// @Param ticket query string false "Stream ticket, for clients that cannot set the Authorization header"
+// @Success 200 {string} string "event stream"
+// @Failure 403 {object} utils.Response
+// @Router /api/statistics/{account_id}/stream [get]
+func (h *StatisticHandler) StreamAccount(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("account_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid account ID")
+		return
 	}
 
-	return nil
+	// Check authorization
+	if err := h.checkAccountAccess(c, uint(accountID)); err != nil {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+
+	h.streamEvents(c, service.Streams.Subscribe([]uint{uint(accountID)}), func(*service.Subscription) error {
+		if err := middleware.Revalidate(c, models.PermAccountsRead); err != nil {
+			return err
+		}
+		return h.checkAccountAccess(c, uint(accountID))
+	})
+}
+
+// StreamPortfolio streams live updates for all accounts the current user owns or was granted access to
+// @Summary Stream portfolio updates
+// @Description Server-Sent Events stream of new snapshots, heartbeats and alerts for all of the caller's own and shared accounts. Ends with a revoked event once the login is revoked.
+// @Tags statistics
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param ticket query string false "Stream ticket, for clients that cannot set the Authorization header"
+// @Success 200 {string} string "event stream"
+// @Failure 500 {object} utils.Response
+// @Router /api/statistics/stream [get]
+func (h *StatisticHandler) StreamPortfolio(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	accountIDs, err := h.accountService.GetAccessibleAccountIDs(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve accounts")
+		return
+	}
+
+	h.streamEvents(c, service.Streams.Subscribe(accountIDs), func(sub *service.Subscription) error {
+		if err := middleware.Revalidate(c, models.PermAccountsRead); err != nil {
+			return err
+		}
+		accountIDs, err := h.accountService.GetAccessibleAccountIDs(userID.(uint))
+		if err != nil {
+			return err
+		}
+		service.Streams.SetAccounts(sub, accountIDs)
+		return nil
+	})
+}
+
+// streamEvents writes subscription events to the client until it disconnects. revalidate
+// runs periodically, and the stream ends with a "revoked" event when it fails or the
+// access token the stream was opened with expires.
+func (h *StatisticHandler) streamEvents(c *gin.Context, sub *service.Subscription, revalidate func(*service.Subscription) error) {
+	defer service.Streams.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	recheck := time.NewTicker(streamRevalidate)
+	defer recheck.Stop()
+
+	var expired <-chan time.Time
+	if claims, ok := c.Get("claims"); ok {
+		if exp := claims.(*utils.JWTClaims).ExpiresAt; exp != nil {
+			expiry := time.NewTimer(time.Until(exp.Time))
+			defer expiry.Stop()
+			expired = expiry.C
+		}
+	}
+
+	// Flush headers so the client knows the stream is open
+	c.Status(200)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-expired:
+			c.SSEvent("revoked", gin.H{"message": "token has expired"})
+			return false
+		case <-recheck.C:
+			if err := revalidate(sub); err != nil {
+				c.SSEvent("revoked", gin.H{"message": err.Error()})
+				return false
+			}
+			return true
+		}
+	})
+}
+
+// checkAccountAccess verifies if the user owns, was granted access to, or may manage all accounts
+func (h *StatisticHandler) checkAccountAccess(c *gin.Context, accountID uint) error {
+	userID, _ := c.Get("user_id")
+
+	_, err := h.accountService.CheckAccess(accountID, userID.(uint), middleware.Can(c, models.PermAccountsManageAll), service.AccountAccessViewer)
+	return err
 }
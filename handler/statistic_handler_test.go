@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamEndsWhenTokenExpires(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &StatisticHandler{}
+
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		c.Set("claims", &utils.JWTClaims{
+			UserID:           1,
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second))},
+		})
+		h.streamEvents(c, service.Streams.Subscribe(nil), func(*service.Subscription) error { return nil })
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream didn't end when the token expired: %v", err)
+	}
+	if !strings.Contains(string(body), "event:revoked") || !strings.Contains(string(body), "token has expired") {
+		t.Errorf("stream ended with %q, want a revoked event", body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stream ended after %v, want it to end when the token expires", elapsed)
+	}
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
+	"time"
+	"x-track/middleware"
+	"x-track/models"
 	"x-track/service"
 	"x-track/utils"
 
@@ -10,30 +14,53 @@ import (
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService     *service.UserService
+	digestService   *service.DigestService
+	passwordService *service.PasswordService
+	loginProtection *service.LoginProtectionService
 }
 
 func NewUserHandler(db *gorm.DB) *UserHandler {
 	return &UserHandler{
-		userService: service.NewUserService(db),
+		userService:     service.NewUserService(db),
+		digestService:   service.NewDigestService(db),
+		passwordService: service.NewPasswordService(db),
+		loginProtection: service.NewLoginProtectionService(db),
 	}
 }
 
 // CreateUserRequest represents the create user request
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role" binding:"required,oneof=admin user"`
+	Email    string `json:"email,omitempty" binding:"omitempty,email"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required,max=20"` // built-in or custom role
 }
 
 // UpdateUserRequest represents the update user request
 type UpdateUserRequest struct {
 	Username string `json:"username"`
-	Password string `json:"password,omitempty" binding:"omitempty,min=6"`
-	Role     string `json:"role,omitempty" binding:"omitempty,oneof=admin user"`
+	Email    string `json:"email,omitempty" binding:"omitempty,email"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty" binding:"omitempty,max=20"`
 }
 
-// CreateUser creates a new user (admin only)
+// SetUserStatusRequest represents the set user status request
+type SetUserStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active suspended deactivated"`
+}
+
+// UpdateEmailRequest represents the update email request
+type UpdateEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// UpdateDigestRequest represents the daily digest preference request
+type UpdateDigestRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// CreateUser creates a new user (requires users:manage)
 // @Summary Create a new user
 // @Description Admin creates a new user account
 // @Tags users
@@ -52,7 +79,11 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(req.Username, req.Password, req.Role)
+	user, err := h.userService.CreateUser(requestActor(c), req.Username, req.Email, req.Password, req.Role)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
@@ -61,7 +92,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	utils.SuccessResponse(c, 201, "User created successfully", user)
 }
 
-// GetAllUsers retrieves all users (admin only)
+// GetAllUsers retrieves all users (requires users:read)
 // @Summary Get all users
 // @Description Retrieve all user accounts
 // @Tags users
@@ -82,12 +113,13 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 
 // GetUser retrieves a user by ID
 // @Summary Get user by ID
-// @Description Retrieve user details by ID
+// @Description Retrieve user details by ID; users without users:read can only retrieve themselves
 // @Tags users
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Router /api/users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
@@ -97,6 +129,12 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
+	currentUserID, _ := c.Get("user_id")
+	if uint(id) != currentUserID.(uint) && !middleware.Can(c, models.PermUsersRead) {
+		utils.ErrorResponse(c, 403, "Permission required: "+models.PermUsersRead)
+		return
+	}
+
 	user, err := h.userService.GetUserByID(uint(id))
 	if err != nil {
 		utils.ErrorResponse(c, 404, "User not found")
@@ -106,7 +144,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "User retrieved successfully", user)
 }
 
-// UpdateUser updates a user (admin only)
+// UpdateUser updates a user (requires users:manage)
 // @Summary Update user
 // @Description Update user details
 // @Tags users
@@ -117,6 +155,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 // @Param user body UpdateUserRequest true "User details"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Router /api/users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -131,7 +170,11 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), req.Username, req.Password, req.Role)
+	user, err := h.userService.UpdateUser(requestActor(c), uint(id), req.Username, req.Email, req.Password, req.Role)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
@@ -140,7 +183,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "User updated successfully", user)
 }
 
-// DeleteUser deletes a user (admin only)
+// DeleteUser deletes a user (requires users:manage)
 // @Summary Delete user
 // @Description Delete a user account
 // @Tags users
@@ -149,6 +192,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Param id path int true "User ID"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
 // @Router /api/users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -157,10 +203,287 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
-		utils.ErrorResponse(c, 400, "Failed to delete user")
+	err = h.userService.DeleteUser(requestActor(c), uint(id))
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrDeleteSelf) || errors.Is(err, service.ErrLastUserManager) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, 404, "User not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to delete user")
 		return
 	}
 
 	utils.SuccessResponse(c, 200, "User deleted successfully", nil)
 }
+
+// CreatePasswordReset issues a password reset token for a user (requires users:manage)
+// @Summary Create password reset
+// @Description Issue a single-use password reset token; the link is emailed if the user has a verified email
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 201 {object} utils.Response{data=service.PasswordResetTicket}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/users/{id}/password-reset [post]
+func (h *UserHandler) CreatePasswordReset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	ticket, err := h.passwordService.CreateReset(requestActor(c), uint(id))
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "Password reset created successfully", ticket)
+}
+
+// SetUserStatus suspends, deactivates or reactivates a user (requires users:manage)
+// @Summary Set user status
+// @Description Suspend or deactivate a user, blocking their logins, tokens and the ingestion of their personal accounts, or reactivate them
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param status body SetUserStatusRequest true "New status"
+// @Success 200 {object} utils.Response{data=models.User}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/users/{id}/status [put]
+func (h *UserHandler) SetUserStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	var req SetUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	user, err := h.userService.SetStatus(requestActor(c), uint(id), req.Status)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		utils.ErrorResponse(c, 403, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "User status updated successfully", user)
+}
+
+// UnlockUser clears a user's login lockout (requires users:manage)
+// @Summary Unlock user
+// @Description Clear a user's failed login counter and temporary lockout
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	if err := h.loginProtection.Unlock(requestActor(c), uint(id)); err != nil {
+		utils.ErrorResponse(c, 404, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "User unlocked successfully", nil)
+}
+
+// GetUserLogins retrieves a user's login history (requires users:read)
+// @Summary Get user login history
+// @Description Retrieve successful and failed logins of a user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.Response
+// @Router /api/users/{id}/logins [get]
+func (h *UserHandler) GetUserLogins(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "Invalid user ID")
+		return
+	}
+
+	h.writeLoginHistory(c, uint(id))
+}
+
+// GetMyLogins retrieves the current user's login history
+// @Summary Get my login history
+// @Description Retrieve successful and failed logins of the current user, with IP and user agent
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 500 {object} utils.Response
+// @Router /api/users/me/logins [get]
+func (h *UserHandler) GetMyLogins(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	h.writeLoginHistory(c, userID.(uint))
+}
+
+// GetMyPermissions retrieves the current user's role and effective permissions
+// @Summary Get my permissions
+// @Description Retrieve the current user's role and the permissions it grants, so clients can hide actions
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=service.EffectivePermissions}
+// @Router /api/users/me/permissions [get]
+func (h *UserHandler) GetMyPermissions(c *gin.Context) {
+	role, _ := c.Get("role")
+	permissions, _ := c.Get("permissions")
+
+	utils.SuccessResponse(c, 200, "Permissions retrieved successfully", service.EffectivePermissions{
+		Role:        role.(string),
+		Permissions: permissions.(models.PermissionSet).List(),
+	})
+}
+
+// writeLoginHistory writes a paginated page of a user's login attempts
+func (h *UserHandler) writeLoginHistory(c *gin.Context, userID uint) {
+	// Parse pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	attempts, pagination, err := h.loginProtection.GetLoginHistory(userID, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "Failed to retrieve login history")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, 200, "Login history retrieved successfully", attempts, *pagination)
+}
+
+// UpdateMyEmail sets the current user's email address
+// @Summary Update my email
+// @Description Set the current user's email address and send a verification link
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param email body UpdateEmailRequest true "Email address"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/users/me/email [put]
+func (h *UserHandler) UpdateMyEmail(c *gin.Context) {
+	var req UpdateEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	user, err := h.userService.UpdateEmail(requestActor(c), userID.(uint), req.Email)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Verification email sent", user)
+}
+
+// UpdateMyDigest opts the current user in or out of the daily digest
+// @Summary Update daily digest preference
+// @Description Enable or disable the end-of-trading-day digest email
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param digest body UpdateDigestRequest true "Digest preference"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/users/me/digest [put]
+func (h *UserHandler) UpdateMyDigest(c *gin.Context) {
+	var req UpdateDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	user, err := h.userService.SetDigestEnabled(userID.(uint), *req.Enabled)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Digest preference updated successfully", user)
+}
+
+// SendMyDigest sends the latest daily digest to the current user immediately
+// @Summary Send my daily digest
+// @Description Email the digest for the most recent trading day to the current user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /api/users/me/digest/send [post]
+func (h *UserHandler) SendMyDigest(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.userService.GetUserByID(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, 404, "User not found")
+		return
+	}
+
+	dayEnd, err := service.TradingDayEnd(time.Now())
+	if err != nil {
+		utils.ErrorResponse(c, 500, err.Error())
+		return
+	}
+
+	if err := h.digestService.SendDigest(user, dayEnd); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "Digest sent successfully", nil)
+}
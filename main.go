@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"x-track/config"
+	"x-track/events"
 	"x-track/middleware"
+	"x-track/migrations"
 	"x-track/models"
 	"x-track/routes"
-	//"x-track/service"
+	"x-track/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,10 +42,27 @@ import (
 // @description API token for statistics ingestion
 
 func main() {
+	if err := run(os.Args[1:]); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		printError(err)
+		os.Exit(1)
+	}
+}
+
+// runServe starts the API server
+func runServe(args []string) error {
+	if len(args) > 0 {
+		return usageError("usage: x-track serve")
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	// Set Gin mode
@@ -48,31 +71,49 @@ func main() {
 	// Initialize database
 	db, err := models.InitDB(cfg.Database.GetDSN())
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Apply pending migrations, otherwise run "x-track migrate up" before deploying
+	if cfg.Database.MigrateOnStart {
+		if _, err := migrations.NewMigrator(db).Up(0); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
 	}
 
-	// Run migrations
-	/*
-	if err := models.AutoMigrate(db); err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	// Initialize event bus
+	if err := events.Init(cfg.Events.Backend, db, cfg.Database.GetDSN()); err != nil {
+		return fmt.Errorf("failed to initialize event bus: %w", err)
 	}
-	*/
-
-	// Ensure admin user exists
-	/*
-	userService := service.NewUserService(db)
-	if err := userService.EnsureAdminExists(cfg.Admin.Username, cfg.Admin.Password); err != nil {
-		log.Printf("Warning: Failed to create admin user: %v", err)
-	} else {
-		log.Printf("Admin user ensured (username: %s)", cfg.Admin.Username)
+	service.StartEventSubscribers(db)
+
+	// Create the token signing keys if needed and rotate them on schedule
+	signingKeyService := service.NewSigningKeyService(db)
+	if err := signingKeyService.EnsureKeys(); err != nil {
+		return fmt.Errorf("failed to prepare token signing keys: %w", err)
 	}
-	*/
+	go signingKeyService.Run(context.Background())
+
+	// Hash the password unknown usernames are checked against before the first login
+	service.NewPasswordPolicyService(db).DummyHash()
+
+	// Delete stream tickets that were never used
+	go service.NewStreamTicketService(db).Run(context.Background())
+
+	// Start daily digest scheduler
+	go service.NewDigestService(db).Run(context.Background())
 
 	// Initialize Gin router
 	router := gin.Default()
 
+	// Only believe forwarded client IPs from configured proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.TrustedPlatform = cfg.Server.TrustedPlatform
+
 	// Apply middleware
-	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.RequestIDMiddleware(), middleware.CORSMiddleware())
 
 	// Setup routes
 	routes.SetupRoutes(router, db)
@@ -81,8 +122,9 @@ func main() {
 	serverAddr := ":" + cfg.Server.Port
 	log.Printf("Starting X-Track API server on %s", serverAddr)
 	log.Printf("Environment: %s", cfg.Server.GinMode)
-	
+
 	if err := router.Run(serverAddr); err != nil {
-		log.Fatal("Failed to start server:", err)
+		return fmt.Errorf("failed to start server: %w", err)
 	}
+	return nil
 }
@@ -1,14 +1,26 @@
 package middleware
 
 import (
+	"errors"
+	"log"
+	"sync"
+	"x-track/config"
 	"x-track/models"
+	"x-track/service"
 	"x-track/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// APITokenMiddleware validates API tokens for statistics ingestion
-func APITokenMiddleware() gin.HandlerFunc {
+// warnUntrustedForwarding is done once, the first time a token arrives through a proxy
+// that isn't trusted
+var warnUntrustedForwarding sync.Once
+
+// APITokenMiddleware validates account API tokens for automated clients and
+// requires the token to be granted the given scope
+func APITokenMiddleware(scope string) gin.HandlerFunc {
+	tokenService := service.NewAccountTokenService(models.DB)
+
 	return func(c *gin.Context) {
 		token := c.GetHeader("X-API-Token")
 		if token == "" {
@@ -17,18 +29,36 @@ func APITokenMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Find account by API token
-		var account models.Account
-		if err := models.DB.Where("api_token = ?", token).First(&account).Error; err != nil {
-			utils.ErrorResponse(c, 401, "Invalid API token")
+		if c.GetHeader("X-Forwarded-For") != "" && !trustsForwarding() {
+			warnUntrustedForwarding.Do(func() {
+				log.Printf("API tokens are used through a proxy, but TRUSTED_PROXIES is not set; IP allowlists are checked against the proxy address %s", c.RemoteIP())
+			})
+		}
+
+		// Find account token by hash and check expiry, scope and IP allowlist. ClientIP only
+		// follows X-Forwarded-For from TRUSTED_PROXIES, so clients can't pick an allowed IP.
+		accountToken, err := tokenService.Authenticate(token, c.ClientIP(), scope)
+		if err != nil {
+			if errors.Is(err, service.ErrAccountTokenForbidden) || service.IsInactiveUserError(err) {
+				utils.ErrorResponse(c, 403, err.Error())
+			} else {
+				utils.ErrorResponse(c, 401, "Invalid API token")
+			}
 			c.Abort()
 			return
 		}
 
 		// Set account information in context
-		c.Set("account_id", account.ID)
-		c.Set("user_id", account.UserID)
+		c.Set("account_id", accountToken.AccountID)
+		c.Set("user_id", accountToken.Account.UserID)
+		c.Set("account_token_id", accountToken.ID)
 
 		c.Next()
 	}
 }
+
+// trustsForwarding reports whether the client IP can come from a proxy or platform header
+func trustsForwarding() bool {
+	cfg := config.AppConfig
+	return cfg != nil && (len(cfg.Server.TrustedProxies) > 0 || cfg.Server.TrustedPlatform != "")
+}
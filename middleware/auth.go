@@ -1,35 +1,80 @@
 package middleware
 
 import (
+	"errors"
+	"net/http"
 	"strings"
-	"x-track/config"
+	"time"
+	"x-track/models"
+	"x-track/service"
 	"x-track/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens
+// Authentication methods recorded in the request context as auth_method
+const (
+	AuthMethodSession             = "session"
+	AuthMethodPersonalAccessToken = "personal_access_token"
+	AuthMethodImpersonation       = "impersonation"
+)
+
+// AuthMiddleware validates JWT tokens and rejects tokens of revoked sessions
+// or issued before the user's role, password or status last changed. Personal
+// access tokens are accepted in place of a JWT, and the claims of a redeemed
+// stream ticket in place of the header. Every request made with an
+// impersonation token is recorded in the audit log.
 func AuthMiddleware() gin.HandlerFunc {
+	sessionService := service.NewSessionService(models.DB)
+	roleService := service.NewRoleService(models.DB)
+	patService := service.NewPersonalAccessTokenService(models.DB)
+	signingKeyService := service.NewSigningKeyService(models.DB)
+	auditService := service.NewAuditService(models.DB)
+
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			utils.ErrorResponse(c, 401, "Authorization header required")
-			c.Abort()
-			return
+		var claims *utils.JWTClaims
+		if ticketClaims, ok := c.Get("stream_ticket_claims"); ok {
+			claims = ticketClaims.(*utils.JWTClaims)
+		} else {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				utils.ErrorResponse(c, 401, "Authorization header required")
+				c.Abort()
+				return
+			}
+
+			// Extract token from "Bearer <token>"
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				utils.ErrorResponse(c, 401, "Invalid authorization header format")
+				c.Abort()
+				return
+			}
+
+			token := parts[1]
+			if service.IsPersonalAccessToken(token) {
+				authenticatePersonalAccessToken(c, patService, token)
+				return
+			}
+
+			parsed, err := signingKeyService.ParseAccessToken(token)
+			if err != nil {
+				utils.ErrorResponse(c, 401, "Invalid or expired token")
+				c.Abort()
+				return
+			}
+			claims = parsed
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			utils.ErrorResponse(c, 401, "Invalid authorization header format")
+		if err := sessionService.ValidateAccess(claims); err != nil {
+			utils.ErrorResponse(c, 401, err.Error())
 			c.Abort()
 			return
 		}
 
-		token := parts[1]
-		claims, err := utils.ValidateJWT(token, config.AppConfig.JWT.Secret)
+		permissions, err := roleService.Permissions(claims.Role)
 		if err != nil {
-			utils.ErrorResponse(c, 401, "Invalid or expired token")
+			utils.ErrorResponse(c, 500, "Failed to resolve permissions")
 			c.Abort()
 			return
 		}
@@ -38,20 +83,209 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("permissions", permissions)
+		c.Set("session_id", claims.SessionID)
+		c.Set("claims", claims)
+		c.Set("must_change_password", claims.MustChangePassword)
+		c.Set("must_enroll_2fa", claims.MustEnroll2FA)
+		c.Set("auth_method", AuthMethodSession)
+
+		if imp := claims.Impersonator; imp != nil {
+			c.Set("auth_method", AuthMethodImpersonation)
+			c.Set("impersonator_id", imp.UserID)
+			c.Set("impersonator_username", imp.Username)
+			c.Set("read_only", claims.ReadOnly)
+
+			c.Next()
+			auditImpersonatedRequest(c, auditService, claims)
+			return
+		}
 
 		c.Next()
 	}
 }
 
-// RequireAdmin ensures the user has admin role
-func RequireAdmin() gin.HandlerFunc {
+// Revalidate checks again that the session or personal access token a long-lived request,
+// such as a live stream, authenticated with is still valid and still grants permission.
+// The permissions in the context are refreshed.
+func Revalidate(c *gin.Context, permission string) error {
+	var permissions models.PermissionSet
+	if c.GetString("auth_method") == AuthMethodPersonalAccessToken {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		_, granted, err := service.NewPersonalAccessTokenService(models.DB).Authenticate(token, c.ClientIP())
+		if err != nil {
+			return err
+		}
+		permissions = granted
+	} else {
+		claims, ok := c.Get("claims")
+		if !ok {
+			return errors.New("request is not authenticated")
+		}
+		if exp := claims.(*utils.JWTClaims).ExpiresAt; exp != nil && !time.Now().Before(exp.Time) {
+			return errors.New("token has expired")
+		}
+		if err := service.NewSessionService(models.DB).ValidateAccess(claims.(*utils.JWTClaims)); err != nil {
+			return err
+		}
+		granted, err := service.NewRoleService(models.DB).Permissions(claims.(*utils.JWTClaims).Role)
+		if err != nil {
+			return err
+		}
+		permissions = granted
+	}
+
+	c.Set("permissions", permissions)
+	if !permissions.Has(permission) {
+		return errors.New("Permission required: " + permission)
+	}
+	return nil
+}
+
+// auditImpersonatedRequest records a request an admin made while impersonating a user
+func auditImpersonatedRequest(c *gin.Context, auditService *service.AuditService, claims *utils.JWTClaims) {
+	actor := service.Actor{
+		UserID:           claims.UserID,
+		Username:         claims.Username,
+		ImpersonatorID:   claims.Impersonator.UserID,
+		ImpersonatorName: claims.Impersonator.Username,
+		IP:               c.ClientIP(),
+		UserAgent:        c.Request.UserAgent(),
+		RequestID:        c.GetString("request_id"),
+	}
+	auditService.Record(actor, models.AuditImpersonatedRequest, models.AuditTargetUser, claims.UserID, nil, map[string]interface{}{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"status": c.Writer.Status(),
+	})
+}
+
+// authenticatePersonalAccessToken authenticates a request as the owner of a personal access
+// token, with the token's scopes as permissions. The request has no session.
+func authenticatePersonalAccessToken(c *gin.Context, patService *service.PersonalAccessTokenService, token string) {
+	pat, permissions, err := patService.Authenticate(token, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrPersonalAccessTokenInvalid) {
+			utils.ErrorResponse(c, 401, err.Error())
+		} else if service.IsInactiveUserError(err) {
+			utils.ErrorResponse(c, 403, err.Error())
+		} else {
+			utils.ErrorResponse(c, 500, "Failed to validate personal access token")
+		}
+		c.Abort()
+		return
+	}
+
+	// Set token owner information in context
+	c.Set("user_id", pat.UserID)
+	c.Set("username", pat.User.Username)
+	c.Set("role", pat.User.Role)
+	c.Set("permissions", permissions)
+	c.Set("must_change_password", pat.User.MustChangePassword)
+	c.Set("auth_method", AuthMethodPersonalAccessToken)
+	c.Set("personal_access_token_id", pat.ID)
+
+	c.Next()
+}
+
+// StreamTicketMiddleware accepts a stream ticket from the ticket query parameter in place
+// of the Authorization header, for routes consumed by EventSource, which cannot set request
+// headers. Tickets are single-use and stand in for a session only, so neither access tokens
+// nor personal access tokens ever appear in URLs. AuthMiddleware then validates the
+// session the ticket was issued for.
+func StreamTicketMiddleware() gin.HandlerFunc {
+	ticketService := service.NewStreamTicketService(models.DB)
+
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			utils.ErrorResponse(c, 403, "Admin access required")
+		ticket := c.Query("ticket")
+		if c.GetHeader("Authorization") != "" || ticket == "" {
+			c.Next()
+			return
+		}
+
+		claims, err := ticketService.RedeemTicket(ticket)
+		if err != nil {
+			if errors.Is(err, service.ErrStreamTicketInvalid) {
+				utils.ErrorResponse(c, 401, err.Error())
+			} else {
+				utils.ErrorResponse(c, 500, "Failed to validate stream ticket")
+			}
 			c.Abort()
 			return
 		}
+
+		c.Set("stream_ticket_claims", claims)
 		c.Next()
 	}
 }
+
+// RequireAccountSetup blocks tokens issued to users who must change their password
+// or enrol in two-factor authentication first
+func RequireAccountSetup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mustChange, _ := c.Get("must_change_password"); mustChange == true {
+			utils.ErrorResponse(c, 403, "Password change required")
+			c.Abort()
+			return
+		}
+		if mustEnroll, _ := c.Get("must_enroll_2fa"); mustEnroll == true {
+			utils.ErrorResponse(c, 403, "Two-factor authentication enrolment required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession blocks personal access tokens and impersonation from routes that manage
+// the login itself, such as passwords, two-factor authentication and the tokens themselves
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != AuthMethodSession {
+			utils.ErrorResponse(c, 403, "This endpoint requires an interactive login")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireWriteAccess blocks requests that change data when an admin is impersonating a
+// user read-only
+func RequireWriteAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if c.GetBool("read_only") {
+				utils.ErrorResponse(c, 403, "This impersonation session is read-only")
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission ensures the user's role grants a permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Can(c, permission) {
+			utils.ErrorResponse(c, 403, "Permission required: "+permission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Can checks if the authenticated user's role grants a permission. It is the single
+// policy check behind RequirePermission and the handlers' resource-level checks.
+func Can(c *gin.Context, permission string) bool {
+	permissions, ok := c.Get("permissions")
+	if !ok {
+		return false
+	}
+	set, ok := permissions.(models.PermissionSet)
+	return ok && set.Has(permission)
+}
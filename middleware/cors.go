@@ -1,22 +1,109 @@
 package middleware
 
 import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"x-track/config"
+	"x-track/utils"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware handles Cross-Origin Resource Sharing
+// CORSMiddleware handles Cross-Origin Resource Sharing for the origins allowed in
+// config.Config. Responses for a specific origin vary by Origin, so caches never
+// serve one origin's headers to another. Preflights from other origins are refused.
 func CORSMiddleware() gin.HandlerFunc {
+	cfg := config.AppConfig.CORS
+	allowAny := false
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(cfg.MaxAge)
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Token")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		header := c.Writer.Header()
+
+		if !allowAny {
+			header.Add("Vary", "Origin")
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		// Same-origin and non-browser requests
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !allowAny && !originAllowed(origin, cfg.AllowedOrigins) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// Without CORS headers the browser won't expose the response
+			c.Next()
+			return
+		}
+
+		if allowAny {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", methods)
+			header.Set("Access-Control-Allow-Headers", headers)
+			header.Set("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
+		if exposed != "" {
+			header.Set("Access-Control-Expose-Headers", exposed)
+		}
 		c.Next()
 	}
 }
+
+// RejectBrowserOrigins refuses requests sent by browsers from another origin, for routes
+// meant to be called by servers only, unless config.Config allows them
+func RejectBrowserOrigins() gin.HandlerFunc {
+	allow := config.AppConfig.CORS.IngestAllowBrowsers
+
+	return func(c *gin.Context) {
+		if !allow && c.GetHeader("Origin") != "" {
+			utils.ErrorResponse(c, 403, "This endpoint does not accept browser requests")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// originAllowed matches an origin against exact origins and glob patterns
+func originAllowed(origin string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if strings.EqualFold(pattern, origin) {
+			return true
+		}
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
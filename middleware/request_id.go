@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"regexp"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request, from a proxy or set by RequestIDMiddleware
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits accepted request IDs to values safe to log and store
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware tags each request with an ID, reusing a well-formed ID set by a
+// proxy, and echoes it in the response so log lines and audit events can be correlated
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID, _ = utils.GenerateSecureToken(16)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"x-track/models"
+	"x-track/service"
+	"x-track/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareLinkMiddleware resolves the share link token in the path for public
+// read-only views. It sets no user or role, so handlers behind it can't act on
+// anyone's behalf, and it refuses every method that could change data.
+func ShareLinkMiddleware() gin.HandlerFunc {
+	shareLinkService := service.NewShareLinkService(models.DB)
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			utils.ErrorResponse(c, 405, "Share links are read-only")
+			c.Abort()
+			return
+		}
+
+		link, err := shareLinkService.Resolve(c.Param("token"))
+		if err != nil {
+			utils.ErrorResponse(c, 404, err.Error())
+			c.Abort()
+			return
+		}
+
+		// Set share link in context
+		c.Set("share_link", link)
+
+		c.Next()
+	}
+}
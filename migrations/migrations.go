@@ -0,0 +1,319 @@
+// Package migrations applies the versioned SQL migrations embedded in the binary.
+//
+// Migrations live in sql/ as NNNN_name.up.sql with an optional NNNN_name.down.sql;
+// a migration without a down file can't be reverted. Applied versions are recorded
+// with the checksum of their up file in schema_migrations, and a migration that was
+// changed after being applied stops further migrations until it is restored.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the Postgres advisory lock held while migrating, so concurrent instances
+// and serverless cold starts apply each migration once
+const lockID = 0x78747261636b02
+
+// fileName matches migration files: version, name and direction
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version  int64  `json:"version"`
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+	up       string
+	down     string
+}
+
+// Reversible reports whether the migration has a down file
+func (m Migration) Reversible() bool {
+	return m.down != ""
+}
+
+// Status is the state of a migration in the database
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the up file changed since it was applied
+	Modified bool `json:"modified,omitempty"`
+	// Missing is set for versions applied by a newer binary, which this one doesn't know
+	Missing bool `json:"missing,omitempty"`
+}
+
+// schemaMigration records an applied migration
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	checksum varchar(64) NOT NULL,
+	applied_at timestamptz NOT NULL
+)`
+
+type Migrator struct {
+	db *gorm.DB
+}
+
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Up applies pending migrations up to and including target, or all of them when
+// target is 0. Each migration runs in its own transaction.
+func (m *Migrator) Up(target int64) ([]Migration, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = m.withLock(func(conn *gorm.DB) error {
+		records, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if err := verify(migrations, records); err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+			if target != 0 && migration.Version > target {
+				break
+			}
+
+			log.Printf("Applying migration %04d_%s", migration.Version, migration.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations, newest first
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []Migration
+	err = m.withLock(func(conn *gorm.DB) error {
+		var records []schemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			migration, ok := byVersion[record.Version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is unknown to this binary", record.Version, record.Name)
+			}
+			if !migration.Reversible() {
+				return fmt.Errorf("migration %04d_%s can't be reverted", migration.Version, migration.Name)
+			}
+
+			log.Printf("Reverting migration %04d_%s", migration.Version, migration.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known and applied migration by version
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.db.Exec(createSchemaMigrations).Error; err != nil {
+		return nil, err
+	}
+	records, err := appliedMigrations(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := records[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		statuses = append(statuses, Status{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &record.AppliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", lockID).Error; err != nil {
+				log.Printf("Failed to release migration lock: %v", err)
+			}
+		}()
+
+		if err := conn.Exec(createSchemaMigrations).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// appliedMigrations loads the applied migrations by version
+func appliedMigrations(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// verify refuses to migrate when an applied migration was changed afterwards. Versions
+// unknown to this binary were applied by a newer one and are left alone.
+func verify(migrations []Migration, applied map[int64]schemaMigration) error {
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		if ok && record.Checksum != migration.Checksum {
+			return fmt.Errorf("migration %04d_%s was modified after it was applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// load parses the embedded migration files, ordered by version
+func load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		content, err := files.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			migration.up = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
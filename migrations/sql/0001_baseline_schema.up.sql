@@ -0,0 +1,49 @@
+-- Schema of the first release: users, their trading accounts and the statistics sent
+-- for them. Databases that already have these tables keep them as they are; columns
+-- added since are brought in by later migrations.
+-- This migration can't be reverted.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    username varchar(50) NOT NULL,
+    password_hash text NOT NULL,
+    role varchar(20) NOT NULL DEFAULT 'user',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id bigserial,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    api_token varchar(64) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_accounts FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON accounts (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_api_token ON accounts (api_token);
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts (user_id);
+
+CREATE TABLE IF NOT EXISTS statistics (
+    id bigserial,
+    account_id bigint NOT NULL,
+    "timestamp" timestamptz NOT NULL,
+    daily_pl decimal NOT NULL,
+    trades_today bigint NOT NULL,
+    total_balance decimal NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_accounts_statistics FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+CREATE INDEX IF NOT EXISTS idx_statistics_deleted_at ON statistics (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_timestamp ON statistics ("timestamp");
+CREATE INDEX IF NOT EXISTS idx_account_timestamp ON statistics (account_id, "timestamp");
@@ -0,0 +1,262 @@
+-- Everything added before versioned migrations: email, session, two-factor and lockout
+-- columns of users, organizations of accounts, and the tables of those features. Every
+-- statement is idempotent, so databases that got part of it by hand can apply it too.
+-- This migration can't be reverted.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email varchar(255),
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz,
+    ADD COLUMN IF NOT EXISTS digest_enabled boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS last_digest_at timestamptz,
+    ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS must_change_password boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS totp_secret varchar(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS failed_login_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_failed_login_at timestamptz,
+    ADD COLUMN IF NOT EXISTS locked_until timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS organization_id bigint;
+CREATE INDEX IF NOT EXISTS idx_accounts_organization_id ON accounts (organization_id);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id bigserial,
+    user_id bigint NOT NULL,
+    email varchar(255) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verifications_token_hash ON email_verifications (token_hash);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id varchar(32),
+    user_id bigint NOT NULL,
+    ip varchar(45),
+    user_agent varchar(255),
+    revoked_at timestamptz,
+    last_used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial,
+    session_id varchar(32) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (session_id) REFERENCES sessions(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id bigserial,
+    user_id bigint NOT NULL,
+    created_by_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_resets_token_hash ON password_resets (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id bigserial,
+    user_id bigint NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS settings (
+    "key" varchar(100),
+    "value" text NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY ("key")
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id bigserial,
+    user_id bigint,
+    username varchar(50) NOT NULL,
+    ip varchar(45) NOT NULL,
+    user_agent varchar(255),
+    success boolean NOT NULL,
+    reason varchar(50),
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempt_ip_time ON login_attempts (ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username ON login_attempts (username);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id ON login_attempts (user_id);
+
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id bigserial,
+    account_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    token_prefix varchar(16) NOT NULL,
+    scopes text NOT NULL,
+    allowed_ips text,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    last_used_ip varchar(45),
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_account_tokens_account FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_account_id ON account_tokens (account_id);
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id bigserial,
+    account_id bigint NOT NULL,
+    created_by_id bigint NOT NULL,
+    label varchar(100),
+    token_hash varchar(64) NOT NULL,
+    token_prefix varchar(16) NOT NULL,
+    show_account_name boolean NOT NULL DEFAULT false,
+    show_balances boolean NOT NULL DEFAULT false,
+    show_equity_curve boolean NOT NULL DEFAULT false,
+    show_drawdown boolean NOT NULL DEFAULT false,
+    expires_at timestamptz,
+    last_viewed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_share_links_account FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_share_links_token_hash ON share_links (token_hash);
+CREATE INDEX IF NOT EXISTS idx_share_links_account_id ON share_links (account_id);
+
+CREATE TABLE IF NOT EXISTS account_members (
+    id bigserial,
+    account_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    granted_by_id bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_account_members_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_account_members_account FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+CREATE INDEX IF NOT EXISTS idx_account_members_user_id ON account_members (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_member ON account_members (account_id, user_id);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id bigserial,
+    name varchar(100) NOT NULL,
+    max_accounts bigint NOT NULL DEFAULT 0,
+    max_members bigint NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations (deleted_at);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id bigserial,
+    organization_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_organization_members_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_organization_members_organization FOREIGN KEY (organization_id) REFERENCES organizations(id)
+);
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_member ON organization_members (organization_id, user_id);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial,
+    name varchar(20) NOT NULL,
+    description varchar(255),
+    permissions text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (name);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id bigserial,
+    user_id bigint NOT NULL,
+    issuer varchar(255) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(255),
+    last_login_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identity_subject ON user_identities (issuer, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id bigserial,
+    state_hash varchar(64) NOT NULL,
+    nonce varchar(64) NOT NULL,
+    code_verifier varchar(128) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_login_states_state_hash ON oidc_login_states (state_hash);
+
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id bigserial,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    token_prefix varchar(16) NOT NULL,
+    scopes text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    last_used_at timestamptz,
+    last_used_ip varchar(45),
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_personal_access_tokens_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_access_tokens_token_hash ON personal_access_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial,
+    actor_id bigint,
+    actor_name varchar(50),
+    action varchar(64) NOT NULL,
+    target_type varchar(32) NOT NULL,
+    target_id bigint,
+    "before" text,
+    "after" text,
+    ip varchar(45),
+    request_id varchar(64),
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_request_id ON audit_events (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_event_target ON audit_events (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
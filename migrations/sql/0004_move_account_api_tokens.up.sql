@@ -0,0 +1,42 @@
+-- Move the single per-account API token of older schemas (plaintext api_token, later
+-- api_token_hash) into account_tokens and drop the old columns. Existing tokens keep
+-- working with every scope. Databases without the old columns are left alone.
+-- This migration can't be reverted.
+
+DO $$
+DECLARE
+    has_plain boolean;
+    has_hash boolean;
+    scopes text := '["statistics:ingest","trades:ingest","heartbeat:ingest","read"]';
+BEGIN
+    SELECT
+        bool_or(column_name = 'api_token'),
+        bool_or(column_name = 'api_token_hash')
+    INTO has_plain, has_hash
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'accounts';
+
+    IF has_hash THEN
+        EXECUTE $sql$
+            INSERT INTO account_tokens (account_id, name, token_hash, token_prefix, scopes, created_at, updated_at)
+            SELECT id, 'Default', api_token_hash, COALESCE(api_token_prefix, ''), $1, now(), now()
+            FROM accounts
+            WHERE deleted_at IS NULL AND COALESCE(api_token_hash, '') <> ''
+        $sql$ USING scopes;
+    END IF;
+
+    IF has_plain THEN
+        EXECUTE format($sql$
+            INSERT INTO account_tokens (account_id, name, token_hash, token_prefix, scopes, created_at, updated_at)
+            SELECT id, 'Default', encode(sha256(convert_to(api_token, 'UTF8')), 'hex'), left(api_token, 8), $1, now(), now()
+            FROM accounts
+            WHERE deleted_at IS NULL AND COALESCE(api_token, '') <> '' %s
+        $sql$, CASE WHEN has_hash THEN $c$AND COALESCE(api_token_hash, '') = ''$c$ ELSE '' END) USING scopes;
+    END IF;
+
+    ALTER TABLE accounts
+        DROP COLUMN IF EXISTS api_token,
+        DROP COLUMN IF EXISTS api_token_hash,
+        DROP COLUMN IF EXISTS api_token_prefix;
+END
+$$;
@@ -0,0 +1,17 @@
+-- Rotating keys that sign access tokens, published as JWKS
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id bigserial,
+    k_id varchar(64) NOT NULL,
+    algorithm varchar(10) NOT NULL,
+    public_key text NOT NULL,
+    private_key text NOT NULL,
+    encrypted boolean NOT NULL DEFAULT false,
+    not_before timestamptz NOT NULL,
+    retires_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_signing_keys_expires_at ON signing_keys (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_k_id ON signing_keys (k_id);
@@ -0,0 +1,10 @@
+-- Previous password hashes, so recent passwords can't be reused
+
+CREATE TABLE IF NOT EXISTS password_history (
+    id bigserial,
+    user_id bigint NOT NULL,
+    password_hash text NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id);
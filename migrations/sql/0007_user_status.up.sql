@@ -0,0 +1,6 @@
+-- Suspended and deactivated users
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS status_changed_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_invites_users;
+DROP INDEX IF EXISTS idx_users_invite_id;
+ALTER TABLE users DROP COLUMN IF EXISTS invite_id;
+DROP TABLE IF EXISTS invites;
@@ -0,0 +1,29 @@
+-- Invite codes for self-registration, and the invite each user registered with
+
+CREATE TABLE IF NOT EXISTS invites (
+    id bigserial,
+    created_by_id bigint NOT NULL,
+    label varchar(100),
+    role varchar(20) NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    code_prefix varchar(16) NOT NULL,
+    max_uses bigint NOT NULL,
+    use_count bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invites_code_hash ON invites (code_hash);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_id bigint;
+CREATE INDEX IF NOT EXISTS idx_users_invite_id ON users (invite_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_invites_users') THEN
+        ALTER TABLE users ADD CONSTRAINT fk_invites_users FOREIGN KEY (invite_id) REFERENCES invites(id);
+    END IF;
+END
+$$;
@@ -0,0 +1,6 @@
+-- Admin behind events recorded while impersonating a user
+
+ALTER TABLE audit_events
+    ADD COLUMN IF NOT EXISTS impersonator_id bigint,
+    ADD COLUMN IF NOT EXISTS impersonator_name varchar(50);
+CREATE INDEX IF NOT EXISTS idx_audit_events_impersonator_id ON audit_events (impersonator_id);
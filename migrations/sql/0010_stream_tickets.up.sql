@@ -0,0 +1,12 @@
+-- Single-use tickets that open live streams in place of an access token in the URL
+
+CREATE TABLE IF NOT EXISTS stream_tickets (
+    id bigserial,
+    ticket_hash varchar(64) NOT NULL,
+    access_token text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_tickets_ticket_hash ON stream_tickets (ticket_hash);
+CREATE INDEX IF NOT EXISTS idx_stream_tickets_expires_at ON stream_tickets (expires_at);
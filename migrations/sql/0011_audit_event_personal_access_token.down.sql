@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_events_personal_access_token_id;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS personal_access_token_id;
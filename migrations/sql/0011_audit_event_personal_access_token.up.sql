@@ -0,0 +1,4 @@
+-- Personal access token behind events recorded for requests authenticated with one
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS personal_access_token_id bigint;
+CREATE INDEX IF NOT EXISTS idx_audit_events_personal_access_token_id ON audit_events (personal_access_token_id);
@@ -0,0 +1,10 @@
+DELETE FROM stream_tickets;
+ALTER TABLE stream_tickets
+    DROP COLUMN IF EXISTS session_id,
+    DROP COLUMN IF EXISTS user_id,
+    DROP COLUMN IF EXISTS token_version,
+    DROP COLUMN IF EXISTS impersonator_id,
+    DROP COLUMN IF EXISTS impersonator_session_id,
+    DROP COLUMN IF EXISTS impersonator_token_version,
+    DROP COLUMN IF EXISTS read_only,
+    ADD COLUMN IF NOT EXISTS access_token text NOT NULL;
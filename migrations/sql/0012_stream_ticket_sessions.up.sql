@@ -0,0 +1,12 @@
+-- Stream tickets name the session they were issued for instead of holding its access token
+
+DELETE FROM stream_tickets;
+ALTER TABLE stream_tickets
+    DROP COLUMN IF EXISTS access_token,
+    ADD COLUMN IF NOT EXISTS session_id varchar(32) NOT NULL,
+    ADD COLUMN IF NOT EXISTS user_id bigint NOT NULL,
+    ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL,
+    ADD COLUMN IF NOT EXISTS impersonator_id bigint,
+    ADD COLUMN IF NOT EXISTS impersonator_session_id varchar(32),
+    ADD COLUMN IF NOT EXISTS impersonator_token_version bigint,
+    ADD COLUMN IF NOT EXISTS read_only boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Stream tickets keep the expiry of the access token they were issued to, which ends the stream
+
+DELETE FROM stream_tickets;
+ALTER TABLE stream_tickets ADD COLUMN IF NOT EXISTS token_expires_at timestamptz NOT NULL;
@@ -0,0 +1,3 @@
+-- Impersonation sessions end at a fixed time, whichever token they are used with
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS expires_at timestamptz;
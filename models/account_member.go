@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Account member roles
+const (
+	AccountRoleViewer  = "viewer"
+	AccountRoleManager = "manager"
+)
+
+// AccountMember grants a user other than the owner access to an account
+type AccountMember struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AccountID   uint      `gorm:"not null;uniqueIndex:idx_account_member" json:"account_id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_account_member;index" json:"user_id"`
+	Role        string    `gorm:"not null;size:20" json:"role"`
+	GrantedByID uint      `gorm:"not null" json:"granted_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	User        User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Account     Account   `gorm:"foreignKey:AccountID" json:"-"`
+}
+
+// TableName specifies the table name for AccountMember model
+func (AccountMember) TableName() string {
+	return "account_members"
+}
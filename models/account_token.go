@@ -0,0 +1,80 @@
+package models
+
+import (
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Account token scopes
+const (
+	ScopeIngestStatistics = "statistics:ingest"
+	ScopeIngestTrades     = "trades:ingest"
+	ScopeIngestHeartbeat  = "heartbeat:ingest"
+	ScopeRead             = "read"
+)
+
+// AccountTokenScopes lists every scope an account token can be granted
+var AccountTokenScopes = []string{ScopeIngestStatistics, ScopeIngestTrades, ScopeIngestHeartbeat, ScopeRead}
+
+// APITokenPrefixLength is how many leading characters of an API token are kept for identification
+const APITokenPrefixLength = 8
+
+// AccountToken is an API token used by automated clients to access one account.
+// Only a hash of the token is stored.
+type AccountToken struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	AccountID   uint       `gorm:"not null;index" json:"account_id"`
+	Name        string     `gorm:"not null;size:100" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	TokenPrefix string     `gorm:"not null;size:16" json:"token_prefix"`
+	Scopes      []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	AllowedIPs  []string   `gorm:"serializer:json;type:text" json:"allowed_ips"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:45" json:"last_used_ip"`
+	Token       string     `gorm:"-" json:"token,omitempty"` // Plaintext, only set when the token was just issued
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Account     Account    `gorm:"foreignKey:AccountID" json:"-"`
+}
+
+// TableName specifies the table name for AccountToken model
+func (AccountToken) TableName() string {
+	return "account_tokens"
+}
+
+// IsExpired checks if the token has passed its expiry
+func (t *AccountToken) IsExpired() bool {
+	return t.ExpiresAt != nil && !time.Now().Before(*t.ExpiresAt)
+}
+
+// HasScope checks if the token was granted a scope
+func (t *AccountToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// AllowsIP checks the client IP against the token's allowlist; an empty list allows any IP
+func (t *AccountToken) AllowsIP(ip string) bool {
+	if len(t.AllowedIPs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, entry := range t.AllowedIPs {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if allowed, err := netip.ParseAddr(entry); err == nil && allowed.Unmap() == addr {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit event actions
+const (
+	AuditUserCreate           = "user.create"
+	AuditUserUpdate           = "user.update"
+	AuditUserPasswordSet      = "user.password_set"
+	AuditUserDelete           = "user.delete"
+	AuditUserEmailChange      = "user.email_change"
+	AuditUserPasswordReset    = "user.password_reset_issued"
+	AuditUserUnlock           = "user.unlock"
+	AuditUserStatusChange     = "user.status_change"
+	AuditAccountCreate        = "account.create"
+	AuditAccountUpdate        = "account.update"
+	AuditAccountDelete        = "account.delete"
+	AuditAccountTokenCreate   = "account_token.create"
+	AuditAccountTokenRotate   = "account_token.rotate"
+	AuditAccountTokenRevoke   = "account_token.revoke"
+	AuditAccountMemberGrant   = "account_member.grant"
+	AuditAccountMemberRevoke  = "account_member.revoke"
+	AuditShareLinkCreate      = "share_link.create"
+	AuditShareLinkRevoke      = "share_link.revoke"
+	AuditOrgMemberAdd         = "organization_member.add"
+	AuditOrgMemberUpdate      = "organization_member.update"
+	AuditOrgMemberRemove      = "organization_member.remove"
+	AuditRoleCreate           = "role.create"
+	AuditRoleUpdate           = "role.update"
+	AuditRoleDelete           = "role.delete"
+	AuditSettingsUpdate       = "settings.update"
+	AuditPersonalTokenCreate  = "personal_access_token.create"
+	AuditPersonalTokenRevoke  = "personal_access_token.revoke"
+	AuditInviteCreate         = "invite.create"
+	AuditInviteRevoke         = "invite.revoke"
+	AuditImpersonationStart   = "impersonation.start"
+	AuditImpersonationEnd     = "impersonation.end"
+	AuditImpersonatedRequest  = "impersonation.request"
+	AuditLogin                = "auth.login"
+	AuditLogout               = "auth.logout"
+	AuditLogoutAll            = "auth.logout_all"
+	AuditPasswordChange       = "auth.password_change"
+	AuditPasswordReset        = "auth.password_reset"
+	AuditTwoFactorEnable      = "auth.2fa_enable"
+	AuditTwoFactorDisable     = "auth.2fa_disable"
+	AuditRecoveryCodesReissue = "auth.recovery_codes_regenerate"
+)
+
+// Audit event target types
+const (
+	AuditTargetUser                = "user"
+	AuditTargetAccount             = "account"
+	AuditTargetAccountToken        = "account_token"
+	AuditTargetPersonalAccessToken = "personal_access_token"
+	AuditTargetInvite              = "invite"
+	AuditTargetAccountMember       = "account_member"
+	AuditTargetShareLink           = "share_link"
+	AuditTargetOrganizationMember  = "organization_member"
+	AuditTargetRole                = "role"
+	AuditTargetSettings            = "settings"
+)
+
+// AuditEvent records who did what to which object. Events are append-only: the
+// database rejects updates and deletes. Before and After hold the changed fields
+// of the target, or a full snapshot when it was created or deleted.
+type AuditEvent struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	ActorID   *uint  `gorm:"index" json:"actor_id"` // nil for anonymous requests and the system
+	ActorName string `gorm:"size:50" json:"actor_name"`
+	// Admin acting as the actor through impersonation
+	ImpersonatorID   *uint  `gorm:"index" json:"impersonator_id,omitempty"`
+	ImpersonatorName string `gorm:"size:50" json:"impersonator_name,omitempty"`
+	// Personal access token the request was authenticated with, nil for sessions
+	PersonalAccessTokenID *uint                  `gorm:"index" json:"personal_access_token_id,omitempty"`
+	Action                string                 `gorm:"not null;size:64;index" json:"action"`
+	TargetType            string                 `gorm:"not null;size:32;index:idx_audit_event_target" json:"target_type"`
+	TargetID              uint                   `gorm:"index:idx_audit_event_target" json:"target_id"`
+	Before                map[string]interface{} `gorm:"serializer:json;type:text" json:"before,omitempty"`
+	After                 map[string]interface{} `gorm:"serializer:json;type:text" json:"after,omitempty"`
+	IP                    string                 `gorm:"size:45" json:"ip"`
+	RequestID             string                 `gorm:"size:64;index" json:"request_id"`
+	CreatedAt             time.Time              `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for AuditEvent model
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
@@ -24,24 +24,6 @@ func InitDB(dsn string) (*gorm.DB, error) {
 	return DB, nil
 }
 
-// AutoMigrate runs database migrations
-func AutoMigrate(db *gorm.DB) error {
-	log.Println("Running database migrations...")
-	
-	err := db.AutoMigrate(
-		&User{},
-		&Account{},
-		&Statistic{},
-	)
-	
-	if err != nil {
-		return err
-	}
-	
-	log.Println("Database migrations completed successfully")
-	return nil
-}
-
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerification represents a pending email address verification
+type EmailVerification struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"not null;size:255" json:"email"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for EmailVerification model
+func (EmailVerification) TableName() string {
+	return "email_verifications"
+}
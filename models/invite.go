@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Invite lets people register themselves with a preset role, up to MaxUses times before
+// it expires. Only a hash of the invite code is stored.
+type Invite struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedByID uint       `gorm:"not null" json:"created_by_id"`
+	Label       string     `gorm:"size:100" json:"label"`
+	Role        string     `gorm:"not null;size:20" json:"role"`
+	CodeHash    string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	CodePrefix  string     `gorm:"not null;size:16" json:"code_prefix"`
+	MaxUses     int        `gorm:"not null" json:"max_uses"`
+	UseCount    int        `gorm:"not null;default:0" json:"use_count"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	Code        string     `gorm:"-" json:"code,omitempty"` // Plaintext, only set when the invite was just created
+	URL         string     `gorm:"-" json:"url,omitempty"`  // Registration link, only set when the invite was just created
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Users       []User     `gorm:"foreignKey:InviteID" json:"users,omitempty"` // users who registered with the invite
+}
+
+// TableName specifies the table name for Invite model
+func (Invite) TableName() string {
+	return "invites"
+}
+
+// IsUsable checks if the invite can still be redeemed
+func (i *Invite) IsUsable() bool {
+	return i.RevokedAt == nil && i.UseCount < i.MaxUses && time.Now().Before(i.ExpiresAt)
+}
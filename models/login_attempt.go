@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt records a successful or failed login
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"`
+	Username  string    `gorm:"not null;size:50;index" json:"username"`
+	IP        string    `gorm:"not null;size:45;index:idx_login_attempt_ip_time" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Success   bool      `gorm:"not null" json:"success"`
+	Reason    string    `gorm:"size:50" json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"index:idx_login_attempt_ip_time" json:"created_at"`
+}
+
+// TableName specifies the table name for LoginAttempt model
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
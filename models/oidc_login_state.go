@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCLoginState holds the secrets of a single sign-on login between the redirect to the
+// identity provider and the callback. It is looked up by the hashed state parameter.
+type OIDCLoginState struct {
+	ID           uint      `gorm:"primarykey"`
+	StateHash    string    `gorm:"uniqueIndex;not null;size:64"`
+	Nonce        string    `gorm:"not null;size:64"`
+	CodeVerifier string    `gorm:"not null;size:128"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+// TableName specifies the table name for OIDCLoginState model
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization member roles, from most to least privileged
+const (
+	OrgRoleOwner   = "owner"
+	OrgRoleManager = "manager"
+	OrgRoleAnalyst = "analyst"
+	OrgRoleViewer  = "viewer"
+)
+
+// orgRoleRanks orders organization roles so they can be compared
+var orgRoleRanks = map[string]int{
+	OrgRoleViewer:  1,
+	OrgRoleAnalyst: 2,
+	OrgRoleManager: 3,
+	OrgRoleOwner:   4,
+}
+
+// OrgRoleRank returns the rank of an organization role; unknown roles rank 0
+func OrgRoleRank(role string) int {
+	return orgRoleRanks[role]
+}
+
+// Organization groups users and accounts, e.g. a trading desk
+type Organization struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Name        string         `gorm:"not null;size:100" json:"name"`
+	MaxAccounts int            `gorm:"not null;default:0" json:"max_accounts"` // 0 means unlimited
+	MaxMembers  int            `gorm:"not null;default:0" json:"max_members"`  // 0 means unlimited
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Role        string         `gorm:"->;-:migration" json:"role,omitempty"` // Caller's role, only read in membership listings
+}
+
+// TableName specifies the table name for Organization model
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationMember gives a user a role in an organization
+type OrganizationMember struct {
+	ID             uint         `gorm:"primarykey" json:"id"`
+	OrganizationID uint         `gorm:"not null;uniqueIndex:idx_org_member" json:"organization_id"`
+	UserID         uint         `gorm:"not null;uniqueIndex:idx_org_member;index" json:"user_id"`
+	Role           string       `gorm:"not null;size:20" json:"role"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	User           User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID" json:"-"`
+}
+
+// TableName specifies the table name for OrganizationMember model
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory is the hash of a password a user had before, kept so it isn't reused
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for PasswordHistory model
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
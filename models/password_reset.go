@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset represents a single-use password reset token issued by an admin
+type PasswordReset struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	CreatedByID uint       `gorm:"not null" json:"created_by_id"`
+	TokenHash   string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for PasswordReset model
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, telling it apart from a JWT
+const PersonalAccessTokenPrefix = "xtp_"
+
+// PersonalAccessToken lets a user's scripts call the API without a login. Its scopes are
+// permissions; a request gets the scopes the owner's role still grants. Only a hash of the
+// token is stored.
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"not null;size:100" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	TokenPrefix string     `gorm:"not null;size:16" json:"token_prefix"`
+	Scopes      []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:45" json:"last_used_ip"`
+	Token       string     `gorm:"-" json:"token,omitempty"` // Plaintext, only set when the token was just issued
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for PersonalAccessToken model
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// IsExpired checks if the token has passed its expiry
+func (t *PersonalAccessToken) IsExpired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+)
+
+// Permissions checked by the API. Account permissions cover the caller's own accounts and
+// accounts shared with them; the manage_all variants extend an action to everyone's.
+const (
+	PermAccountsRead           = "accounts:read"
+	PermAccountsWrite          = "accounts:write"
+	PermAccountsDelete         = "accounts:delete"
+	PermAccountsManageAll      = "accounts:manage_all"
+	PermUsersRead              = "users:read"
+	PermUsersManage            = "users:manage"
+	PermUsersImpersonate       = "users:impersonate"
+	PermRolesManage            = "roles:manage"
+	PermOrganizationsWrite     = "organizations:write"
+	PermOrganizationsManageAll = "organizations:manage_all"
+	PermSettingsManage         = "settings:manage"
+	PermAuditRead              = "audit:read"
+)
+
+// Permissions lists every permission a role can hold
+var Permissions = []string{
+	PermAccountsRead,
+	PermAccountsWrite,
+	PermAccountsDelete,
+	PermAccountsManageAll,
+	PermUsersRead,
+	PermUsersManage,
+	PermUsersImpersonate,
+	PermRolesManage,
+	PermOrganizationsWrite,
+	PermOrganizationsManageAll,
+	PermSettingsManage,
+	PermAuditRead,
+}
+
+// Built-in role names
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// BuiltInRoles are the roles defined in code; they can't be changed or deleted
+var BuiltInRoles = []Role{
+	{
+		Name:        RoleAdmin,
+		Description: "Full access to every user, account and setting",
+		Permissions: Permissions,
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleUser,
+		Description: "Manages their own accounts and organizations",
+		Permissions: []string{PermAccountsRead, PermAccountsWrite, PermAccountsDelete, PermOrganizationsWrite},
+		BuiltIn:     true,
+	},
+}
+
+// Role is a named set of permissions assigned to users through User.Role
+type Role struct {
+	ID          uint      `gorm:"primarykey" json:"id,omitempty"`
+	Name        string    `gorm:"uniqueIndex;not null;size:20" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Permissions []string  `gorm:"serializer:json;type:text" json:"permissions"`
+	BuiltIn     bool      `gorm:"-" json:"built_in"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// TableName specifies the table name for Role model
+func (Role) TableName() string {
+	return "roles"
+}
+
+// IsPermission checks if a permission exists
+func IsPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionSet is the effective permissions of a caller
+type PermissionSet map[string]struct{}
+
+// NewPermissionSet builds a permission set from a list of permissions
+func NewPermissionSet(permissions []string) PermissionSet {
+	set := make(PermissionSet, len(permissions))
+	for _, p := range permissions {
+		set[p] = struct{}{}
+	}
+	return set
+}
+
+// Has checks if the set holds a permission
+func (s PermissionSet) Has(permission string) bool {
+	_, ok := s[permission]
+	return ok
+}
+
+// List returns the permissions in the set in catalogue order
+func (s PermissionSet) List() []string {
+	list := make([]string, 0, len(s))
+	for _, p := range Permissions {
+		if s.Has(p) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// Intersect returns the permissions held by both the set and the list
+func (s PermissionSet) Intersect(permissions []string) PermissionSet {
+	set := make(PermissionSet, len(permissions))
+	for _, p := range permissions {
+		if s.Has(p) {
+			set[p] = struct{}{}
+		}
+	}
+	return set
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Session represents a login session; every refresh token rotated from one login shares it
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:32" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	IP         string     `gorm:"size:45" json:"ip"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // set on impersonation sessions, which can't be extended
+	LastUsedAt time.Time  `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for Session model
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsRevoked checks if the session has been revoked
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsExpired checks if a session with a fixed end has ended
+func (s *Session) IsExpired() bool {
+	return s.ExpiresAt != nil && !time.Now().Before(*s.ExpiresAt)
+}
+
+// RefreshToken represents a single-use refresh token belonging to a session
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	SessionID string     `gorm:"not null;size:32;index" json:"session_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Session   Session    `gorm:"foreignKey:SessionID" json:"-"`
+}
+
+// TableName specifies the table name for RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
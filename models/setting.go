@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting keys
+const (
+	SettingRequireAdmin2FA = "security.require_admin_2fa"
+)
+
+// Setting represents a system-wide setting managed by admins
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Setting model
+func (Setting) TableName() string {
+	return "settings"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// ShareLink grants public read-only access to one account's performance.
+// Only a hash of the link token is stored.
+type ShareLink struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	AccountID       uint       `gorm:"not null;index" json:"account_id"`
+	CreatedByID     uint       `gorm:"not null" json:"created_by_id"`
+	Label           string     `gorm:"size:100" json:"label"`
+	TokenHash       string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	TokenPrefix     string     `gorm:"not null;size:16" json:"token_prefix"`
+	ShowAccountName bool       `gorm:"not null;default:false" json:"show_account_name"`
+	ShowBalances    bool       `gorm:"not null;default:false" json:"show_balances"` // Absolute balances and P/L; percentages are always shown
+	ShowEquityCurve bool       `gorm:"not null;default:false" json:"show_equity_curve"`
+	ShowDrawdown    bool       `gorm:"not null;default:false" json:"show_drawdown"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	LastViewedAt    *time.Time `json:"last_viewed_at"`
+	URL             string     `gorm:"-" json:"url,omitempty"` // Only set when the link was just created
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Account         Account    `gorm:"foreignKey:AccountID" json:"-"`
+}
+
+// TableName specifies the table name for ShareLink model
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// IsExpired checks if the link has passed its expiry
+func (l *ShareLink) IsExpired() bool {
+	return l.ExpiresAt != nil && !time.Now().Before(*l.ExpiresAt)
+}
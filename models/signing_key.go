@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey is an asymmetric key pair access tokens are signed with, named by the
+// token's kid header. A key signs from NotBefore until RetiresAt and is published for
+// verification until ExpiresAt, so tokens it signed stay valid through a rotation.
+type SigningKey struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	KID        string    `gorm:"uniqueIndex;not null;size:64" json:"kid"`
+	Algorithm  string    `gorm:"not null;size:10" json:"algorithm"`
+	PublicKey  string    `gorm:"type:text;not null" json:"-"` // PEM, PKIX
+	PrivateKey string    `gorm:"type:text;not null" json:"-"` // PEM, PKCS #8; sealed when Encrypted
+	Encrypted  bool      `gorm:"not null;default:false" json:"encrypted"`
+	NotBefore  time.Time `gorm:"not null" json:"not_before"`
+	RetiresAt  time.Time `gorm:"not null" json:"retires_at"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SigningKey model
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
+
+// IsSigning checks if the key is the one to sign new tokens with at the given time
+func (k *SigningKey) IsSigning(at time.Time) bool {
+	return !at.Before(k.NotBefore) && at.Before(k.RetiresAt)
+}
+
+// IsExpired checks if tokens signed with the key can no longer be valid
+func (k *SigningKey) IsExpired(at time.Time) bool {
+	return !at.Before(k.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// StreamTicket lets an EventSource, which cannot set request headers, open a live stream
+// for the session that asked for it. It is looked up by the hashed ticket, used once and
+// lives for seconds, and holds no credential, so neither the ticket in the URL nor the row
+// can be replayed once logged.
+type StreamTicket struct {
+	ID           uint   `gorm:"primarykey"`
+	TicketHash   string `gorm:"uniqueIndex;not null;size:64"`
+	SessionID    string `gorm:"not null;size:32"`
+	UserID       uint   `gorm:"not null"`
+	TokenVersion int    `gorm:"not null"`
+	// Impersonator fields are set when the ticket was issued to an impersonation token
+	ImpersonatorID           *uint
+	ImpersonatorSessionID    string `gorm:"size:32"`
+	ImpersonatorTokenVersion int
+	ReadOnly                 bool `gorm:"not null;default:false"`
+	// TokenExpiresAt is when the access token the ticket was issued to expires; the stream ends then
+	TokenExpiresAt time.Time `gorm:"not null"`
+	ExpiresAt      time.Time `gorm:"not null;index"`
+	CreatedAt      time.Time
+}
+
+// TableName specifies the table name for StreamTicket model
+func (StreamTicket) TableName() string {
+	return "stream_tickets"
+}
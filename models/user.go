@@ -6,16 +6,39 @@ import (
 	"gorm.io/gorm"
 )
 
+// User statuses. Suspended and deactivated users can't log in or use any token, and
+// their personal accounts stop accepting ingestion until they are reactivated.
+const (
+	UserStatusActive      = "active"
+	UserStatusSuspended   = "suspended"   // temporarily blocked
+	UserStatusDeactivated = "deactivated" // left the team, kept for their history
+)
+
 // User represents a user in the system
 type User struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;not null;size:50" json:"username"`
-	PasswordHash string         `gorm:"not null" json:"-"`
-	Role         string         `gorm:"not null;size:20;default:'user'" json:"role"` // admin or user
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-	Accounts     []Account      `gorm:"foreignKey:UserID" json:"accounts,omitempty"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	Username           string         `gorm:"uniqueIndex;not null;size:50" json:"username"`
+	PasswordHash       string         `gorm:"not null" json:"-"`
+	Role               string         `gorm:"not null;size:20;default:'user'" json:"role"` // built-in (admin, user) or custom role name
+	Status             string         `gorm:"not null;size:20;default:'active';index" json:"status"`
+	StatusChangedAt    *time.Time     `json:"status_changed_at,omitempty"`
+	InviteID           *uint          `gorm:"index" json:"invite_id,omitempty"` // invite the user registered with
+	Email              string         `gorm:"size:255;index" json:"email,omitempty"`
+	EmailVerifiedAt    *time.Time     `json:"email_verified_at,omitempty"`
+	DigestEnabled      bool           `gorm:"not null;default:false" json:"digest_enabled"`
+	LastDigestAt       *time.Time     `json:"-"`
+	TokenVersion       int            `gorm:"not null;default:1" json:"-"` // bumped to invalidate all issued tokens
+	MustChangePassword bool           `gorm:"not null;default:false" json:"must_change_password"`
+	TOTPSecret         string         `gorm:"size:64" json:"-"`
+	TOTPEnabled        bool           `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep       int64          `gorm:"not null;default:0" json:"-"` // last accepted time step, prevents code replay
+	FailedLoginCount   int            `gorm:"not null;default:0" json:"-"`
+	LastFailedLoginAt  *time.Time     `json:"-"`
+	LockedUntil        *time.Time     `json:"locked_until,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+	Accounts           []Account      `gorm:"foreignKey:UserID" json:"accounts,omitempty"`
 }
 
 // TableName specifies the table name for User model
@@ -25,5 +48,25 @@ func (User) TableName() string {
 
 // IsAdmin checks if the user has admin role
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return u.Role == RoleAdmin
+}
+
+// HasVerifiedEmail checks if the user has a verified email address
+func (u *User) HasVerifiedEmail() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
+// IsActive checks if the user may log in and use their tokens
+func (u *User) IsActive() bool {
+	return u.Status == UserStatusActive
+}
+
+// IsValidUserStatus checks if status is one of the user statuses
+func IsValidUserStatus(status string) bool {
+	return status == UserStatusActive || status == UserStatusSuspended || status == UserStatusDeactivated
+}
+
+// IsLocked checks if the user is temporarily locked out after failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
 }
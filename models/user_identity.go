@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	Issuer      string    `gorm:"not null;size:255;uniqueIndex:idx_user_identity_subject" json:"issuer"`
+	Subject     string    `gorm:"not null;size:255;uniqueIndex:idx_user_identity_subject" json:"subject"`
+	Email       string    `gorm:"size:255" json:"email,omitempty"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	User        User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for UserIdentity model
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"x-track/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AccountMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountMemberRepository(db *gorm.DB) *AccountMemberRepository {
+	return &AccountMemberRepository{db: db}
+}
+
+// Upsert grants a user access to an account, replacing the role of an existing grant
+func (r *AccountMemberRepository) Upsert(member *models.AccountMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "granted_by_id", "updated_at"}),
+	}).Create(member).Error
+}
+
+// Find finds a user's grant on an account
+func (r *AccountMemberRepository) Find(accountID, userID uint) (*models.AccountMember, error) {
+	var member models.AccountMember
+	if err := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// FindByAccountID finds all members of an account with their users
+func (r *AccountMemberRepository) FindByAccountID(accountID uint) ([]models.AccountMember, error) {
+	var members []models.AccountMember
+	if err := r.db.Preload("User").Where("account_id = ?", accountID).Order("created_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// FindByUserID finds all grants of a user with their accounts and owners
+func (r *AccountMemberRepository) FindByUserID(userID uint) ([]models.AccountMember, error) {
+	var members []models.AccountMember
+	if err := r.db.Preload("Account.User").
+		Joins("JOIN accounts ON accounts.id = account_members.account_id AND accounts.deleted_at IS NULL").
+		Where("account_members.user_id = ?", userID).
+		Order("account_members.created_at").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Delete revokes a user's access to an account
+func (r *AccountMemberRepository) Delete(accountID, userID uint) error {
+	result := r.db.Where("account_id = ? AND user_id = ?", accountID, userID).Delete(&models.AccountMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByAccountID revokes all grants on an account
+func (r *AccountMemberRepository) DeleteByAccountID(accountID uint) error {
+	return r.db.Where("account_id = ?", accountID).Delete(&models.AccountMember{}).Error
+}
+
+// DeleteByUserID revokes all grants of a user
+func (r *AccountMemberRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.AccountMember{}).Error
+}
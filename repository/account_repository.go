@@ -37,13 +37,41 @@ func (r *AccountRepository) FindByUserID(userID uint) ([]models.Account, error)
 	return accounts, nil
 }
 
-// FindByToken finds an account by API token
-func (r *AccountRepository) FindByToken(token string) (*models.Account, error) {
-	var account models.Account
-	if err := r.db.Where("api_token = ?", token).First(&account).Error; err != nil {
+// FindPersonalByUserID finds the accounts a user created outside any organization
+func (r *AccountRepository) FindPersonalByUserID(userID uint) ([]models.Account, error) {
+	var accounts []models.Account
+	if err := r.db.Where("user_id = ? AND organization_id IS NULL", userID).Find(&accounts).Error; err != nil {
 		return nil, err
 	}
-	return &account, nil
+	return accounts, nil
+}
+
+// FindByOrganizationID finds all accounts of an organization
+func (r *AccountRepository) FindByOrganizationID(orgID uint) ([]models.Account, error) {
+	var accounts []models.Account
+	if err := r.db.Where("organization_id = ?", orgID).Order("name").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// FindByOrganizationIDs finds all accounts of several organizations
+func (r *AccountRepository) FindByOrganizationIDs(orgIDs []uint) ([]models.Account, error) {
+	var accounts []models.Account
+	if len(orgIDs) == 0 {
+		return accounts, nil
+	}
+	if err := r.db.Where("organization_id IN ?", orgIDs).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// CountByOrganizationID counts the accounts of an organization
+func (r *AccountRepository) CountByOrganizationID(orgID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Account{}).Where("organization_id = ?", orgID).Count(&count).Error
+	return count, err
 }
 
 // FindAll retrieves all accounts
@@ -64,13 +92,3 @@ func (r *AccountRepository) Update(account *models.Account) error {
 func (r *AccountRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Account{}, id).Error
 }
-
-// TokenExists checks if a token already exists
-func (r *AccountRepository) TokenExists(token string) (bool, error) {
-	var count int64
-	err := r.db.Model(&models.Account{}).Where("api_token = ?", token).Count(&count).Error
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
-}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type AccountTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountTokenRepository(db *gorm.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+// Create creates a new account token
+func (r *AccountTokenRepository) Create(token *models.AccountToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByID finds a token by ID within an account
+func (r *AccountTokenRepository) FindByID(accountID, id uint) (*models.AccountToken, error) {
+	var token models.AccountToken
+	if err := r.db.Where("account_id = ?", accountID).First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByHash finds a token by its hash, with its account and the account's owner
+func (r *AccountTokenRepository) FindByHash(tokenHash string) (*models.AccountToken, error) {
+	var token models.AccountToken
+	if err := r.db.Preload("Account.User").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByAccountID finds all tokens of an account
+func (r *AccountTokenRepository) FindByAccountID(accountID uint) ([]models.AccountToken, error) {
+	var tokens []models.AccountToken
+	if err := r.db.Where("account_id = ?", accountID).Order("created_at").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// HashExists checks if a token hash already exists
+func (r *AccountTokenRepository) HashExists(tokenHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.AccountToken{}).Where("token_hash = ?", tokenHash).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SetExpiry sets when a token stops working
+func (r *AccountTokenRepository) SetExpiry(id uint, expiresAt time.Time) error {
+	return r.db.Model(&models.AccountToken{}).Where("id = ?", id).Update("expires_at", expiresAt).Error
+}
+
+// Touch records when and from where a token was last used
+func (r *AccountTokenRepository) Touch(id uint, ip string) error {
+	return r.db.Model(&models.AccountToken{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": time.Now(), "last_used_ip": ip}).Error
+}
+
+// Delete deletes a token
+func (r *AccountTokenRepository) Delete(accountID, id uint) error {
+	result := r.db.Where("account_id = ?", accountID).Delete(&models.AccountToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByAccountID deletes all tokens of an account
+func (r *AccountTokenRepository) DeleteByAccountID(accountID uint) error {
+	return r.db.Where("account_id = ?", accountID).Delete(&models.AccountToken{}).Error
+}
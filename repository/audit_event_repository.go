@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditEventFilter narrows an audit event query; zero values match everything
+type AuditEventFilter struct {
+	ActorID        *uint
+	ImpersonatorID *uint
+	// Personal access token the actor used
+	PersonalAccessTokenID *uint
+	Action                string
+	TargetType            string
+	TargetID              *uint
+	RequestID             string
+	From                  *time.Time
+	To                    *time.Time
+}
+
+// apply adds the filter's conditions to a query
+func (f AuditEventFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.ActorID != nil {
+		db = db.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.ImpersonatorID != nil {
+		db = db.Where("impersonator_id = ?", *f.ImpersonatorID)
+	}
+	if f.PersonalAccessTokenID != nil {
+		db = db.Where("personal_access_token_id = ?", *f.PersonalAccessTokenID)
+	}
+	if f.Action != "" {
+		db = db.Where("action = ?", f.Action)
+	}
+	if f.TargetType != "" {
+		db = db.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != nil {
+		db = db.Where("target_id = ?", *f.TargetID)
+	}
+	if f.RequestID != "" {
+		db = db.Where("request_id = ?", f.RequestID)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at <= ?", *f.To)
+	}
+	return db
+}
+
+// AuditEventRepository appends and queries audit events. It has no update or delete
+// methods: the audit log is append-only.
+type AuditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) *AuditEventRepository {
+	return &AuditEventRepository{db: db}
+}
+
+// Create appends an audit event
+func (r *AuditEventRepository) Create(event *models.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Find finds audit events matching a filter with pagination, newest first
+func (r *AuditEventRepository) Find(filter AuditEventFilter, page, pageSize int) ([]models.AuditEvent, int64, error) {
+	var events []models.AuditEvent
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := filter.apply(r.db.Model(&models.AuditEvent{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := filter.apply(r.db).
+		Order("created_at DESC, id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// FindInBatches passes audit events matching a filter to fn in batches, oldest first
+func (r *AuditEventRepository) FindInBatches(filter AuditEventFilter, batchSize int, fn func([]models.AuditEvent) error) error {
+	var batch []models.AuditEvent
+	return filter.apply(r.db).FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// Create creates a new email verification
+func (r *EmailVerificationRepository) Create(verification *models.EmailVerification) error {
+	return r.db.Create(verification).Error
+}
+
+// FindByTokenHash finds an unused, unexpired verification by token hash
+func (r *EmailVerificationRepository) FindByTokenHash(tokenHash string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	if err := r.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&verification).Error; err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// MarkUsed marks a verification as used
+func (r *EmailVerificationRepository) MarkUsed(id uint) error {
+	return r.db.Model(&models.EmailVerification{}).Where("id = ?", id).Update("used_at", time.Now()).Error
+}
+
+// DeleteByUserID deletes all pending verifications for a user
+func (r *EmailVerificationRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ? AND used_at IS NULL", userID).Delete(&models.EmailVerification{}).Error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type InviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+// Create creates a new invite
+func (r *InviteRepository) Create(invite *models.Invite) error {
+	return r.db.Create(invite).Error
+}
+
+// FindAll finds all invites, newest first, with the users who registered with them
+func (r *InviteRepository) FindAll() ([]models.Invite, error) {
+	var invites []models.Invite
+	if err := r.db.Preload("Users").Order("created_at DESC").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// FindByID finds an invite by ID
+func (r *InviteRepository) FindByID(id uint) (*models.Invite, error) {
+	var invite models.Invite
+	if err := r.db.First(&invite, id).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// FindByHash finds an invite by its code hash
+func (r *InviteRepository) FindByHash(codeHash string) (*models.Invite, error) {
+	var invite models.Invite
+	if err := r.db.Where("code_hash = ?", codeHash).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// HashExists checks if an invite code hash is already taken
+func (r *InviteRepository) HashExists(codeHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Invite{}).Where("code_hash = ?", codeHash).Count(&count).Error
+	return count > 0, err
+}
+
+// Claim uses up one redemption of an invite, returning false if it is revoked, expired
+// or used up (e.g. by a concurrent registration)
+func (r *InviteRepository) Claim(id uint) (bool, error) {
+	result := r.db.Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL AND use_count < max_uses AND expires_at > ?", id, time.Now()).
+		UpdateColumn("use_count", gorm.Expr("use_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release gives back a redemption claimed for a registration that failed
+func (r *InviteRepository) Release(id uint) error {
+	return r.db.Model(&models.Invite{}).Where("id = ? AND use_count > 0", id).
+		UpdateColumn("use_count", gorm.Expr("use_count - 1")).Error
+}
+
+// Revoke stops an invite from being redeemed
+func (r *InviteRepository) Revoke(id uint) (bool, error) {
+	result := r.db.Model(&models.Invite{}).Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
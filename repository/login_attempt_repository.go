@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// Create creates a new login attempt
+func (r *LoginAttemptRepository) Create(attempt *models.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// CountFailuresByIP counts failed logins with one of the given reasons from an IP since the given time
+func (r *LoginAttemptRepository) CountFailuresByIP(ip string, reasons []string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.LoginAttempt{}).
+		Where("ip = ? AND success = ? AND reason IN ? AND created_at >= ?", ip, false, reasons, since).
+		Count(&count).Error
+	return count, err
+}
+
+// FindFailureTimesByUsername returns when logins for a username failed with one of the given
+// reasons since the given time, oldest first
+func (r *LoginAttemptRepository) FindFailureTimesByUsername(username string, reasons []string, since time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.db.Model(&models.LoginAttempt{}).
+		Where("username = ? AND success = ? AND reason IN ? AND created_at >= ?", username, false, reasons, since).
+		Order("created_at ASC").
+		Pluck("created_at", &times).Error
+	return times, err
+}
+
+// FindByUserID finds login attempts for a user with pagination
+func (r *LoginAttemptRepository) FindByUserID(userID uint, page, pageSize int) ([]models.LoginAttempt, int64, error) {
+	var attempts []models.LoginAttempt
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := r.db.Model(&models.LoginAttempt{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, total, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OIDCLoginStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCLoginStateRepository(db *gorm.DB) *OIDCLoginStateRepository {
+	return &OIDCLoginStateRepository{db: db}
+}
+
+// Create stores the secrets of a pending login
+func (r *OIDCLoginStateRepository) Create(state *models.OIDCLoginState) error {
+	return r.db.Create(state).Error
+}
+
+// Consume deletes and returns an unexpired pending login, so each state is used at most once
+func (r *OIDCLoginStateRepository) Consume(stateHash string) (*models.OIDCLoginState, error) {
+	var states []models.OIDCLoginState
+	err := r.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &states[0], nil
+}
+
+// DeleteExpired removes abandoned logins
+func (r *OIDCLoginStateRepository) DeleteExpired() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&models.OIDCLoginState{}).Error
+}
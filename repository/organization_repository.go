@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"x-track/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// Create creates an organization with its first owner
+func (r *OrganizationRepository) Create(org *models.Organization, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         ownerID,
+			Role:           models.OrgRoleOwner,
+		}).Error
+	})
+}
+
+// FindByID finds an organization by ID
+func (r *OrganizationRepository) FindByID(id uint) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.First(&org, id).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// FindAll retrieves all organizations
+func (r *OrganizationRepository) FindAll() ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := r.db.Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// FindByUserID retrieves the organizations a user belongs to, with the user's role in each
+func (r *OrganizationRepository) FindByUserID(userID uint) ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := r.db.Select("organizations.*, organization_members.role AS role").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.name").
+		Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// Update updates an organization
+func (r *OrganizationRepository) Update(org *models.Organization) error {
+	return r.db.Save(org).Error
+}
+
+// Delete deletes an organization and its memberships
+func (r *OrganizationRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Organization{}, id).Error
+	})
+}
+
+// FindMember finds a user's membership in an organization
+func (r *OrganizationRepository) FindMember(orgID, userID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// FindMembers finds all members of an organization with their users
+func (r *OrganizationRepository) FindMembers(orgID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	if err := r.db.Preload("User").Where("organization_id = ?", orgID).Order("created_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// FindMembershipsByUserID finds all organization memberships of a user
+func (r *OrganizationRepository) FindMembershipsByUserID(userID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	if err := r.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// CountMembers counts the members of an organization, optionally only those with a role
+func (r *OrganizationRepository) CountMembers(orgID uint, role string) (int64, error) {
+	var count int64
+	query := r.db.Model(&models.OrganizationMember{}).Where("organization_id = ?", orgID)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// UpsertMember adds a user to an organization or changes their role
+func (r *OrganizationRepository) UpsertMember(member *models.OrganizationMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+// DeleteMember removes a user from an organization
+func (r *OrganizationRepository) DeleteMember(orgID, userID uint) error {
+	result := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMembershipsByUserID removes a user from all organizations
+func (r *OrganizationRepository) DeleteMembershipsByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.OrganizationMember{}).Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Create records a previous password of a user
+func (r *PasswordHistoryRepository) Create(entry *models.PasswordHistory) error {
+	return r.db.Create(entry).Error
+}
+
+// FindRecent finds a user's most recent previous passwords, newest first
+func (r *PasswordHistoryRepository) FindRecent(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Prune deletes all but a user's most recent keep previous passwords
+func (r *PasswordHistoryRepository) Prune(userID uint, keep int) error {
+	if keep <= 0 {
+		return r.DeleteByUserID(userID)
+	}
+	recent := r.db.Model(&models.PasswordHistory{}).Select("id").
+		Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(keep)
+	return r.db.Where("user_id = ? AND id NOT IN (?)", userID, recent).Delete(&models.PasswordHistory{}).Error
+}
+
+// DeleteByUserID deletes a user's password history
+func (r *PasswordHistoryRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error
+}
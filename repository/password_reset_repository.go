@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create creates a new password reset
+func (r *PasswordResetRepository) Create(reset *models.PasswordReset) error {
+	return r.db.Create(reset).Error
+}
+
+// FindByTokenHash finds an unused, unexpired password reset by token hash
+func (r *PasswordResetRepository) FindByTokenHash(tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := r.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&reset).Error; err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// MarkUsed marks a password reset as used, returning false if it was already used
+func (r *PasswordResetRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.PasswordReset{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteUnusedByUserID deletes all outstanding password resets for a user
+func (r *PasswordResetRepository) DeleteUnusedByUserID(userID uint) error {
+	return r.db.Where("user_id = ? AND used_at IS NULL", userID).Delete(&models.PasswordReset{}).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+// Create creates a new personal access token
+func (r *PersonalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash finds a token by its hash, with its owner
+func (r *PersonalAccessTokenRepository) FindByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByUserID finds all tokens of a user
+func (r *PersonalAccessTokenRepository) FindByUserID(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// HashExists checks if a token hash already exists
+func (r *PersonalAccessTokenRepository) HashExists(tokenHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PersonalAccessToken{}).Where("token_hash = ?", tokenHash).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Touch records when and from where a token was last used
+func (r *PersonalAccessTokenRepository) Touch(id uint, ip string) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": time.Now(), "last_used_ip": ip}).Error
+}
+
+// Delete deletes a token of a user
+func (r *PersonalAccessTokenRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.PersonalAccessToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID deletes all tokens of a user
+func (r *PersonalAccessTokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PersonalAccessToken{}).Error
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// Create creates a new custom role
+func (r *RoleRepository) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// FindByName finds a custom role by name
+func (r *RoleRepository) FindByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindAll retrieves all custom roles
+func (r *RoleRepository) FindAll() ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Update updates a custom role
+func (r *RoleRepository) Update(role *models.Role) error {
+	return r.db.Save(role).Error
+}
+
+// Delete deletes a custom role
+func (r *RoleRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Role{}, id).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID finds a session by ID
+func (r *SessionRepository) FindByID(id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch records session activity
+func (r *SessionRepository) Touch(id, ip, userAgent string) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"ip":           ip,
+		"user_agent":   userAgent,
+	}).Error
+}
+
+// Revoke revokes a session
+func (r *SessionRepository) Revoke(id string) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeByUserID revokes all active sessions of a user except exceptID and returns their IDs
+func (r *SessionRepository) RevokeByUserID(userID uint, exceptID string) ([]string, error) {
+	var ids []string
+	if err := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND id <> ?", userID, exceptID).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if err := r.db.Model(&models.Session{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CreateRefreshToken creates a new refresh token
+func (r *SessionRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindRefreshTokenByHash finds a refresh token by hash, including used ones
+func (r *SessionRepository) FindRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Preload("Session").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marks a refresh token as used, returning false if it was already used
+func (r *SessionRepository) MarkRefreshTokenUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteUnusedRefreshTokens deletes the outstanding refresh tokens of a session
+func (r *SessionRepository) DeleteUnusedRefreshTokens(sessionID string) error {
+	return r.db.Where("session_id = ? AND used_at IS NULL", sessionID).Delete(&models.RefreshToken{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+	"x-track/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{db: db}
+}
+
+// Get returns a setting value, or defaultValue if it has not been set
+func (r *SettingRepository) Get(key, defaultValue string) (string, error) {
+	var setting models.Setting
+	if err := r.db.Where("key = ?", key).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultValue, nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// Set creates or updates a setting
+func (r *SettingRepository) Set(key, value string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
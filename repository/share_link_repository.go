@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type ShareLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewShareLinkRepository(db *gorm.DB) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
+
+// Create creates a new share link
+func (r *ShareLinkRepository) Create(link *models.ShareLink) error {
+	return r.db.Create(link).Error
+}
+
+// FindByHash finds a share link by its token hash, with its account
+func (r *ShareLinkRepository) FindByHash(tokenHash string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.db.Preload("Account").Where("token_hash = ?", tokenHash).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// FindByAccountID finds all share links of an account
+func (r *ShareLinkRepository) FindByAccountID(accountID uint) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	if err := r.db.Where("account_id = ?", accountID).Order("created_at").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Find finds a share link of an account
+func (r *ShareLinkRepository) Find(accountID, id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.db.Where("account_id = ?", accountID).First(&link, id).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Touch records when a share link was last viewed
+func (r *ShareLinkRepository) Touch(id uint) error {
+	return r.db.Model(&models.ShareLink{}).Where("id = ?", id).UpdateColumn("last_viewed_at", time.Now()).Error
+}
+
+// Delete deletes a share link
+func (r *ShareLinkRepository) Delete(accountID, id uint) error {
+	result := r.db.Where("account_id = ?", accountID).Delete(&models.ShareLink{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByAccountID deletes all share links of an account
+func (r *ShareLinkRepository) DeleteByAccountID(accountID uint) error {
+	return r.db.Where("account_id = ?", accountID).Delete(&models.ShareLink{}).Error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+// signingKeyLockID is the Postgres advisory lock held while keys are rotated,
+// so concurrent instances don't each create a successor
+const signingKeyLockID = 0x78747261636b01
+
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// FindUnexpired finds all keys that can still verify tokens, oldest first
+func (r *SigningKeyRepository) FindUnexpired(at time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	if err := r.db.Where("expires_at > ?", at).Order("not_before, id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Create creates a new signing key
+func (r *SigningKeyRepository) Create(key *models.SigningKey) error {
+	return r.db.Create(key).Error
+}
+
+// Retire stops a key from signing from the given time on
+func (r *SigningKeyRepository) Retire(id uint, at time.Time) error {
+	return r.db.Model(&models.SigningKey{}).Where("id = ? AND retires_at > ?", id, at).Update("retires_at", at).Error
+}
+
+// DeleteExpired deletes keys that can no longer verify any token
+func (r *SigningKeyRepository) DeleteExpired(at time.Time) error {
+	return r.db.Where("expires_at <= ?", at).Delete(&models.SigningKey{}).Error
+}
+
+// WithRotationLock runs fn in a transaction holding the rotation lock
+func (r *SigningKeyRepository) WithRotationLock(fn func(repo *SigningKeyRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLockID).Error; err != nil {
+			return err
+		}
+		return fn(&SigningKeyRepository{db: tx})
+	})
+}
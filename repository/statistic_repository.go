@@ -103,7 +103,125 @@ func (r *StatisticRepository) GetLatestStatistic(accountID uint) (*models.Statis
 	return &statistic, nil
 }
 
+// FindLatestBefore gets the most recent statistic for an account before the given time
+func (r *StatisticRepository) FindLatestBefore(accountID uint, before time.Time) (*models.Statistic, error) {
+	var statistic models.Statistic
+	if err := r.db.Where("account_id = ? AND timestamp < ?", accountID, before).
+		Order("timestamp DESC").
+		First(&statistic).Error; err != nil {
+		return nil, err
+	}
+	return &statistic, nil
+}
+
+// FindLatestByAccountIDs finds the latest statistic of each of the given accounts
+func (r *StatisticRepository) FindLatestByAccountIDs(accountIDs []uint) ([]models.Statistic, error) {
+	var statistics []models.Statistic
+	if len(accountIDs) == 0 {
+		return statistics, nil
+	}
+	if err := r.db.Select("DISTINCT ON (account_id) *").
+		Where("account_id IN ?", accountIDs).
+		Order("account_id, timestamp DESC").
+		Find(&statistics).Error; err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+// FindBetween finds all statistics for an account within [start, end) in chronological order
+func (r *StatisticRepository) FindBetween(accountID uint, start, end time.Time) ([]models.Statistic, error) {
+	var statistics []models.Statistic
+	if err := r.db.Where("account_id = ? AND timestamp >= ? AND timestamp < ?", accountID, start, end).
+		Order("timestamp ASC").
+		Find(&statistics).Error; err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+// GetPeakBalance gets the highest recorded balance for an account up to the given time
+func (r *StatisticRepository) GetPeakBalance(accountID uint, until time.Time) (float64, error) {
+	var peak float64
+	err := r.db.Model(&models.Statistic{}).
+		Where("account_id = ? AND timestamp < ?", accountID, until).
+		Select("COALESCE(MAX(total_balance), 0)").
+		Scan(&peak).Error
+	if err != nil {
+		return 0, err
+	}
+	return peak, nil
+}
+
+// FindDailyCloses finds the last statistic of each day for an account in chronological order
+func (r *StatisticRepository) FindDailyCloses(accountID uint) ([]models.Statistic, error) {
+	var statistics []models.Statistic
+	if err := r.db.Select("DISTINCT ON (DATE(timestamp)) *").
+		Where("account_id = ?", accountID).
+		Order("DATE(timestamp) ASC, timestamp DESC").
+		Find(&statistics).Error; err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+// UpdateTradesToday sets the trade count of a statistic
+func (r *StatisticRepository) UpdateTradesToday(id uint, tradesToday int) error {
+	return r.db.Model(&models.Statistic{}).Where("id = ?", id).Update("trades_today", tradesToday).Error
+}
+
 // Delete deletes a statistic
 func (r *StatisticRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Statistic{}, id).Error
 }
+
+// CreateMissing inserts the statistics of an account whose timestamps aren't recorded yet,
+// in one transaction, and returns how many were inserted
+func (r *StatisticRepository) CreateMissing(accountID uint, statistics []models.Statistic) (int, error) {
+	if len(statistics) == 0 {
+		return 0, nil
+	}
+
+	start, end := statistics[0].Timestamp, statistics[0].Timestamp
+	for _, statistic := range statistics {
+		if statistic.Timestamp.Before(start) {
+			start = statistic.Timestamp
+		}
+		if statistic.Timestamp.After(end) {
+			end = statistic.Timestamp
+		}
+	}
+
+	inserted := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []time.Time
+		if err := tx.Model(&models.Statistic{}).
+			Where("account_id = ? AND timestamp BETWEEN ? AND ?", accountID, start, end).
+			Pluck("timestamp", &existing).Error; err != nil {
+			return err
+		}
+		seen := make(map[int64]bool, len(existing))
+		for _, timestamp := range existing {
+			seen[timestamp.UnixNano()] = true
+		}
+
+		var missing []models.Statistic
+		for _, statistic := range statistics {
+			if seen[statistic.Timestamp.UnixNano()] {
+				continue
+			}
+			seen[statistic.Timestamp.UnixNano()] = true
+			statistic.AccountID = accountID
+			missing = append(missing, statistic)
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(missing, 500).Error; err != nil {
+			return err
+		}
+		inserted = len(missing)
+		return nil
+	})
+	return inserted, err
+}
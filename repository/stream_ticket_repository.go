@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StreamTicketRepository struct {
+	db *gorm.DB
+}
+
+func NewStreamTicketRepository(db *gorm.DB) *StreamTicketRepository {
+	return &StreamTicketRepository{db: db}
+}
+
+// Create stores a ticket
+func (r *StreamTicketRepository) Create(ticket *models.StreamTicket) error {
+	return r.db.Create(ticket).Error
+}
+
+// Consume deletes and returns an unexpired ticket, so each ticket is used at most once
+func (r *StreamTicketRepository) Consume(ticketHash string) (*models.StreamTicket, error) {
+	var tickets []models.StreamTicket
+	err := r.db.Clauses(clause.Returning{}).
+		Where("ticket_hash = ? AND expires_at > ?", ticketHash, time.Now()).
+		Delete(&tickets).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tickets[0], nil
+}
+
+// DeleteExpired removes tickets that were never used
+func (r *StreamTicketRepository) DeleteExpired() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&models.StreamTicket{}).Error
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+	"x-track/models"
+
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+// Create links a user to an external identity
+func (r *UserIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// FindBySubject finds the identity of a provider subject with its user
+func (r *UserIdentityRepository) FindBySubject(issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Preload("User").Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Touch records a login through an identity
+func (r *UserIdentityRepository) Touch(id uint, email string) error {
+	return r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"last_login_at": time.Now(),
+	}).Error
+}
+
+// DeleteByUserID unlinks all external identities of a user
+func (r *UserIdentityRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserIdentity{}).Error
+}
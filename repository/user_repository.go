@@ -1,10 +1,11 @@
 package repository
 
 import (
-	"errors"
+	"time"
 	"x-track/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -38,6 +39,15 @@ func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByEmail finds a user by email address, ignoring case
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // FindAll retrieves all users
 func (r *UserRepository) FindAll() ([]models.User, error) {
 	var users []models.User
@@ -52,11 +62,96 @@ func (r *UserRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+// CountActiveByRoles counts the active users with one of the given roles
+func (r *UserRepository) CountActiveByRoles(roles []string) (int64, error) {
+	var count int64
+	if len(roles) == 0 {
+		return 0, nil
+	}
+	err := r.db.Model(&models.User{}).Where("role IN ? AND status = ?", roles, models.UserStatusActive).Count(&count).Error
+	return count, err
+}
+
+// UpgradePasswordHash replaces a user's password hash with a stronger hash of the same
+// password, unless the password was changed in the meantime
+func (r *UserRepository) UpgradePasswordHash(userID uint, oldHash, newHash string) error {
+	return r.db.Model(&models.User{}).Where("id = ? AND password_hash = ?", userID, oldHash).
+		Update("password_hash", newHash).Error
+}
+
 // Delete deletes a user
 func (r *UserRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
+// FindDigestRecipients retrieves users who opted in to the daily digest and have a verified email
+func (r *UserRepository) FindDigestRecipients() ([]models.User, error) {
+	var users []models.User
+	if err := r.db.Where("digest_enabled = ? AND status = ? AND email <> '' AND email_verified_at IS NOT NULL", true, models.UserStatusActive).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ClaimDigest marks the digest for the trading day ending at dayEnd as sent,
+// returning false if it was already claimed (e.g. by another instance)
+func (r *UserRepository) ClaimDigest(userID uint, dayEnd time.Time) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", userID, dayEnd).
+		Update("last_digest_at", dayEnd)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// AdvanceTOTPStep records the last accepted TOTP time step, returning false if
+// the step was already used (e.g. by a concurrent request)
+func (r *UserRepository) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordFailedLogin increments a user's failed login counter and locks the user
+// until lockUntil once the counter reaches maxFailures. The counter starts over when
+// the user's lock ran out or their last failure was before staleBefore.
+func (r *UserRepository) RecordFailedLogin(userID uint, maxFailures int, staleBefore, lockUntil time.Time) error {
+	now := time.Now()
+	count := "CASE WHEN (locked_until IS NOT NULL AND locked_until <= ?::timestamptz) OR last_failed_login_at IS NULL OR last_failed_login_at < ?::timestamptz " +
+		"THEN 1 ELSE failed_login_count + 1 END"
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_count":   gorm.Expr(count, now, staleBefore),
+		"last_failed_login_at": now,
+		"locked_until": gorm.Expr("CASE WHEN "+count+" >= ? THEN ?::timestamptz WHEN locked_until <= ?::timestamptz THEN NULL ELSE locked_until END",
+			now, staleBefore, maxFailures, lockUntil, now),
+	}).Error
+}
+
+// ResetFailedLogins clears a user's failed login counter and lockout
+func (r *UserRepository) ResetFailedLogins(userID uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_count":   0,
+		"last_failed_login_at": nil,
+		"locked_until":         nil,
+	}).Error
+}
+
+// EmailExists checks if an email address is already used by another user
+func (r *UserRepository) EmailExists(email string, excludeUserID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", email, excludeUserID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // UsernameExists checks if a username already exists
 func (r *UserRepository) UsernameExists(username string) (bool, error) {
 	var count int64
@@ -67,43 +162,27 @@ func (r *UserRepository) UsernameExists(username string) (bool, error) {
 	return count > 0, nil
 }
 
-// EnsureAdminExists creates an admin user if no admin exists
-func (r *UserRepository) EnsureAdminExists(username, password string) error {
-	var count int64
-	if err := r.db.Model(&models.User{}).Where("role = ?", "admin").Count(&count).Error; err != nil {
-		return err
+// BumpTokenVersionsByRole increments the token version of every user holding a role and
+// returns their IDs
+func (r *UserRepository) BumpTokenVersionsByRole(role string) ([]uint, error) {
+	var users []models.User
+	err := r.db.Model(&users).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("role = ?", role).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+	if err != nil {
+		return nil, err
 	}
 
-	if count == 0 {
-		// Check if username already exists
-		exists, err := r.UsernameExists(username)
-		if err != nil {
-			return err
-		}
-		if exists {
-			return errors.New("admin username already taken")
-		}
-
-		// Create admin user
-		admin := &models.User{
-			Username: username,
-			Role:     "admin",
-		}
-		
-		// Hash password
-		hashedPassword, err := hashPassword(password)
-		if err != nil {
-			return err
-		}
-		admin.PasswordHash = hashedPassword
-
-		return r.Create(admin)
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
 	}
-
-	return nil
+	return ids, nil
 }
 
-func hashPassword(password string) (string, error) {
-	// Import from utils to avoid circular dependency
-	return password, nil // Will be hashed in service layer
+// CountByRole counts the users holding a role
+func (r *UserRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
 }
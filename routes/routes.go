@@ -3,6 +3,7 @@ package routes
 import (
 	"x-track/handler"
 	"x-track/middleware"
+	"x-track/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,6 +16,16 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	userHandler := handler.NewUserHandler(db)
 	accountHandler := handler.NewAccountHandler(db)
 	statisticHandler := handler.NewStatisticHandler(db)
+	settingHandler := handler.NewSettingHandler(db)
+	accountTokenHandler := handler.NewAccountTokenHandler(db)
+	shareLinkHandler := handler.NewShareLinkHandler(db)
+	accountMemberHandler := handler.NewAccountMemberHandler(db)
+	organizationHandler := handler.NewOrganizationHandler(db)
+	roleHandler := handler.NewRoleHandler(db)
+	personalAccessTokenHandler := handler.NewPersonalAccessTokenHandler(db)
+	auditHandler := handler.NewAuditHandler(db)
+	signingKeyHandler := handler.NewSigningKeyHandler(db)
+	inviteHandler := handler.NewInviteHandler(db)
 
 	// API group
 	api := r.Group("/api")
@@ -23,56 +34,184 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/2fa", authHandler.LoginTwoFactor)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.GET("/password-policy", authHandler.GetPasswordPolicy)
+			auth.GET("/oidc", authHandler.GetOIDCConfig)
+			auth.GET("/oidc/authorize", authHandler.AuthorizeOIDC)
+			auth.POST("/oidc/callback", authHandler.OIDCCallback)
 		}
 
-		// Statistics ingestion endpoint (protected by API token)
+		// Automated client endpoints (protected by account API token, each needs a scope;
+		// browsers are refused unless CORS_INGEST_ALLOW_BROWSERS is set)
 		ingest := api.Group("/ingest")
-		ingest.Use(middleware.APITokenMiddleware())
+		ingest.Use(middleware.RejectBrowserOrigins())
 		{
-			ingest.POST("/statistics", statisticHandler.IngestStatistic)
+			ingest.POST("/statistics", middleware.APITokenMiddleware(models.ScopeIngestStatistics), statisticHandler.IngestStatistic)
+			ingest.POST("/trades", middleware.APITokenMiddleware(models.ScopeIngestTrades), statisticHandler.IngestTrades)
+			ingest.POST("/heartbeat", middleware.APITokenMiddleware(models.ScopeIngestHeartbeat), statisticHandler.IngestHeartbeat)
+			ingest.GET("/today", middleware.APITokenMiddleware(models.ScopeRead), statisticHandler.GetTokenTodaySummary)
+		}
+
+		// Public read-only share links (no user, GET only, resolved by the link token)
+		share := api.Group("/public/share/:token")
+		share.Use(middleware.ShareLinkMiddleware())
+		{
+			share.GET("", shareLinkHandler.GetSharedView)
+		}
+
+		// Live stream routes (JWT or personal access token required; EventSource passes a
+		// single-use stream ticket as a query parameter instead)
+		streams := api.Group("/statistics")
+		streams.Use(middleware.StreamTicketMiddleware(), middleware.AuthMiddleware(), middleware.RequireAccountSetup(), middleware.RequirePermission(models.PermAccountsRead))
+		{
+			streams.GET("/stream", statisticHandler.StreamPortfolio)
+			streams.GET("/:account_id/stream", statisticHandler.StreamAccount)
 		}
 
 		// Protected routes (JWT required)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
-			// User routes (admin only for create, update, delete)
-			users := protected.Group("/users")
+			// Session routes (available while a password change or 2FA enrolment is pending,
+			// not to personal access tokens or impersonation)
+			session := protected.Group("/auth")
+			session.Use(middleware.RequireSession())
+			{
+				session.POST("/logout", authHandler.Logout)
+				session.POST("/logout-all", authHandler.LogoutAll)
+				session.POST("/password", authHandler.ChangePassword)
+				session.POST("/2fa/setup", authHandler.SetupTwoFactor)
+				session.POST("/2fa/enable", authHandler.EnableTwoFactor)
+				session.POST("/2fa/disable", authHandler.DisableTwoFactor)
+				session.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			}
+
+			// Ends the impersonation the token belongs to, even a read-only one
+			protected.POST("/auth/impersonation/end", authHandler.EndImpersonation)
+		}
+
+		// Application routes (JWT or personal access token required, password change must
+		// not be pending, read-only impersonation can only read). Every route is guarded by a permission of the caller's role, or
+		// of the token's scopes; /me routes only touch the caller's own profile and need none.
+		app := api.Group("")
+		app.Use(middleware.AuthMiddleware(), middleware.RequireAccountSetup(), middleware.RequireWriteAccess())
+		{
+			// User routes
+			users := app.Group("/users")
+			{
+				users.GET("/:id", userHandler.GetUser) // self, or users:read for others
+				users.GET("/me/permissions", userHandler.GetMyPermissions)
+				users.GET("/me/logins", userHandler.GetMyLogins)
+				users.PUT("/me/email", middleware.RequireSession(), userHandler.UpdateMyEmail)
+				users.GET("/me/tokens", middleware.RequireSession(), personalAccessTokenHandler.ListTokens)
+				users.POST("/me/tokens", middleware.RequireSession(), personalAccessTokenHandler.CreateToken)
+				users.DELETE("/me/tokens/:token_id", middleware.RequireSession(), personalAccessTokenHandler.RevokeToken)
+				users.PUT("/me/digest", userHandler.UpdateMyDigest)
+				users.POST("/me/digest/send", userHandler.SendMyDigest)
+
+				users.GET("", middleware.RequirePermission(models.PermUsersRead), userHandler.GetAllUsers)
+				users.GET("/:id/logins", middleware.RequirePermission(models.PermUsersRead), userHandler.GetUserLogins)
+				users.POST("", middleware.RequirePermission(models.PermUsersManage), userHandler.CreateUser)
+				users.PUT("/:id", middleware.RequirePermission(models.PermUsersManage), userHandler.UpdateUser)
+				users.DELETE("/:id", middleware.RequirePermission(models.PermUsersManage), userHandler.DeleteUser)
+				users.POST("/:id/password-reset", middleware.RequirePermission(models.PermUsersManage), userHandler.CreatePasswordReset)
+				users.POST("/:id/unlock", middleware.RequirePermission(models.PermUsersManage), userHandler.UnlockUser)
+				users.PUT("/:id/status", middleware.RequirePermission(models.PermUsersManage), userHandler.SetUserStatus)
+				users.POST("/:id/impersonate", middleware.RequireSession(), middleware.RequirePermission(models.PermUsersImpersonate), authHandler.Impersonate)
+			}
+
+			// Invite routes
+			invites := app.Group("/invites")
+			invites.Use(middleware.RequirePermission(models.PermUsersManage))
 			{
-				users.GET("/:id", userHandler.GetUser)
-				
-				// Admin only routes
-				adminUsers := users.Group("")
-				adminUsers.Use(middleware.RequireAdmin())
-				{
-					adminUsers.POST("", userHandler.CreateUser)
-					adminUsers.GET("", userHandler.GetAllUsers)
-					adminUsers.PUT("/:id", userHandler.UpdateUser)
-					adminUsers.DELETE("/:id", userHandler.DeleteUser)
-				}
+				invites.GET("", inviteHandler.GetInvites)
+				invites.POST("", inviteHandler.CreateInvite)
+				invites.DELETE("/:id", inviteHandler.RevokeInvite)
 			}
 
-			// Account routes
-			accounts := protected.Group("/accounts")
+			// Role routes
+			roles := app.Group("/roles")
+			roles.Use(middleware.RequirePermission(models.PermRolesManage))
 			{
-				accounts.POST("", accountHandler.CreateAccount)
-				accounts.GET("/me", accountHandler.GetMyAccounts)
-				accounts.GET("/:id", accountHandler.GetAccount)
-				accounts.PUT("/:id", accountHandler.UpdateAccount)
-				accounts.DELETE("/:id", accountHandler.DeleteAccount)
-				accounts.POST("/:id/regenerate-token", accountHandler.RegenerateToken)
-				
-				// Admin only - get all accounts
-				adminAccounts := accounts.Group("")
-				adminAccounts.Use(middleware.RequireAdmin())
-				{
-					adminAccounts.GET("", accountHandler.GetAllAccounts)
-				}
+				roles.GET("", roleHandler.GetRoles)
+				roles.GET("/permissions", roleHandler.GetPermissions)
+				roles.POST("", roleHandler.CreateRole)
+				roles.PUT("/:name", roleHandler.UpdateRole)
+				roles.DELETE("/:name", roleHandler.DeleteRole)
+			}
+
+			// Account routes (the caller also needs access to the account itself)
+			accounts := app.Group("/accounts")
+			{
+				read := middleware.RequirePermission(models.PermAccountsRead)
+				write := middleware.RequirePermission(models.PermAccountsWrite)
+
+				accounts.GET("", middleware.RequirePermission(models.PermAccountsManageAll), accountHandler.GetAllAccounts)
+				accounts.POST("", write, accountHandler.CreateAccount)
+				accounts.GET("/me", read, accountHandler.GetMyAccounts)
+				accounts.GET("/shared", read, accountHandler.GetSharedAccounts)
+				accounts.GET("/:id", read, accountHandler.GetAccount)
+				accounts.PUT("/:id", write, accountHandler.UpdateAccount)
+				accounts.DELETE("/:id", middleware.RequirePermission(models.PermAccountsDelete), accountHandler.DeleteAccount)
+				accounts.GET("/:id/tokens", write, accountTokenHandler.ListTokens)
+				accounts.POST("/:id/tokens", write, accountTokenHandler.CreateToken)
+				accounts.POST("/:id/tokens/:token_id/rotate", write, accountTokenHandler.RotateToken)
+				accounts.DELETE("/:id/tokens/:token_id", write, accountTokenHandler.RevokeToken)
+				accounts.GET("/:id/share-links", write, shareLinkHandler.ListShareLinks)
+				accounts.POST("/:id/share-links", write, shareLinkHandler.CreateShareLink)
+				accounts.DELETE("/:id/share-links/:link_id", write, shareLinkHandler.RevokeShareLink)
+				accounts.GET("/:id/members", write, accountMemberHandler.ListMembers)
+				accounts.POST("/:id/members", write, accountMemberHandler.GrantAccess)
+				accounts.DELETE("/:id/members/:user_id", write, accountMemberHandler.RevokeAccess)
+			}
+
+			// Organization routes (the caller also needs a role within the organization)
+			organizations := app.Group("/organizations")
+			{
+				read := middleware.RequirePermission(models.PermAccountsRead)
+				write := middleware.RequirePermission(models.PermOrganizationsWrite)
+				manageAll := middleware.RequirePermission(models.PermOrganizationsManageAll)
+
+				organizations.GET("", manageAll, organizationHandler.GetAllOrganizations)
+				organizations.POST("", write, organizationHandler.CreateOrganization)
+				organizations.GET("/me", read, organizationHandler.GetMyOrganizations)
+				organizations.GET("/:id", read, organizationHandler.GetOrganization)
+				organizations.PUT("/:id", write, organizationHandler.UpdateOrganization)
+				organizations.DELETE("/:id", write, organizationHandler.DeleteOrganization)
+				organizations.PUT("/:id/quotas", manageAll, organizationHandler.UpdateQuotas)
+				organizations.GET("/:id/dashboard", read, organizationHandler.GetDashboard)
+				organizations.GET("/:id/members", read, organizationHandler.ListMembers)
+				organizations.POST("/:id/members", write, organizationHandler.SetMember)
+				organizations.DELETE("/:id/members/:user_id", write, organizationHandler.RemoveMember)
+				organizations.GET("/:id/accounts", read, organizationHandler.ListAccounts)
+				organizations.POST("/:id/accounts", write, organizationHandler.CreateAccount)
+			}
+
+			// Settings routes
+			settings := app.Group("/settings")
+			settings.Use(middleware.RequirePermission(models.PermSettingsManage))
+			{
+				settings.GET("/security", settingHandler.GetSecuritySettings)
+				settings.PUT("/security", settingHandler.UpdateSecuritySettings)
+			}
+
+			// Audit log routes
+			audit := app.Group("/audit-events")
+			audit.Use(middleware.RequirePermission(models.PermAuditRead))
+			{
+				audit.GET("", auditHandler.GetEvents)
+				audit.GET("/export", auditHandler.ExportEvents)
 			}
 
 			// Statistics routes (query endpoints)
-			statistics := protected.Group("/statistics")
+			statistics := app.Group("/statistics")
+			statistics.Use(middleware.RequirePermission(models.PermAccountsRead))
 			{
+				statistics.POST("/stream-ticket", statisticHandler.CreateStreamTicket)
 				statistics.GET("/:account_id", statisticHandler.GetStatistics)
 				statistics.GET("/:account_id/range", statisticHandler.GetStatisticsByDateRange)
 				statistics.GET("/:account_id/today", statisticHandler.GetTodaySummary)
@@ -81,6 +220,9 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		}
 	}
 
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", signingKeyHandler.GetJWKS)
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
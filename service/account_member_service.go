@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"x-track/models"
+	"x-track/repository"
+
+	"gorm.io/gorm"
+)
+
+type AccountMemberService struct {
+	memberRepo   *repository.AccountMemberRepository
+	accountRepo  *repository.AccountRepository
+	userRepo     *repository.UserRepository
+	auditService *AuditService
+}
+
+func NewAccountMemberService(db *gorm.DB) *AccountMemberService {
+	return &AccountMemberService{
+		memberRepo:   repository.NewAccountMemberRepository(db),
+		accountRepo:  repository.NewAccountRepository(db),
+		userRepo:     repository.NewUserRepository(db),
+		auditService: NewAuditService(db),
+	}
+}
+
+// ListMembers retrieves the users an account is shared with
+func (s *AccountMemberService) ListMembers(accountID uint) ([]models.AccountMember, error) {
+	return s.memberRepo.FindByAccountID(accountID)
+}
+
+// GrantAccess shares an account with a user as viewer or manager, or changes their role
+func (s *AccountMemberService) GrantAccess(actor Actor, accountID uint, username, role string) (*models.AccountMember, error) {
+	if role != models.AccountRoleViewer && role != models.AccountRoleManager {
+		return nil, errors.New("role must be viewer or manager")
+	}
+
+	account, err := s.accountRepo.FindByID(accountID)
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if account.OrganizationID == nil && user.ID == account.UserID {
+		return nil, errors.New("the owner already has full access")
+	}
+
+	before, err := s.memberRepo.Find(accountID, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	member := &models.AccountMember{
+		AccountID:   accountID,
+		UserID:      user.ID,
+		Role:        role,
+		GrantedByID: actor.UserID,
+	}
+	if err := s.memberRepo.Upsert(member); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditAccountMemberGrant, models.AuditTargetAccountMember, member.ID, before, member)
+
+	member.User = *user
+	return member, nil
+}
+
+// RevokeAccess removes a user's access to an account
+func (s *AccountMemberService) RevokeAccess(actor Actor, accountID, userID uint) error {
+	member, err := s.memberRepo.Find(accountID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.memberRepo.Delete(accountID, userID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditAccountMemberRevoke, models.AuditTargetAccountMember, member.ID, member, nil)
+	return nil
+}
@@ -2,48 +2,82 @@ package service
 
 import (
 	"errors"
+	"x-track/events"
 	"x-track/models"
 	"x-track/repository"
-	"x-track/utils"
 
 	"gorm.io/gorm"
 )
 
+// AccountAccess is what a user may do with an account; each level includes the ones below it
+type AccountAccess int
+
+const (
+	AccountAccessNone AccountAccess = iota
+	AccountAccessViewer
+	AccountAccessManager
+	AccountAccessOwner
+)
+
+var (
+	// ErrAccountNotFound is returned when an account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountAccessDenied is returned when a user lacks the access an action needs
+	ErrAccountAccessDenied = errors.New("access denied")
+)
+
 type AccountService struct {
-	accountRepo *repository.AccountRepository
-	userRepo    *repository.UserRepository
+	db           *gorm.DB
+	accountRepo  *repository.AccountRepository
+	userRepo     *repository.UserRepository
+	tokenRepo    *repository.AccountTokenRepository
+	linkRepo     *repository.ShareLinkRepository
+	memberRepo   *repository.AccountMemberRepository
+	orgRepo      *repository.OrganizationRepository
+	tokenService *AccountTokenService
+	auditService *AuditService
 }
 
 func NewAccountService(db *gorm.DB) *AccountService {
 	return &AccountService{
-		accountRepo: repository.NewAccountRepository(db),
-		userRepo:    repository.NewUserRepository(db),
+		db:           db,
+		accountRepo:  repository.NewAccountRepository(db),
+		userRepo:     repository.NewUserRepository(db),
+		tokenRepo:    repository.NewAccountTokenRepository(db),
+		linkRepo:     repository.NewShareLinkRepository(db),
+		memberRepo:   repository.NewAccountMemberRepository(db),
+		orgRepo:      repository.NewOrganizationRepository(db),
+		tokenService: NewAccountTokenService(db),
+		auditService: NewAuditService(db),
 	}
 }
 
-// CreateAccount creates a new account for a user
-func (s *AccountService) CreateAccount(userID uint, name string) (*models.Account, error) {
+// CreateAccount creates a new account for a user, optionally in an organization, with
+// an initial API token holding every scope. The plaintext token is only returned here.
+func (s *AccountService) CreateAccount(actor Actor, userID uint, name string, organizationID *uint) (*models.Account, error) {
 	// Verify user exists
 	if _, err := s.userRepo.FindByID(userID); err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	// Generate unique API token
-	token, err := s.generateUniqueToken()
-	if err != nil {
-		return nil, err
-	}
-
 	account := &models.Account{
-		UserID:   userID,
-		Name:     name,
-		APIToken: token,
+		UserID:         userID,
+		Name:           name,
+		OrganizationID: organizationID,
 	}
 
 	if err := s.accountRepo.Create(account); err != nil {
 		return nil, err
 	}
 
+	token, err := s.tokenService.CreateToken(actor, account.ID, "Default", models.AccountTokenScopes, nil, nil)
+	if err != nil {
+		s.accountRepo.Delete(account.ID)
+		return nil, err
+	}
+	account.APIToken = token.Token
+	s.auditService.Record(actor, models.AuditAccountCreate, models.AuditTargetAccount, account.ID, nil, account)
+
 	return account, nil
 }
 
@@ -52,79 +86,197 @@ func (s *AccountService) GetAccountByID(id uint) (*models.Account, error) {
 	return s.accountRepo.FindByID(id)
 }
 
-// GetAccountsByUserID retrieves all accounts for a user
+// GetAccountsByUserID retrieves the personal accounts of a user. Accounts the user created
+// in an organization belong to the organization and are reached through it.
 func (s *AccountService) GetAccountsByUserID(userID uint) ([]models.Account, error) {
-	return s.accountRepo.FindByUserID(userID)
+	return s.accountRepo.FindPersonalByUserID(userID)
 }
 
-// GetAllAccounts retrieves all accounts
-func (s *AccountService) GetAllAccounts() ([]models.Account, error) {
-	return s.accountRepo.FindAll()
+// GetSharedAccounts retrieves accounts other users have shared with a user, with the user's role on each
+func (s *AccountService) GetSharedAccounts(userID uint) ([]models.Account, error) {
+	members, err := s.memberRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]models.Account, 0, len(members))
+	for _, member := range members {
+		account := member.Account
+		account.AccessRole = member.Role
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
 }
 
-// UpdateAccount updates an account
-func (s *AccountService) UpdateAccount(id uint, name string) (*models.Account, error) {
-	account, err := s.accountRepo.FindByID(id)
+// GetAccessibleAccountIDs retrieves the IDs of personal accounts a user owns, was granted
+// access to or can see through an organization
+func (s *AccountService) GetAccessibleAccountIDs(userID uint) ([]uint, error) {
+	owned, err := s.accountRepo.FindPersonalByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
-
-	if name != "" {
-		account.Name = name
+	shared, err := s.memberRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.accountRepo.Update(account); err != nil {
+	memberships, err := s.orgRepo.FindMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var orgIDs []uint
+	for _, membership := range memberships {
+		if orgRoleAccountAccess(membership.Role) >= AccountAccessViewer {
+			orgIDs = append(orgIDs, membership.OrganizationID)
+		}
+	}
+	orgAccounts, err := s.accountRepo.FindByOrganizationIDs(orgIDs)
+	if err != nil {
 		return nil, err
 	}
 
-	return account, nil
+	seen := make(map[uint]struct{})
+	ids := make([]uint, 0, len(owned)+len(shared)+len(orgAccounts))
+	add := func(id uint) {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	for _, account := range owned {
+		add(account.ID)
+	}
+	for _, member := range shared {
+		add(member.AccountID)
+	}
+	for _, account := range orgAccounts {
+		add(account.ID)
+	}
+
+	return ids, nil
 }
 
-// RegenerateToken generates a new API token for an account
-func (s *AccountService) RegenerateToken(id uint) (*models.Account, error) {
-	account, err := s.accountRepo.FindByID(id)
+// GetAccess determines what a user may do with an account, from ownership, a direct
+// grant or their role in the account's organization. Users allowed to manage all accounts
+// act as owners of every account. Organization accounts have no owner: whoever created one
+// keeps access only while the organization or a grant gives it.
+func (s *AccountService) GetAccess(account *models.Account, userID uint, manageAll bool) (AccountAccess, error) {
+	if manageAll || (account.OrganizationID == nil && account.UserID == userID) {
+		return AccountAccessOwner, nil
+	}
+
+	access := AccountAccessNone
+
+	member, err := s.memberRepo.Find(account.ID, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return AccountAccessNone, err
+	}
+	if member != nil {
+		switch member.Role {
+		case models.AccountRoleManager:
+			access = AccountAccessManager
+		case models.AccountRoleViewer:
+			access = AccountAccessViewer
+		}
+	}
+
+	if account.OrganizationID != nil {
+		orgMember, err := s.orgRepo.FindMember(*account.OrganizationID, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return AccountAccessNone, err
+		}
+		if orgMember != nil {
+			access = max(access, orgRoleAccountAccess(orgMember.Role))
+		}
+	}
+
+	return access, nil
+}
+
+// orgRoleAccountAccess maps an organization role to the access it gives on the organization's
+// accounts. Organization viewers only see the aggregated dashboard.
+func orgRoleAccountAccess(role string) AccountAccess {
+	switch role {
+	case models.OrgRoleOwner:
+		return AccountAccessOwner
+	case models.OrgRoleManager:
+		return AccountAccessManager
+	case models.OrgRoleAnalyst:
+		return AccountAccessViewer
+	}
+	return AccountAccessNone
+}
+
+// CheckAccess loads an account and verifies the user has at least the required access to it
+func (s *AccountService) CheckAccess(accountID, userID uint, manageAll bool, required AccountAccess) (*models.Account, error) {
+	account, err := s.accountRepo.FindByID(accountID)
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	access, err := s.GetAccess(account, userID, manageAll)
 	if err != nil {
 		return nil, err
 	}
+	if access < required {
+		return nil, ErrAccountAccessDenied
+	}
+
+	return account, nil
+}
+
+// GetAllAccounts retrieves all accounts
+func (s *AccountService) GetAllAccounts() ([]models.Account, error) {
+	return s.accountRepo.FindAll()
+}
 
-	// Generate new unique token
-	token, err := s.generateUniqueToken()
+// UpdateAccount updates an account
+func (s *AccountService) UpdateAccount(actor Actor, id uint, name string) (*models.Account, error) {
+	account, err := s.accountRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := *account
 
-	account.APIToken = token
+	if name != "" {
+		account.Name = name
+	}
 
 	if err := s.accountRepo.Update(account); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, models.AuditAccountUpdate, models.AuditTargetAccount, account.ID, &before, account)
 
 	return account, nil
 }
 
-// DeleteAccount deletes an account
-func (s *AccountService) DeleteAccount(id uint) error {
-	return s.accountRepo.Delete(id)
+// DeleteAccount deletes an account with its API tokens, share links and member grants
+func (s *AccountService) DeleteAccount(actor Actor, id uint) error {
+	// Either the account, everything that belongs to it and the audit event are gone, or nothing is
+	return events.Transaction(s.db, func(tx *gorm.DB) error {
+		return NewAccountService(tx).deleteAccountData(actor, id)
+	})
 }
 
-// generateUniqueToken generates a unique API token
-func (s *AccountService) generateUniqueToken() (string, error) {
-	maxAttempts := 5
-	for i := 0; i < maxAttempts; i++ {
-		token, err := utils.GenerateSecureToken(32)
-		if err != nil {
-			return "", err
-		}
-
-		exists, err := s.accountRepo.TokenExists(token)
-		if err != nil {
-			return "", err
-		}
-
-		if !exists {
-			return token, nil
-		}
+// deleteAccountData deletes an account with its grants, tokens and share links and records it
+func (s *AccountService) deleteAccountData(actor Actor, id uint) error {
+	account, err := s.accountRepo.FindByID(id)
+	if err != nil {
+		return err
 	}
 
-	return "", errors.New("failed to generate unique token")
+	if err := s.memberRepo.DeleteByAccountID(id); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.DeleteByAccountID(id); err != nil {
+		return err
+	}
+	if err := s.linkRepo.DeleteByAccountID(id); err != nil {
+		return err
+	}
+	if err := s.accountRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.auditService.Append(actor, models.AuditAccountDelete, models.AuditTargetAccount, id, account, nil)
 }
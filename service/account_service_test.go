@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"x-track/config"
+	"x-track/models"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	config.AppConfig = &config.Config{}
+
+	tests := []struct {
+		name string
+		// dropTable makes the deletion fail where it writes to that table
+		dropTable   string
+		wantDeleted bool
+	}{
+		{name: "deletes everything", wantDeleted: true},
+		{name: "failed step keeps everything", dropTable: "share_links"},
+		{name: "failed audit event keeps everything", dropTable: "audit_events"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.User{}, &models.Account{}, &models.AccountMember{},
+				&models.AccountToken{}, &models.ShareLink{}, &models.AuditEvent{})
+
+			owner := &models.User{Username: "owner"}
+			viewer := &models.User{Username: "viewer"}
+			if err := db.Create([]*models.User{owner, viewer}).Error; err != nil {
+				t.Fatal(err)
+			}
+			account := &models.Account{UserID: owner.ID, Name: "Main"}
+			if err := db.Create(account).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range []interface{}{
+				&models.AccountMember{AccountID: account.ID, UserID: viewer.ID, Role: "viewer", GrantedByID: owner.ID},
+				&models.AccountToken{AccountID: account.ID, Name: "Default", TokenHash: "token-hash", TokenPrefix: "xt_token", Scopes: models.AccountTokenScopes},
+				&models.ShareLink{AccountID: account.ID, CreatedByID: owner.ID, TokenHash: "link-hash", TokenPrefix: "xt_link"},
+			} {
+				if err := db.Create(row).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.dropTable != "" {
+				if err := db.Migrator().DropTable(tt.dropTable); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := NewAccountService(db).DeleteAccount(Actor{UserID: owner.ID, Username: owner.Username}, account.ID)
+			if tt.wantDeleted && err != nil {
+				t.Fatalf("DeleteAccount() error = %v", err)
+			}
+			if !tt.wantDeleted && err == nil {
+				t.Fatal("DeleteAccount() succeeded, want the failed step's error")
+			}
+
+			remaining := int64(1)
+			if tt.wantDeleted {
+				remaining = 0
+			}
+			for _, rows := range []struct {
+				table string
+				model interface{}
+				where string
+			}{
+				{"accounts", &models.Account{}, "id = ?"},
+				{"account_members", &models.AccountMember{}, "account_id = ?"},
+				{"account_tokens", &models.AccountToken{}, "account_id = ?"},
+				{"share_links", &models.ShareLink{}, "account_id = ?"},
+			} {
+				if rows.table == tt.dropTable {
+					continue
+				}
+				var count int64
+				if err := db.Model(rows.model).Where(rows.where, account.ID).Count(&count).Error; err != nil {
+					t.Fatal(err)
+				}
+				if count != remaining {
+					t.Errorf("%s has %d rows of the account, want %d", rows.table, count, remaining)
+				}
+			}
+
+			if tt.dropTable != "audit_events" {
+				var audited int64
+				db.Model(&models.AuditEvent{}).Where("action = ? AND target_id = ?", models.AuditAccountDelete, account.ID).Count(&audited)
+				if audited != 1-remaining {
+					t.Errorf("%d %s audit events, want %d", audited, models.AuditAccountDelete, 1-remaining)
+				}
+			}
+		})
+	}
+}
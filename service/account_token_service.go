@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// tokenTouchInterval limits how often last-used information is written for a busy token
+	tokenTouchInterval = time.Minute
+	// MaxTokenRotationGrace is the longest a rotated token may keep working
+	MaxTokenRotationGrace = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrAccountTokenInvalid is returned for unknown, expired or orphaned tokens
+	ErrAccountTokenInvalid = errors.New("invalid API token")
+	// ErrAccountTokenForbidden is returned when a valid token lacks a scope or is used from a disallowed IP
+	ErrAccountTokenForbidden = errors.New("API token is not allowed to perform this request")
+)
+
+type AccountTokenService struct {
+	tokenRepo    *repository.AccountTokenRepository
+	auditService *AuditService
+}
+
+func NewAccountTokenService(db *gorm.DB) *AccountTokenService {
+	return &AccountTokenService{
+		tokenRepo:    repository.NewAccountTokenRepository(db),
+		auditService: NewAuditService(db),
+	}
+}
+
+// ListTokens retrieves all tokens of an account
+func (s *AccountTokenService) ListTokens(accountID uint) ([]models.AccountToken, error) {
+	return s.tokenRepo.FindByAccountID(accountID)
+}
+
+// CreateToken issues a new token for an account. The returned token carries the
+// plaintext secret, which cannot be retrieved again afterwards.
+func (s *AccountTokenService) CreateToken(actor Actor, accountID uint, name string, scopes []string, expiresAt *time.Time, allowedIPs []string) (*models.AccountToken, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.AccountTokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q, valid scopes are %s", scope, strings.Join(models.AccountTokenScopes, ", "))
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+	for _, entry := range allowedIPs {
+		if _, err := netip.ParsePrefix(entry); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(entry); err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR range %q", entry)
+		}
+	}
+
+	token := &models.AccountToken{
+		AccountID:  accountID,
+		Name:       name,
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(scopes))),
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  expiresAt,
+	}
+
+	if err := s.issue(token); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditAccountTokenCreate, models.AuditTargetAccountToken, token.ID, nil, token)
+
+	return token, nil
+}
+
+// RotateToken issues a replacement with the same settings and lets the old token
+// keep working for the grace period, so clients can be switched over one by one
+func (s *AccountTokenService) RotateToken(actor Actor, accountID, tokenID uint, grace time.Duration) (*models.AccountToken, error) {
+	if grace < 0 || grace > MaxTokenRotationGrace {
+		return nil, fmt.Errorf("grace period must be between 0 and %d hours", int(MaxTokenRotationGrace.Hours()))
+	}
+
+	old, err := s.tokenRepo.FindByID(accountID, tokenID)
+	if err != nil {
+		return nil, errors.New("token not found")
+	}
+
+	replacement := &models.AccountToken{
+		AccountID:  old.AccountID,
+		Name:       old.Name,
+		Scopes:     old.Scopes,
+		AllowedIPs: old.AllowedIPs,
+		ExpiresAt:  old.ExpiresAt,
+	}
+	if replacement.IsExpired() {
+		replacement.ExpiresAt = nil
+	}
+
+	if err := s.issue(replacement); err != nil {
+		return nil, err
+	}
+
+	if grace == 0 {
+		err = s.tokenRepo.Delete(accountID, old.ID)
+	} else if graceEnd := time.Now().Add(grace); old.ExpiresAt == nil || graceEnd.Before(*old.ExpiresAt) {
+		err = s.tokenRepo.SetExpiry(old.ID, graceEnd)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditAccountTokenRotate, models.AuditTargetAccountToken, old.ID, old, replacement)
+
+	return replacement, nil
+}
+
+// RevokeToken deletes a token immediately
+func (s *AccountTokenService) RevokeToken(actor Actor, accountID, tokenID uint) error {
+	token, err := s.tokenRepo.FindByID(accountID, tokenID)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.Delete(accountID, tokenID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditAccountTokenRevoke, models.AuditTargetAccountToken, token.ID, token, nil)
+	return nil
+}
+
+// Authenticate resolves a plaintext token used from an IP for a scope
+func (s *AccountTokenService) Authenticate(plain, ip, scope string) (*models.AccountToken, error) {
+	token, err := s.tokenRepo.FindByHash(utils.HashToken(plain))
+	if err != nil || token.IsExpired() || token.Account.ID == 0 {
+		return nil, ErrAccountTokenInvalid
+	}
+	// Personal accounts stop ingesting while their owner is suspended or deactivated;
+	// organization accounts belong to the organization
+	if token.Account.OrganizationID == nil {
+		if token.Account.User.ID == 0 {
+			return nil, ErrAccountTokenInvalid
+		}
+		if err := checkUserActive(&token.Account.User); err != nil {
+			return nil, err
+		}
+	}
+
+	if !token.HasScope(scope) || !token.AllowsIP(ip) {
+		return nil, ErrAccountTokenForbidden
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > tokenTouchInterval || token.LastUsedIP != ip {
+		// Usage tracking must not fail the request
+		_ = s.tokenRepo.Touch(token.ID, ip)
+	}
+
+	return token, nil
+}
+
+// issue generates a unique secret for a token and stores it
+func (s *AccountTokenService) issue(token *models.AccountToken) error {
+	maxAttempts := 5
+	for i := 0; i < maxAttempts; i++ {
+		plain, err := utils.GenerateSecureToken(32)
+		if err != nil {
+			return err
+		}
+
+		hash := utils.HashToken(plain)
+		exists, err := s.tokenRepo.HashExists(hash)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			token.TokenHash = hash
+			token.TokenPrefix = plain[:models.APITokenPrefixLength]
+			if err := s.tokenRepo.Create(token); err != nil {
+				return err
+			}
+			token.Token = plain
+			return nil
+		}
+	}
+
+	return errors.New("failed to generate unique token")
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"reflect"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize is how many audit events are loaded at a time while exporting
+const auditExportBatchSize = 500
+
+// auditOmittedFields are JSON fields never kept in snapshots: plaintext secrets that are
+// only set right after issuing, nested relations and the update time
+var auditOmittedFields = []string{"token", "api_token", "code", "url", "user", "accounts", "statistics", "updated_at"}
+
+// Actor is who performs an action and from where. Handlers build it from the request;
+// services record it in the audit log.
+type Actor struct {
+	UserID   uint // zero for anonymous requests and the system
+	Username string
+	// Admin impersonating the user, zero unless the request uses an impersonation token
+	ImpersonatorID   uint
+	ImpersonatorName string
+	// Personal access token the request was authenticated with, zero for sessions
+	PersonalAccessTokenID uint
+	IP                    string
+	UserAgent             string
+	RequestID             string
+	// Effective permissions of the request, which bound what the actor can grant others
+	Permissions models.PermissionSet
+}
+
+// SystemActor is the actor of actions that aren't caused by an API request
+func SystemActor() Actor {
+	return Actor{Username: "system", Permissions: models.NewPermissionSet(models.Permissions)}
+}
+
+// WithUser returns the actor acting as a user it just authenticated, e.g. during login
+func (a Actor) WithUser(user *models.User) Actor {
+	a.UserID = user.ID
+	a.Username = user.Username
+	return a
+}
+
+// AuditService appends security- and data-relevant actions to the audit log and queries it
+type AuditService struct {
+	auditRepo *repository.AuditEventRepository
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{
+		auditRepo: repository.NewAuditEventRepository(db),
+	}
+}
+
+// Record appends an audit event. before and after are snapshots of the target, nil when it
+// didn't exist before or doesn't exist after; for updates only the changed fields are kept.
+// The action already happened, so a failure to record it is logged rather than returned.
+func (s *AuditService) Record(actor Actor, action, targetType string, targetID uint, before, after interface{}) {
+	if err := s.Append(actor, action, targetType, targetID, before, after); err != nil {
+		log.Printf("Failed to record audit event %s on %s %d: %v", action, targetType, targetID, err)
+	}
+}
+
+// Append records an audit event like Record but returns the error, for actions recorded
+// in their own transaction that must not commit without their audit event
+func (s *AuditService) Append(actor Actor, action, targetType string, targetID uint, before, after interface{}) error {
+	event := &models.AuditEvent{
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	}
+	if actor.UserID != 0 {
+		event.ActorID = &actor.UserID
+	}
+	if actor.ImpersonatorID != 0 {
+		event.ImpersonatorID = &actor.ImpersonatorID
+		event.ImpersonatorName = actor.ImpersonatorName
+	}
+	if actor.PersonalAccessTokenID != 0 {
+		event.PersonalAccessTokenID = &actor.PersonalAccessTokenID
+	}
+
+	if event.Before != nil && event.After != nil {
+		for field, value := range event.Before {
+			if reflect.DeepEqual(value, event.After[field]) {
+				delete(event.Before, field)
+				delete(event.After, field)
+			}
+		}
+	}
+
+	return s.auditRepo.Create(event)
+}
+
+// GetEvents retrieves audit events matching a filter with pagination, newest first
+func (s *AuditService) GetEvents(filter repository.AuditEventFilter, page, pageSize int) ([]models.AuditEvent, *utils.PaginationMeta, error) {
+	events, total, err := s.auditRepo.Find(filter, page, pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	pagination := &utils.PaginationMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: total,
+		TotalPages: totalPages,
+	}
+
+	return events, pagination, nil
+}
+
+// ExportEvents passes every audit event matching a filter to fn in batches, oldest first
+func (s *AuditService) ExportEvents(filter repository.AuditEventFilter, fn func([]models.AuditEvent) error) error {
+	return s.auditRepo.FindInBatches(filter, auditExportBatchSize, fn)
+}
+
+// auditSnapshot converts a target to its JSON fields, so only what the API exposes
+// (never hashes or secrets tagged json:"-") ends up in the audit log
+func auditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	for _, field := range auditOmittedFields {
+		delete(snapshot, field)
+	}
+	return snapshot
+}
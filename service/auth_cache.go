@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// authCacheTTL bounds how long an instance may act on stale authentication state
+const authCacheTTL = 30 * time.Second
+
+type ttlEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// ttlCache is a small concurrency-safe cache whose entries expire after a fixed TTL.
+// Expired entries are swept at most once per TTL when values are set, so the cache only
+// holds what was set within the last two TTLs.
+type ttlCache[K comparable, V any] struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[K]ttlEntry[V]
+	lastSweep time.Time
+}
+
+func newTTLCache[K comparable, V any](ttl time.Duration) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:       ttl,
+		entries:   make(map[K]ttlEntry[V]),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns a cached value if present and not expired
+func (c *ttlCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set caches a value for the cache TTL
+func (c *ttlCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[key] = ttlEntry[V]{value: value, expires: now.Add(c.ttl)}
+}
+
+// Delete removes a cached value
+func (c *ttlCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
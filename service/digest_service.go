@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+
+	// Bundle the timezone database so DIGEST_TIMEZONE works in minimal containers
+	_ "time/tzdata"
+
+	"gorm.io/gorm"
+)
+
+type DigestService struct {
+	userRepo      *repository.UserRepository
+	accountRepo   *repository.AccountRepository
+	statisticRepo *repository.StatisticRepository
+	emailService  *EmailService
+}
+
+func NewDigestService(db *gorm.DB) *DigestService {
+	return &DigestService{
+		userRepo:      repository.NewUserRepository(db),
+		accountRepo:   repository.NewAccountRepository(db),
+		statisticRepo: repository.NewStatisticRepository(db),
+		emailService:  NewEmailService(db),
+	}
+}
+
+// AccountDigest summarises one account over a trading day
+type AccountDigest struct {
+	AccountID          uint    `json:"account_id"`
+	AccountName        string  `json:"account_name"`
+	HasData            bool    `json:"has_data"`
+	DailyPL            float64 `json:"daily_pl"`
+	Balance            float64 `json:"balance"`
+	TradesToday        int     `json:"trades_today"`
+	DrawdownPercent    float64 `json:"drawdown_percent"`
+	MaxDrawdownPercent float64 `json:"max_drawdown_percent"`
+}
+
+// TradingDayEnd returns the end of the most recent trading day at or before t
+func TradingDayEnd(t time.Time) (time.Time, error) {
+	cfg := config.AppConfig.Digest
+
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DIGEST_TIMEZONE: %w", err)
+	}
+
+	closeAt, err := time.ParseInLocation("15:04", cfg.Time, loc)
+	if err != nil {
+		return time.Time{}, errors.New("invalid DIGEST_TIME, use HH:MM")
+	}
+
+	local := t.In(loc)
+	end := time.Date(local.Year(), local.Month(), local.Day(), closeAt.Hour(), closeAt.Minute(), 0, 0, loc)
+	if end.After(t) {
+		end = end.AddDate(0, 0, -1)
+	}
+	return end, nil
+}
+
+// BuildDigest summarises every personal account of a user for the trading day ending at dayEnd
+func (s *DigestService) BuildDigest(userID uint, dayEnd time.Time) ([]AccountDigest, error) {
+	accounts, err := s.accountRepo.FindPersonalByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart := dayEnd.AddDate(0, 0, -1)
+	digests := make([]AccountDigest, 0, len(accounts))
+
+	for _, account := range accounts {
+		statistics, err := s.statisticRepo.FindBetween(account.ID, dayStart, dayEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		var peak float64
+		if len(statistics) > 0 {
+			if peak, err = s.statisticRepo.GetPeakBalance(account.ID, dayStart); err != nil {
+				return nil, err
+			}
+		}
+
+		digests = append(digests, summarizeAccount(account, statistics, peak))
+	}
+
+	return digests, nil
+}
+
+// summarizeAccount summarises an account's statistics over a trading day, in time order.
+// peak is the highest balance before the day started.
+func summarizeAccount(account models.Account, statistics []models.Statistic, peak float64) AccountDigest {
+	digest := AccountDigest{AccountID: account.ID, AccountName: account.Name}
+	if len(statistics) == 0 {
+		return digest
+	}
+
+	// Track the running peak through the day to find the deepest drawdown
+	for _, stat := range statistics {
+		if stat.TotalBalance > peak {
+			peak = stat.TotalBalance
+		}
+		if dd := drawdownPercent(peak, stat.TotalBalance); dd > digest.MaxDrawdownPercent {
+			digest.MaxDrawdownPercent = dd
+		}
+	}
+
+	latest := statistics[len(statistics)-1]
+	digest.HasData = true
+	digest.DailyPL = latest.DailyPL
+	digest.Balance = latest.TotalBalance
+	digest.TradesToday = latest.TradesToday
+	digest.DrawdownPercent = drawdownPercent(peak, latest.TotalBalance)
+	return digest
+}
+
+// SendDigest emails the digest for the trading day ending at dayEnd to a user
+func (s *DigestService) SendDigest(user *models.User, dayEnd time.Time) error {
+	if !user.HasVerifiedEmail() {
+		return errors.New("user has no verified email address")
+	}
+
+	accounts, err := s.BuildDigest(user.ID, dayEnd)
+	if err != nil {
+		return err
+	}
+
+	return s.sendDigest(user, dayEnd, accounts)
+}
+
+// sendDigest emails the account summaries of a trading day, with their totals, to a user
+func (s *DigestService) sendDigest(user *models.User, dayEnd time.Time, accounts []AccountDigest) error {
+	var totalPL, totalBalance float64
+	for _, account := range accounts {
+		totalPL += account.DailyPL
+		totalBalance += account.Balance
+	}
+
+	return s.emailService.Send(user.Email, "daily_digest", map[string]interface{}{
+		"Username":     user.Username,
+		"Date":         dayEnd.Format("2006-01-02"),
+		"Accounts":     accounts,
+		"TotalPL":      totalPL,
+		"TotalBalance": totalBalance,
+	})
+}
+
+// SendAll sends the digest for the trading day ending at dayEnd to every opted-in user
+func (s *DigestService) SendAll(dayEnd time.Time) error {
+	users, err := s.userRepo.FindDigestRecipients()
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		claimed, err := s.userRepo.ClaimDigest(users[i].ID, dayEnd)
+		if err != nil {
+			log.Printf("Digest: failed to claim digest for user %d: %v", users[i].ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.SendDigest(&users[i], dayEnd); err != nil {
+			log.Printf("Digest: failed to send digest to user %d: %v", users[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Run sends the daily digest at the end of every trading day until ctx is cancelled
+func (s *DigestService) Run(ctx context.Context) {
+	for {
+		lastEnd, err := TradingDayEnd(time.Now())
+		if err != nil {
+			log.Printf("Digest scheduler disabled: %v", err)
+			return
+		}
+		nextEnd := lastEnd.AddDate(0, 0, 1)
+
+		timer := time.NewTimer(time.Until(nextEnd))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.SendAll(nextEnd); err != nil {
+			log.Printf("Digest: %v", err)
+		}
+	}
+}
+
+// drawdownPercent returns how far balance is below peak, in percent
+func drawdownPercent(peak, balance float64) float64 {
+	if peak <= 0 || balance >= peak {
+		return 0
+	}
+	return (peak - balance) / peak * 100
+}
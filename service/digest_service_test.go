@@ -0,0 +1,266 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+)
+
+// sentEmail is an email captured by memorySender
+type sentEmail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// memorySender keeps emails in memory instead of sending them
+type memorySender struct {
+	sent []sentEmail
+}
+
+func (m *memorySender) Send(to, subject, htmlBody string) error {
+	m.sent = append(m.sent, sentEmail{To: to, Subject: subject, Body: htmlBody})
+	return nil
+}
+
+func TestSummarizeAccount(t *testing.T) {
+	account := models.Account{ID: 7, Name: "Main"}
+	at := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	stat := func(hour int, balance, dailyPL float64, trades int) models.Statistic {
+		return models.Statistic{
+			AccountID:    account.ID,
+			Timestamp:    at.Add(time.Duration(hour) * time.Hour),
+			TotalBalance: balance,
+			DailyPL:      dailyPL,
+			TradesToday:  trades,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		statistics []models.Statistic
+		peak       float64
+		want       AccountDigest
+	}{
+		{
+			name: "no statistics",
+			want: AccountDigest{AccountID: 7, AccountName: "Main"},
+		},
+		{
+			name:       "new high",
+			statistics: []models.Statistic{stat(0, 1000, 0, 0), stat(1, 1100, 100, 2)},
+			peak:       1000,
+			want: AccountDigest{
+				AccountID: 7, AccountName: "Main", HasData: true,
+				DailyPL: 100, Balance: 1100, TradesToday: 2,
+			},
+		},
+		{
+			name:       "recovered intraday drawdown",
+			statistics: []models.Statistic{stat(0, 1000, 0, 0), stat(1, 800, -200, 3), stat(2, 950, -50, 5)},
+			peak:       1000,
+			want: AccountDigest{
+				AccountID: 7, AccountName: "Main", HasData: true,
+				DailyPL: -50, Balance: 950, TradesToday: 5,
+				DrawdownPercent: 5, MaxDrawdownPercent: 20,
+			},
+		},
+		{
+			name:       "peak before the day",
+			statistics: []models.Statistic{stat(0, 900, -100, 1)},
+			peak:       1200,
+			want: AccountDigest{
+				AccountID: 7, AccountName: "Main", HasData: true,
+				DailyPL: -100, Balance: 900, TradesToday: 1,
+				DrawdownPercent: 25, MaxDrawdownPercent: 25,
+			},
+		},
+		{
+			name:       "peak set during the day",
+			statistics: []models.Statistic{stat(0, 1500, 500, 1), stat(1, 1200, 200, 4)},
+			peak:       1000,
+			want: AccountDigest{
+				AccountID: 7, AccountName: "Main", HasData: true,
+				DailyPL: 200, Balance: 1200, TradesToday: 4,
+				DrawdownPercent: 20, MaxDrawdownPercent: 20,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeAccount(account, tt.statistics, tt.peak)
+
+			// Compare percentages with a tolerance, then the rest exactly
+			if math.Abs(got.DrawdownPercent-tt.want.DrawdownPercent) > 1e-9 {
+				t.Errorf("DrawdownPercent = %v, want %v", got.DrawdownPercent, tt.want.DrawdownPercent)
+			}
+			if math.Abs(got.MaxDrawdownPercent-tt.want.MaxDrawdownPercent) > 1e-9 {
+				t.Errorf("MaxDrawdownPercent = %v, want %v", got.MaxDrawdownPercent, tt.want.MaxDrawdownPercent)
+			}
+			got.DrawdownPercent, got.MaxDrawdownPercent = tt.want.DrawdownPercent, tt.want.MaxDrawdownPercent
+			if got != tt.want {
+				t.Errorf("summarizeAccount() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTradingDayEnd(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		time     string
+		timezone string
+		at       time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "before close belongs to the previous day",
+			time:     "22:00",
+			timezone: "UTC",
+			at:       time.Date(2026, 3, 2, 21, 59, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "at close ends the day",
+			time:     "22:00",
+			timezone: "UTC",
+			at:       time.Date(2026, 3, 2, 22, 0, 0, 0, time.UTC),
+			want:     time.Date(2026, 3, 2, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "close in another timezone",
+			time:     "17:00",
+			timezone: "America/New_York",
+			at:       time.Date(2026, 7, 1, 20, 30, 0, 0, time.UTC),
+			want:     time.Date(2026, 6, 30, 17, 0, 0, 0, newYork),
+		},
+		{
+			name:     "invalid timezone",
+			time:     "22:00",
+			timezone: "Mars/Olympus",
+			at:       time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+		{
+			name:     "invalid time",
+			time:     "10pm",
+			timezone: "UTC",
+			at:       time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig = &config.Config{Digest: config.DigestConfig{Time: tt.time, Timezone: tt.timezone}}
+
+			got, err := TradingDayEnd(tt.at)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("TradingDayEnd() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TradingDayEnd() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("TradingDayEnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	user := &models.User{Username: "trader", Email: "trader@example.com"}
+	dayEnd := time.Date(2026, 3, 2, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		accounts []AccountDigest
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "accounts with and without data",
+			accounts: []AccountDigest{
+				{AccountName: "Main", HasData: true, DailyPL: -50, Balance: 950, TradesToday: 5, DrawdownPercent: 5, MaxDrawdownPercent: 20},
+				{AccountName: "Swing", HasData: true, DailyPL: 120.5, Balance: 2000, TradesToday: 1},
+				{AccountName: "Idle"},
+			},
+			contains: []string{
+				"Hi trader", "2026-03-02",
+				"Main", "-50.00", "950.00", "5.00%", "20.00%",
+				"Swing", "120.50", "2000.00",
+				"Idle", "No data received today",
+				"<strong>Total daily P/L:</strong> 70.50", "<strong>Total balance:</strong> 2950.00",
+			},
+		},
+		{
+			name:     "no accounts",
+			contains: []string{"Hi trader", "<strong>Total daily P/L:</strong> 0.00"},
+			excludes: []string{"No data received today"},
+		},
+		{
+			name:     "names are escaped",
+			accounts: []AccountDigest{{AccountName: "<b>Bold</b>"}},
+			contains: []string{"&lt;b&gt;Bold&lt;/b&gt;"},
+			excludes: []string{"<b>Bold</b>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &memorySender{}
+			s := &DigestService{emailService: &EmailService{sender: sender}}
+
+			if err := s.sendDigest(user, dayEnd, tt.accounts); err != nil {
+				t.Fatalf("sendDigest() error = %v", err)
+			}
+			if len(sender.sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sender.sent))
+			}
+
+			email := sender.sent[0]
+			if email.To != user.Email {
+				t.Errorf("To = %q, want %q", email.To, user.Email)
+			}
+			if want := "X-Track daily digest for 2026-03-02"; email.Subject != want {
+				t.Errorf("Subject = %q, want %q", email.Subject, want)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(email.Body, want) {
+					t.Errorf("body doesn't contain %q", want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(email.Body, unwanted) {
+					t.Errorf("body contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestSendDigestRequiresVerifiedEmail(t *testing.T) {
+	sender := &memorySender{}
+	s := &DigestService{emailService: &EmailService{sender: sender}}
+
+	err := s.SendDigest(&models.User{Username: "trader", Email: "trader@example.com"}, time.Now())
+	if err == nil {
+		t.Fatal("SendDigest() sent to an unverified address")
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails, want none", len(sender.sent))
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/url"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var emailTemplates = loadEmailTemplates()
+
+// emailVerificationTTL is how long an email verification link stays valid
+const emailVerificationTTL = 48 * time.Hour
+
+// Sender delivers a rendered email
+type Sender interface {
+	Send(to, subject, htmlBody string) error
+}
+
+// smtpSender sends email through the configured SMTP server, and drops it when none is
+// configured
+type smtpSender struct{}
+
+func (smtpSender) Send(to, subject, htmlBody string) error {
+	smtpConfig := config.AppConfig.SMTP
+	if !smtpConfig.Enabled() {
+		log.Printf("SMTP not configured, skipping email %q to %s", subject, to)
+		return nil
+	}
+
+	return utils.SendMail(smtpConfig, to, subject, htmlBody)
+}
+
+type EmailService struct {
+	userRepo         *repository.UserRepository
+	verificationRepo *repository.EmailVerificationRepository
+	sender           Sender
+}
+
+func NewEmailService(db *gorm.DB) *EmailService {
+	return &EmailService{
+		userRepo:         repository.NewUserRepository(db),
+		verificationRepo: repository.NewEmailVerificationRepository(db),
+		sender:           smtpSender{},
+	}
+}
+
+// loadEmailTemplates parses each email template together with the shared layout
+func loadEmailTemplates() map[string]*template.Template {
+	templates := make(map[string]*template.Template)
+	for _, name := range []string{"verify_email", "alert", "daily_digest", "password_reset"} {
+		templates[name] = template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return templates
+}
+
+// Send renders a named template and emails it to the given address
+func (s *EmailService) Send(to, templateName string, data interface{}) error {
+	tmpl, ok := emailTemplates[templateName]
+	if !ok {
+		return errors.New("unknown email template: " + templateName)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return err
+	}
+
+	return s.sender.Send(to, subject.String(), body.String())
+}
+
+// RequestVerification issues a verification token for the user's current email and sends the link
+func (s *EmailService) RequestVerification(user *models.User) error {
+	if user.Email == "" {
+		return errors.New("user has no email address")
+	}
+
+	// Only the most recent link stays valid
+	if err := s.verificationRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	}
+	if err := s.verificationRepo.Create(verification); err != nil {
+		return err
+	}
+
+	return s.Send(user.Email, "verify_email", map[string]interface{}{
+		"Username":  user.Username,
+		"Email":     user.Email,
+		"Link":      config.AppConfig.Server.BaseURL + "/verify-email?token=" + url.QueryEscape(token),
+		"ExpiresAt": verification.ExpiresAt,
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the user's email as verified
+func (s *EmailService) VerifyEmail(token string) (*models.User, error) {
+	verification, err := s.verificationRepo.FindByTokenHash(utils.HashToken(token))
+	if err != nil {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.FindByID(verification.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// The address changed after this link was sent
+	if user.Email != verification.Email {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.verificationRepo.MarkUsed(verification.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SendAlert emails an alert to a user if they have a verified email address
+func (s *EmailService) SendAlert(user *models.User, accountName, title, message string) error {
+	if !user.HasVerifiedEmail() {
+		return nil
+	}
+
+	return s.Send(user.Email, "alert", map[string]interface{}{
+		"Username":    user.Username,
+		"AccountName": accountName,
+		"Title":       title,
+		"Message":     message,
+		"Time":        time.Now(),
+	})
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"x-track/events"
+	"x-track/repository"
+
+	"gorm.io/gorm"
+)
+
+// StartEventSubscribers attaches live streams, alert notifications, session revocation
+// and role changes to the event bus
+func StartEventSubscribers(db *gorm.DB) {
+	events.Subscribe(Streams.HandleEvent)
+	events.Subscribe(newAlertNotifier(db).HandleEvent)
+	events.Subscribe(NewSessionService(db).HandleEvent)
+	events.Subscribe(NewRoleService(db).HandleEvent)
+}
+
+// alertNotifier emails account owners when an alert, such as a drawdown past
+// ALERT_DRAWDOWN_PERCENT, is raised
+type alertNotifier struct {
+	accountRepo    *repository.AccountRepository
+	accountService *AccountService
+	emailService   *EmailService
+}
+
+func newAlertNotifier(db *gorm.DB) *alertNotifier {
+	return &alertNotifier{
+		accountRepo:    repository.NewAccountRepository(db),
+		accountService: NewAccountService(db),
+		emailService:   NewEmailService(db),
+	}
+}
+
+// HandleEvent sends alert emails for alerts raised on this instance, so each alert is emailed once
+func (n *alertNotifier) HandleEvent(event events.Event) {
+	if event.Type != events.AlertRaised || !event.IsLocal() {
+		return
+	}
+
+	go func() {
+		var alert events.Alert
+		if err := json.Unmarshal(event.Payload, &alert); err != nil {
+			log.Printf("Alert notifier: invalid alert payload: %v", err)
+			return
+		}
+
+		account, err := n.accountRepo.FindByID(event.AccountID)
+		if err != nil {
+			log.Printf("Alert notifier: account %d not found: %v", event.AccountID, err)
+			return
+		}
+
+		// The creator of an organization account only hears of it while still a member
+		access, err := n.accountService.GetAccess(account, account.UserID, false)
+		if err != nil || access < AccountAccessViewer {
+			return
+		}
+
+		if err := n.emailService.SendAlert(&account.User, account.Name, alert.Title, alert.Message); err != nil {
+			log.Printf("Alert notifier: failed to email user %d: %v", account.UserID, err)
+		}
+	}()
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultImpersonationDuration is how long an impersonation lasts when no duration is given
+	DefaultImpersonationDuration = 15 * time.Minute
+	// MaxImpersonationDuration is the longest an impersonation can last; it can't be refreshed
+	MaxImpersonationDuration = time.Hour
+)
+
+// ErrNotImpersonating is returned when ending an impersonation with a regular session
+var ErrNotImpersonating = errors.New("this session is not impersonating a user")
+
+// Impersonation is a running impersonation and the access token that acts as the user
+type Impersonation struct {
+	AccessToken  string       `json:"token"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	ReadOnly     bool         `json:"read_only"`
+	User         *models.User `json:"-"`
+	Impersonator string       `json:"impersonator"`
+}
+
+// ImpersonationService lets admins see the API as another user sees it
+type ImpersonationService struct {
+	userRepo          *repository.UserRepository
+	sessionRepo       *repository.SessionRepository
+	roleService       *RoleService
+	sessionService    *SessionService
+	signingKeyService *SigningKeyService
+	auditService      *AuditService
+}
+
+func NewImpersonationService(db *gorm.DB) *ImpersonationService {
+	return &ImpersonationService{
+		userRepo:          repository.NewUserRepository(db),
+		sessionRepo:       repository.NewSessionRepository(db),
+		roleService:       NewRoleService(db),
+		sessionService:    NewSessionService(db),
+		signingKeyService: NewSigningKeyService(db),
+		auditService:      NewAuditService(db),
+	}
+}
+
+// Start issues a token that acts as a user for the given duration, tied to the admin's own
+// session. The token is read-only unless allowWrite is set, and has no refresh token.
+// Admins can only impersonate users whose permissions they hold themselves.
+func (s *ImpersonationService) Start(actor Actor, sessionID string, userID uint, reason string, duration time.Duration, allowWrite bool) (*Impersonation, error) {
+	if duration <= 0 || duration > MaxImpersonationDuration {
+		return nil, fmt.Errorf("duration must be between 1 and %d minutes", int(MaxImpersonationDuration.Minutes()))
+	}
+	if userID == actor.UserID {
+		return nil, errors.New("you cannot impersonate yourself")
+	}
+
+	impersonator, err := s.userRepo.FindByID(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUserActive(user); err != nil {
+		return nil, err
+	}
+
+	// Impersonation must not grant the admin anything their own role doesn't
+	own, err := s.roleService.Permissions(impersonator.Role)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := s.roleService.Permissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range theirs.List() {
+		if !own.Has(permission) {
+			return nil, fmt.Errorf("you cannot impersonate a user with permission %s", permission)
+		}
+	}
+
+	impersonationSessionID, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+	// The session ends with the token, so claims rebuilt from it can't outlast the limit
+	expiresAt := time.Now().Add(duration)
+	if err := s.sessionRepo.Create(&models.Session{
+		ID:         impersonationSessionID,
+		UserID:     user.ID,
+		IP:         actor.IP,
+		UserAgent:  truncate(actor.UserAgent, 255),
+		ExpiresAt:  &expiresAt,
+		LastUsedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	readOnly := !allowWrite
+	token, err := s.signingKeyService.SignAccessToken(utils.JWTClaims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		SessionID:    impersonationSessionID,
+		TokenVersion: user.TokenVersion,
+		Impersonator: &utils.ImpersonatorClaims{
+			UserID:       impersonator.ID,
+			Username:     impersonator.Username,
+			SessionID:    sessionID,
+			TokenVersion: impersonator.TokenVersion,
+		},
+		ReadOnly: readOnly,
+	}, time.Until(expiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, models.AuditImpersonationStart, models.AuditTargetUser, user.ID, nil, map[string]interface{}{
+		"session_id": impersonationSessionID,
+		"reason":     reason,
+		"read_only":  readOnly,
+		"expires_at": expiresAt,
+	})
+
+	return &Impersonation{
+		AccessToken:  token,
+		ExpiresAt:    expiresAt,
+		ReadOnly:     readOnly,
+		User:         user,
+		Impersonator: impersonator.Username,
+	}, nil
+}
+
+// End revokes the impersonation session the actor is using
+func (s *ImpersonationService) End(actor Actor, sessionID string) error {
+	if actor.ImpersonatorID == 0 {
+		return ErrNotImpersonating
+	}
+	if err := s.sessionService.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditImpersonationEnd, models.AuditTargetUser, actor.UserID, nil, nil)
+	return nil
+}
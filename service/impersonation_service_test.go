@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// testJWTConfig is the token configuration of tests that sign tokens
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Algorithm:          "EdDSA",
+		Issuer:             "x-track",
+		Audience:           "x-track-api",
+		KeyRotationDays:    30,
+		AccessTokenMinutes: 15,
+		RefreshTokenDays:   7,
+	}
+}
+
+// useTestSigningKey replaces the key ring with a single key stored in db that signs from now
+func useTestSigningKey(t *testing.T, db *gorm.DB) *models.SigningKey {
+	t.Helper()
+
+	signingKeys.snapshot.Store(nil)
+	t.Cleanup(func() { signingKeys.snapshot.Store(nil) })
+
+	key, err := NewSigningKeyService(db).createKey(repository.NewSigningKeyRepository(db), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestImpersonationSessionExpires(t *testing.T) {
+	config.AppConfig = &config.Config{JWT: testJWTConfig()}
+	db := newTestDB(t, &models.User{}, &models.Session{}, &models.SigningKey{}, &models.Role{}, &models.AuditEvent{})
+	useTestSigningKey(t, db)
+
+	admin := &models.User{Username: "admin", Role: models.RoleAdmin, TokenVersion: 1}
+	alice := &models.User{Username: "alice", Role: models.RoleUser, TokenVersion: 1}
+	if err := db.Create([]*models.User{admin, alice}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{admin.ID, alice.ID} {
+		tokenVersionCache.Delete(id)
+	}
+	if err := db.Create(&models.Session{ID: "admin-session", UserID: admin.ID, LastUsedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewImpersonationService(db)
+	sessions := NewSessionService(db)
+	actor := Actor{UserID: admin.ID, Username: admin.Username, Permissions: models.NewPermissionSet(models.Permissions)}
+
+	impersonation, err := s.Start(actor, "admin-session", alice.ID, "support ticket", time.Second, false)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	claims, err := NewSigningKeyService(db).ParseAccessToken(impersonation.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseAccessToken() error = %v", err)
+	}
+
+	var session models.Session
+	if err := db.First(&session, "id = ?", claims.SessionID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.ExpiresAt == nil || !session.ExpiresAt.Equal(impersonation.ExpiresAt) {
+		t.Fatalf("session expires at %v, want %v", session.ExpiresAt, impersonation.ExpiresAt)
+	}
+
+	// Claims rebuilt from the session, as for stream tickets, carry no token expiry
+	rebuilt := &utils.JWTClaims{
+		UserID:       claims.UserID,
+		SessionID:    claims.SessionID,
+		TokenVersion: claims.TokenVersion,
+		Impersonator: claims.Impersonator,
+		ReadOnly:     claims.ReadOnly,
+	}
+	if err := sessions.ValidateAccess(rebuilt); err != nil {
+		t.Fatalf("ValidateAccess() during the impersonation error = %v", err)
+	}
+
+	time.Sleep(time.Until(impersonation.ExpiresAt) + 10*time.Millisecond)
+	if err := sessions.ValidateAccess(rebuilt); err == nil {
+		t.Fatal("ValidateAccess() accepted an impersonation past its time limit")
+	}
+	if sessions.IsActive(claims.SessionID) {
+		t.Error("IsActive() reports an expired impersonation session as active")
+	}
+	if !sessions.IsActive("admin-session") {
+		t.Error("IsActive() reports the admin's own session as ended")
+	}
+}
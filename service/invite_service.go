@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxInviteLifetime is the longest an invite can stay valid
+	maxInviteLifetime = 90 * 24 * time.Hour
+	// maxInviteUses is the most registrations a single invite allows
+	maxInviteUses = 1000
+)
+
+// ErrInviteInvalid is returned for unknown, revoked, expired or used up invites
+var ErrInviteInvalid = errors.New("invite is invalid, expired or used up")
+
+type InviteService struct {
+	inviteRepo     *repository.InviteRepository
+	userRepo       *repository.UserRepository
+	roleService    *RoleService
+	passwordPolicy *PasswordPolicyService
+	emailService   *EmailService
+	auditService   *AuditService
+}
+
+func NewInviteService(db *gorm.DB) *InviteService {
+	return &InviteService{
+		inviteRepo:     repository.NewInviteRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		roleService:    NewRoleService(db),
+		passwordPolicy: NewPasswordPolicyService(db),
+		emailService:   NewEmailService(db),
+		auditService:   NewAuditService(db),
+	}
+}
+
+// ListInvites retrieves all invites with the users who registered with them
+func (s *InviteService) ListInvites() ([]models.Invite, error) {
+	return s.inviteRepo.FindAll()
+}
+
+// CreateInvite creates an invite granting role to up to maxUses registrations before
+// expiresAt. The returned invite carries the code and link, which cannot be retrieved again.
+func (s *InviteService) CreateInvite(actor Actor, label, role string, maxUses int, expiresAt time.Time) (*models.Invite, error) {
+	if maxUses < 1 || maxUses > maxInviteUses {
+		return nil, fmt.Errorf("usage limit must be between 1 and %d", maxInviteUses)
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+	if expiresAt.After(time.Now().Add(maxInviteLifetime)) {
+		return nil, fmt.Errorf("invites can be valid for at most %d days", int(maxInviteLifetime.Hours()/24))
+	}
+	if exists, err := s.roleService.RoleExists(role); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, errors.New("role does not exist")
+	}
+	// Anyone with the link gets the role, so it can't exceed the creator's own permissions
+	if err := s.roleService.CheckRoleGrantable(actor, role); err != nil {
+		return nil, err
+	}
+
+	invite := &models.Invite{
+		CreatedByID: actor.UserID,
+		Label:       label,
+		Role:        role,
+		MaxUses:     maxUses,
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.issue(invite); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditInviteCreate, models.AuditTargetInvite, invite.ID, nil, invite)
+
+	invite.URL = config.AppConfig.Server.BaseURL + "/register/" + url.PathEscape(invite.Code)
+	return invite, nil
+}
+
+// RevokeInvite stops an invite from being redeemed. Users who already registered keep their access.
+func (s *InviteService) RevokeInvite(actor Actor, id uint) error {
+	invite, err := s.inviteRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.inviteRepo.Revoke(id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		s.auditService.Record(actor, models.AuditInviteRevoke, models.AuditTargetInvite, id, invite, nil)
+	}
+	return nil
+}
+
+// Register creates a user with the role of the invite the code belongs to
+func (s *InviteService) Register(actor Actor, code, username, email, password string) (*models.User, error) {
+	invite, err := s.inviteRepo.FindByHash(utils.HashToken(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInviteInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !invite.IsUsable() {
+		return nil, ErrInviteInvalid
+	}
+
+	if exists, err := s.userRepo.UsernameExists(username); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errors.New("username already exists")
+	}
+	if email != "" {
+		if exists, err := s.userRepo.EmailExists(email, 0); err != nil {
+			return nil, err
+		} else if exists {
+			return nil, errors.New("email already in use")
+		}
+	}
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return nil, err
+	}
+	if exists, err := s.roleService.RoleExists(invite.Role); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("the invite's role %q no longer exists", invite.Role)
+	}
+
+	hashedPassword, err := s.passwordPolicy.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim a use before creating the user, so concurrent registrations can't exceed the limit
+	claimed, err := s.inviteRepo.Claim(invite.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrInviteInvalid
+	}
+
+	user := &models.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: hashedPassword,
+		Role:         invite.Role,
+		InviteID:     &invite.ID,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		if releaseErr := s.inviteRepo.Release(invite.ID); releaseErr != nil {
+			log.Printf("Failed to release use of invite %d: %v", invite.ID, releaseErr)
+		}
+		return nil, err
+	}
+	s.auditService.Record(actor.WithUser(user), models.AuditUserCreate, models.AuditTargetUser, user.ID, nil, user)
+
+	if user.Email != "" {
+		if err := s.emailService.RequestVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// issue generates a unique code for an invite and stores it
+func (s *InviteService) issue(invite *models.Invite) error {
+	maxAttempts := 5
+	for i := 0; i < maxAttempts; i++ {
+		code, err := utils.GenerateSecureToken(16)
+		if err != nil {
+			return err
+		}
+
+		hash := utils.HashToken(code)
+		exists, err := s.inviteRepo.HashExists(hash)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			invite.CodeHash = hash
+			invite.CodePrefix = code[:models.APITokenPrefixLength]
+			if err := s.inviteRepo.Create(invite); err != nil {
+				return err
+			}
+			invite.Code = code
+			return nil
+		}
+	}
+
+	return errors.New("failed to generate unique invite code")
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+)
+
+func TestCreateInviteAuditOmitsCode(t *testing.T) {
+	config.AppConfig = &config.Config{Server: config.ServerConfig{BaseURL: "http://localhost:5173"}}
+	db := newTestDB(t, &models.User{}, &models.Invite{}, &models.Role{}, &models.AuditEvent{})
+	s := NewInviteService(db)
+
+	actor := SystemActor()
+	actor.UserID = 1
+	invite, err := s.CreateInvite(actor, "traders", models.RoleUser, 5, time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("CreateInvite() error = %v", err)
+	}
+	if invite.Code == "" || invite.URL == "" {
+		t.Fatal("CreateInvite() didn't return the code and link")
+	}
+
+	var event models.AuditEvent
+	if err := db.Where("action = ?", models.AuditInviteCreate).First(&event).Error; err != nil {
+		t.Fatalf("no %s audit event: %v", models.AuditInviteCreate, err)
+	}
+	if event.After["code_prefix"] != invite.CodePrefix {
+		t.Errorf("after = %v, want the code prefix %q", event.After, invite.CodePrefix)
+	}
+	for _, field := range []string{"code", "url"} {
+		if _, ok := event.After[field]; ok {
+			t.Errorf("audit event keeps the invite %s", field)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// progressiveDelayAfter is how many consecutive failures are allowed before delays start
+	progressiveDelayAfter = 3
+	// maxProgressiveDelay caps the wait between attempts before a full lockout
+	maxProgressiveDelay = 30 * time.Second
+)
+
+// Login attempt reasons
+const (
+	LoginReasonInvalidCredentials = "invalid_credentials"
+	LoginReasonInvalid2FA         = "invalid_2fa"
+	LoginReasonLocked             = "locked"
+	LoginReasonRateLimited        = "rate_limited"
+)
+
+// LoginBlockedError is returned when a login is refused before checking credentials
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+type LoginProtectionService struct {
+	userRepo     *repository.UserRepository
+	attemptRepo  *repository.LoginAttemptRepository
+	auditService *AuditService
+}
+
+func NewLoginProtectionService(db *gorm.DB) *LoginProtectionService {
+	return &LoginProtectionService{
+		userRepo:     repository.NewUserRepository(db),
+		attemptRepo:  repository.NewLoginAttemptRepository(db),
+		auditService: NewAuditService(db),
+	}
+}
+
+// CheckAllowed refuses logins for locked users, users inside their progressive delay
+// and IPs with too many recent failures. Unknown usernames are locked and delayed the
+// same way, so the response doesn't reveal which usernames exist.
+func (s *LoginProtectionService) CheckAllowed(username, ip, userAgent string) error {
+	cfg := config.AppConfig.Login
+
+	// Only actual guesses count, so retrying while blocked doesn't extend the block
+	guesses := []string{LoginReasonInvalidCredentials, LoginReasonInvalid2FA}
+	failures, err := s.attemptRepo.CountFailuresByIP(ip, guesses, time.Now().Add(-time.Duration(cfg.WindowMinutes)*time.Minute))
+	if err != nil {
+		return err
+	}
+	if failures >= int64(cfg.MaxIPFailures) {
+		s.record(nil, username, ip, userAgent, false, LoginReasonRateLimited)
+		return &LoginBlockedError{RetryAfter: time.Duration(cfg.WindowMinutes) * time.Minute}
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	known := user
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = s.replayFailures(username, guesses)
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.IsLocked() {
+		s.record(known, username, ip, userAgent, false, LoginReasonLocked)
+		return &LoginBlockedError{RetryAfter: time.Until(*user.LockedUntil)}
+	}
+
+	if wait := progressiveDelay(user, time.Duration(cfg.WindowMinutes)*time.Minute); wait > 0 {
+		s.record(known, username, ip, userAgent, false, LoginReasonRateLimited)
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// RecordFailure records a failed login and counts it against the user if they exist
+func (s *LoginProtectionService) RecordFailure(username, ip, userAgent, reason string) {
+	cfg := config.AppConfig.Login
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		user = nil
+	}
+
+	if user != nil {
+		now := time.Now()
+		staleBefore := now.Add(-time.Duration(cfg.WindowMinutes) * time.Minute)
+		lockUntil := now.Add(time.Duration(cfg.LockoutMinutes) * time.Minute)
+		if err := s.userRepo.RecordFailedLogin(user.ID, cfg.MaxFailures, staleBefore, lockUntil); err != nil {
+			log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		}
+	}
+
+	s.record(user, username, ip, userAgent, false, reason)
+}
+
+// RecordSuccess records a successful login and clears the user's failure counter
+func (s *LoginProtectionService) RecordSuccess(user *models.User, ip, userAgent string) {
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+			log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+		}
+	}
+
+	s.record(user, user.Username, ip, userAgent, true, "")
+}
+
+// Unlock clears a user's lockout and failure counter
+func (s *LoginProtectionService) Unlock(actor Actor, userID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.ResetFailedLogins(userID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditUserUnlock, models.AuditTargetUser, userID, nil, nil)
+	return nil
+}
+
+// GetLoginHistory retrieves a user's login attempts with pagination
+func (s *LoginProtectionService) GetLoginHistory(userID uint, page, pageSize int) ([]models.LoginAttempt, *utils.PaginationMeta, error) {
+	attempts, total, err := s.attemptRepo.FindByUserID(userID, page, pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	pagination := &utils.PaginationMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: total,
+		TotalPages: totalPages,
+	}
+
+	return attempts, pagination, nil
+}
+
+// record stores a login attempt; failures to record never block the login itself
+func (s *LoginProtectionService) record(user *models.User, username, ip, userAgent string, success bool, reason string) {
+	attempt := &models.LoginAttempt{
+		Username:  truncate(username, 50),
+		IP:        ip,
+		UserAgent: truncate(userAgent, 255),
+		Success:   success,
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		log.Printf("Failed to record login attempt for %q: %v", username, err)
+	}
+}
+
+// replayFailures rebuilds the failed login counter, last failure and lock an unknown
+// username would have from the failures recorded for it, applying the same rules as
+// UserRepository.RecordFailedLogin
+func (s *LoginProtectionService) replayFailures(username string, reasons []string) (*models.User, error) {
+	cfg := config.AppConfig.Login
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	lockout := time.Duration(cfg.LockoutMinutes) * time.Minute
+
+	// Older failures can't have left a lock or delay behind
+	since := time.Now().Add(-max(window, lockout))
+	times, err := s.attemptRepo.FindFailureTimesByUsername(truncate(username, 50), reasons, since)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: username}
+	for i := range times {
+		failedAt := times[i]
+		if (user.LockedUntil != nil && !user.LockedUntil.After(failedAt)) ||
+			user.LastFailedLoginAt == nil || user.LastFailedLoginAt.Before(failedAt.Add(-window)) {
+			user.FailedLoginCount = 0
+			user.LockedUntil = nil
+		}
+		user.FailedLoginCount++
+		user.LastFailedLoginAt = &failedAt
+		if user.FailedLoginCount >= cfg.MaxFailures {
+			lockUntil := failedAt.Add(lockout)
+			user.LockedUntil = &lockUntil
+		}
+	}
+
+	return user, nil
+}
+
+// progressiveDelay returns how long a user must wait after their last failure. Failures
+// before an expired lock or older than window no longer count.
+func progressiveDelay(user *models.User, window time.Duration) time.Duration {
+	if user.FailedLoginCount < progressiveDelayAfter || user.LastFailedLoginAt == nil {
+		return 0
+	}
+	// Callers check the lock first, so a lock that is still set has run out
+	if user.LockedUntil != nil || time.Since(*user.LastFailedLoginAt) > window {
+		return 0
+	}
+
+	delay := time.Second << (user.FailedLoginCount - progressiveDelayAfter)
+	if delay > maxProgressiveDelay || delay <= 0 {
+		delay = maxProgressiveDelay
+	}
+
+	return time.Until(user.LastFailedLoginAt.Add(delay))
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"x-track/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	stubClientID = "x-track"
+	stubKeyID    = "stub-key"
+)
+
+// stubIdP is an OpenID Connect identity provider serving discovery, a JWK set and a
+// token endpoint that answers every code but "revoked" with an ID token
+type stubIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// idToken returns the issued ID token; defaults to claims signed with the provider key
+	idToken func() string
+	// claims are the ID token claims issued for every code
+	claims jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &stubIdP{key: key}
+	idp.idToken = func() string { return idp.sign(stubKeyID, idp.claims) }
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeStubJSON(w, http.StatusOK, oidcDiscovery{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeStubJSON(w, http.StatusOK, map[string][]jsonWebKey{"keys": {{
+			Kty: "RSA",
+			Kid: stubKeyID,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code_verifier") == "" {
+			writeStubJSON(w, http.StatusBadRequest, oidcTokenResponse{Error: "invalid_request"})
+			return
+		}
+		if r.PostForm.Get("code") == "revoked" {
+			writeStubJSON(w, http.StatusBadRequest, oidcTokenResponse{Error: "invalid_grant", ErrorDescription: "code was already redeemed"})
+			return
+		}
+		writeStubJSON(w, http.StatusOK, oidcTokenResponse{IDToken: idp.idToken(), AccessToken: "access"})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// sign signs claims with the provider key under the given key ID
+func (idp *stubIdP) sign(kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// identityClaims are valid ID token claims for a subject of this provider
+func (idp *stubIdP) identityClaims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   stubClientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+// config is the single sign-on configuration of a client registered at this provider
+func (idp *stubIdP) config() config.OIDCConfig {
+	return config.OIDCConfig{
+		IssuerURL:     idp.URL,
+		ClientID:      stubClientID,
+		RedirectURL:   "http://localhost:5173/login/sso",
+		Scopes:        []string{"openid", "profile", "email"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+	}
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	idp := newStubIdP(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		token   func(claims jwt.MapClaims) string
+		claims  func(claims jwt.MapClaims)
+		wantErr string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "token without key ID",
+			token: func(claims jwt.MapClaims) string {
+				return idp.sign("", claims)
+			},
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  func(claims jwt.MapClaims) { claims["nonce"] = "replayed" },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "missing nonce",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "nonce") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "other issuer",
+			claims:  func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+			wantErr: "invalid issuer",
+		},
+		{
+			name:    "other audience",
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			wantErr: "invalid audience",
+		},
+		{
+			name:    "several audiences without authorized party",
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = []string{stubClientID, "other-client"} },
+			wantErr: "authorized party mismatch",
+		},
+		{
+			name: "several audiences issued to another party",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{stubClientID, "other-client"}
+				claims["azp"] = "other-client"
+			},
+			wantErr: "authorized party mismatch",
+		},
+		{
+			name: "several audiences issued to us",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{stubClientID, "other-client"}
+				claims["azp"] = stubClientID
+			},
+		},
+		{
+			name:    "expired",
+			claims:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "token is expired",
+		},
+		{
+			name:    "no expiry",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "exp") },
+			wantErr: "token is missing required claim",
+		},
+		{
+			name:    "missing subject",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "sub") },
+			wantErr: "missing subject",
+		},
+		{
+			name: "unknown key ID",
+			token: func(claims jwt.MapClaims) string {
+				return idp.sign("rotated-away", claims)
+			},
+			wantErr: `unknown signing key "rotated-away"`,
+		},
+		{
+			name: "signed with another key",
+			token: func(claims jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = stubKeyID
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			},
+			wantErr: "signature is invalid",
+		},
+		{
+			name: "unsigned",
+			token: func(claims jwt.MapClaims) string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return signed
+			},
+			wantErr: "signing method none is invalid",
+		},
+		{
+			name:    "token endpoint error",
+			code:    "revoked",
+			wantErr: "invalid_grant code was already redeemed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.identityClaims("subject-1", "nonce-1")
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			idp.claims = claims
+			idp.idToken = func() string { return idp.sign(stubKeyID, idp.claims) }
+			if tt.token != nil {
+				idp.idToken = func() string { return tt.token(idp.claims) }
+			}
+
+			cfg := idp.config()
+			provider := newOIDCProvider(&cfg)
+			code := tt.code
+			if code == "" {
+				code = "code-1"
+			}
+
+			got, err := provider.Exchange(code, "verifier", "nonce-1")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if got["sub"] != "subject-1" {
+				t.Errorf("sub = %v, want subject-1", got["sub"])
+			}
+		})
+	}
+}
+
+func TestOIDCProviderDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newStubIdP(t)
+	cfg := idp.config()
+
+	// The metadata is served for another issuer than the configured one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, idp.URL+"/.well-known/openid-configuration", http.StatusFound)
+	}))
+	defer server.Close()
+	cfg.IssuerURL = server.URL
+
+	provider := newOIDCProvider(&cfg)
+	if _, err := provider.AuthorizationURL("state", "nonce", "challenge"); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("AuthorizationURL() error = %v, want an issuer mismatch", err)
+	}
+}
+
+func TestOIDCProviderAuthorizationURL(t *testing.T) {
+	idp := newStubIdP(t)
+	cfg := idp.config()
+
+	authURL, err := newOIDCProvider(&cfg).AuthorizationURL("state-1", "nonce-1", "challenge-1")
+	if err != nil {
+		t.Fatalf("AuthorizationURL() error = %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Fatalf("AuthorizationURL() = %q, want the provider's authorization endpoint", authURL)
+	}
+	for _, want := range []string{"state=state-1", "nonce=nonce-1", "code_challenge=challenge-1", "code_challenge_method=S256", "client_id=" + stubClientID} {
+		if !strings.Contains(authURL, want) {
+			t.Errorf("AuthorizationURL() = %q, missing %q", authURL, want)
+		}
+	}
+}
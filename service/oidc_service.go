@@ -0,0 +1,386 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// oidcLoginTTL is how long a user has to complete a login at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCDisabled is returned when single sign-on is not configured
+	ErrOIDCDisabled = errors.New("single sign-on is not configured")
+	// ErrOIDCInvalidState is returned for an unknown, reused or expired login
+	ErrOIDCInvalidState = errors.New("single sign-on login expired or is invalid, please try again")
+	// ErrOIDCNoAccount is returned when no user matches the identity and provisioning is off
+	ErrOIDCNoAccount = errors.New("no X-Track account is linked to this identity")
+)
+
+// oidcIdentity is what X-Track reads from a verified ID token
+type oidcIdentity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// OIDCService logs users in through an OpenID Connect identity provider using the
+// authorization code flow with PKCE, linking or provisioning X-Track users
+type OIDCService struct {
+	cfg            *config.OIDCConfig
+	provider       *oidcProvider
+	stateRepo      *repository.OIDCLoginStateRepository
+	identityRepo   *repository.UserIdentityRepository
+	userRepo       *repository.UserRepository
+	roleService    *RoleService
+	sessionService *SessionService
+	passwordPolicy *PasswordPolicyService
+	auditService   *AuditService
+}
+
+func NewOIDCService(db *gorm.DB) *OIDCService {
+	cfg := &config.AppConfig.OIDC
+	return &OIDCService{
+		cfg:            cfg,
+		provider:       newOIDCProvider(cfg),
+		stateRepo:      repository.NewOIDCLoginStateRepository(db),
+		identityRepo:   repository.NewUserIdentityRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		roleService:    NewRoleService(db),
+		sessionService: NewSessionService(db),
+		passwordPolicy: NewPasswordPolicyService(db),
+		auditService:   NewAuditService(db),
+	}
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.Enabled()
+}
+
+// ProviderName is the identity provider name shown to users
+func (s *OIDCService) ProviderName() string {
+	return s.cfg.ProviderName
+}
+
+// RequiresTwoFactor reports whether a user signing in through the identity provider
+// must still pass X-Track two-factor authentication
+func (s *OIDCService) RequiresTwoFactor(user *models.User) bool {
+	return user.TOTPEnabled && !s.cfg.IdPEnforcesMFA
+}
+
+// BeginLogin starts a login and returns the identity provider URL to send the browser to.
+// The state, nonce and PKCE verifier are kept server-side until the callback.
+func (s *OIDCService) BeginLogin() (string, error) {
+	if !s.Enabled() {
+		return "", ErrOIDCDisabled
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := s.provider.AuthorizationURL(state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.stateRepo.DeleteExpired(); err != nil {
+		log.Printf("Failed to delete expired OIDC logins: %v", err)
+	}
+	if err := s.stateRepo.Create(&models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// CompleteLogin redeems the authorization code returned with state and resolves the
+// X-Track user of the verified identity. checkAllowed is called with the username before
+// the user is linked, provisioned or has their role changed, and its error aborts the login.
+func (s *OIDCService) CompleteLogin(actor Actor, code, state string, checkAllowed func(username string) error) (*models.User, error) {
+	if !s.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	pending, err := s.stateRepo.Consume(utils.HashToken(state))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOIDCInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.provider.Exchange(code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveUser(actor, s.parseIdentity(claims), checkAllowed)
+}
+
+// parseIdentity reads the subject, username, email and groups from ID token claims
+func (s *OIDCService) parseIdentity(claims jwt.MapClaims) oidcIdentity {
+	identity := oidcIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Username, _ = claims[s.cfg.UsernameClaim].(string)
+	identity.Email, _ = claims["email"].(string)
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	switch groups := claims[s.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = strings.Fields(groups)
+	}
+
+	return identity
+}
+
+// resolveUser finds the user linked to an identity, links an existing user by the configured
+// claims or provisions a new one, then applies the role mapped from the identity's groups.
+// Nothing is changed unless checkAllowed accepts the username first.
+func (s *OIDCService) resolveUser(actor Actor, identity oidcIdentity, checkAllowed func(username string) error) (*models.User, error) {
+	issuer := s.cfg.IssuerURL
+
+	linked, err := s.identityRepo.FindBySubject(issuer, identity.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var user *models.User
+	if linked != nil && linked.User.ID != 0 {
+		user = &linked.User
+		if err := checkAllowed(user.Username); err != nil {
+			return nil, err
+		}
+		if err := s.identityRepo.Touch(linked.ID, identity.Email); err != nil {
+			return nil, err
+		}
+	} else {
+		if linked != nil {
+			// The linked user was deleted
+			return nil, ErrOIDCNoAccount
+		}
+
+		user, err = s.findExistingUser(identity)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			if err := checkAllowed(user.Username); err != nil {
+				return nil, err
+			}
+		} else {
+			if !s.cfg.AutoProvision {
+				return nil, ErrOIDCNoAccount
+			}
+			username, err := provisionUsername(identity)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkAllowed(username); err != nil {
+				return nil, err
+			}
+			if user, err = s.provisionUser(actor, identity, username); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.identityRepo.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Issuer:      issuer,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			LastLoginAt: time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.syncRole(actor.WithUser(user), user, identity.Groups); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findExistingUser matches an identity to an existing user by verified email and/or username,
+// as configured. Email only matches users who verified the same address in X-Track.
+func (s *OIDCService) findExistingUser(identity oidcIdentity) (*models.User, error) {
+	for _, by := range s.cfg.LinkBy {
+		var user *models.User
+		var err error
+
+		switch by {
+		case "email":
+			if identity.Email == "" || !identity.EmailVerified {
+				continue
+			}
+			user, err = s.userRepo.FindByEmail(identity.Email)
+			if err == nil && !user.HasVerifiedEmail() {
+				continue
+			}
+		case "username":
+			if identity.Username == "" {
+				continue
+			}
+			user, err = s.userRepo.FindByUsername(identity.Username)
+		default:
+			continue
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	return nil, nil
+}
+
+// provisionUsername is the username a first-time identity is provisioned with
+func provisionUsername(identity oidcIdentity) (string, error) {
+	username := identity.Username
+	if username == "" && identity.Email != "" {
+		username, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if username == "" || len(username) > 50 {
+		return "", errors.New("identity provider did not return a usable username")
+	}
+	return username, nil
+}
+
+// provisionUser creates a user for a first-time identity. The user has no usable
+// password; an administrator can issue a password reset to enable local login.
+func (s *OIDCService) provisionUser(actor Actor, identity oidcIdentity, username string) (*models.User, error) {
+	exists, err := s.userRepo.UsernameExists(username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("username %q is already taken by an X-Track account that isn't linked to this identity", username)
+	}
+
+	role := s.cfg.DefaultRole
+	if mapped, ok := s.mapRole(identity.Groups); ok {
+		role = mapped
+	}
+	if exists, err := s.roleService.RoleExists(role); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("single sign-on role %q does not exist", role)
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.passwordPolicy.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+	if identity.Email != "" && identity.EmailVerified {
+		if taken, err := s.userRepo.EmailExists(identity.Email, 0); err != nil {
+			return nil, err
+		} else if !taken {
+			now := time.Now()
+			user.Email = identity.Email
+			user.EmailVerifiedAt = &now
+		}
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor.WithUser(user), models.AuditUserCreate, models.AuditTargetUser, user.ID, nil, user)
+
+	return user, nil
+}
+
+// syncRole applies the role mapped from the identity's groups. Users in no mapped
+// group keep their role, so local role assignments survive unmapped logins. A changed
+// role ends the user's other sessions, as when an administrator changes it.
+func (s *OIDCService) syncRole(actor Actor, user *models.User, groups []string) error {
+	role, ok := s.mapRole(groups)
+	if !ok || role == user.Role {
+		return nil
+	}
+
+	if exists, err := s.roleService.RoleExists(role); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("single sign-on role %q does not exist", role)
+	}
+
+	before := *user
+	user.Role = role
+	user.TokenVersion++
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditUserUpdate, models.AuditTargetUser, user.ID, &before, user)
+
+	return s.sessionService.InvalidateUser(user.ID, "")
+}
+
+// mapRole returns the role of the first mapping whose group the identity is in
+func (s *OIDCService) mapRole(groups []string) (string, bool) {
+	for _, mapping := range s.cfg.RoleMapping {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role, true
+			}
+		}
+	}
+	return "", false
+}
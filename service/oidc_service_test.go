@@ -0,0 +1,439 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/utils"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an empty in-memory database with the given models migrated
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newOIDCTestService returns a single sign-on service for a stub identity provider
+func newOIDCTestService(t *testing.T, configure func(cfg *config.OIDCConfig)) (*OIDCService, *stubIdP, *gorm.DB) {
+	t.Helper()
+
+	idp := newStubIdP(t)
+	config.AppConfig = &config.Config{
+		OIDC:     idp.config(),
+		Password: config.PasswordConfig{HashAlgorithm: utils.PasswordHashBcrypt, BcryptCost: bcrypt.MinCost},
+	}
+	config.AppConfig.OIDC.DefaultRole = models.RoleUser
+	config.AppConfig.OIDC.RoleMapping = []config.OIDCRoleMapping{{Group: "admins", Role: models.RoleAdmin}}
+	if configure != nil {
+		configure(&config.AppConfig.OIDC)
+	}
+
+	db := newTestDB(t, &models.User{}, &models.UserIdentity{}, &models.OIDCLoginState{},
+		&models.Role{}, &models.Session{}, &models.AuditEvent{})
+	return NewOIDCService(db), idp, db
+}
+
+// login signs in through the stub identity provider, which issues claims built for the login's nonce
+func (idp *stubIdP) login(s *OIDCService, claims func(nonce string) jwt.MapClaims, checkAllowed func(username string) error) (*models.User, error) {
+	authURL, err := s.BeginLogin()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return nil, err
+	}
+	query := parsed.Query()
+
+	idp.claims = claims(query.Get("nonce"))
+	return s.CompleteLogin(Actor{IP: "127.0.0.1"}, "code", query.Get("state"), checkAllowed)
+}
+
+func allowLogin(string) error { return nil }
+
+func TestOIDCCompleteLogin(t *testing.T) {
+	verifiedAt := time.Now()
+	errBlocked := errors.New("too many failed login attempts")
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.OIDCConfig)
+		// users are created before the login, linked to subject-1 when linked is set
+		users        []models.User
+		linked       string
+		deleteLinked bool
+		claims       map[string]interface{}
+		nonce        string
+		checkAllowed func(username string) error
+
+		wantErr  error
+		wantText string
+		wantUser string
+		wantRole string
+		// wantTokenVersion is the user's token version after the login, when set
+		wantTokenVersion int
+	}{
+		{
+			name:     "linked identity",
+			users:    []models.User{{Username: "alice", Role: models.RoleUser}},
+			linked:   "alice",
+			wantUser: "alice",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "deleted linked user",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.AutoProvision = true
+			},
+			users:        []models.User{{Username: "alice", Role: models.RoleUser}},
+			linked:       "alice",
+			deleteLinked: true,
+			claims:       map[string]interface{}{"preferred_username": "alice-2"},
+			wantErr:      ErrOIDCNoAccount,
+		},
+		{
+			name: "links verified email",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email"}
+			},
+			users:    []models.User{{Username: "alice", Role: models.RoleUser, Email: "Alice@example.com", EmailVerifiedAt: &verifiedAt}},
+			claims:   map[string]interface{}{"email": "alice@example.com", "email_verified": true},
+			wantUser: "alice",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "links email verified as a string",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email"}
+			},
+			users:    []models.User{{Username: "alice", Role: models.RoleUser, Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}},
+			claims:   map[string]interface{}{"email": "alice@example.com", "email_verified": "true"},
+			wantUser: "alice",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "ignores email the provider didn't verify",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email"}
+			},
+			users:   []models.User{{Username: "alice", Role: models.RoleUser, Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}},
+			claims:  map[string]interface{}{"email": "alice@example.com", "email_verified": false},
+			wantErr: ErrOIDCNoAccount,
+		},
+		{
+			name: "ignores email the user didn't verify",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email"}
+			},
+			users:   []models.User{{Username: "alice", Role: models.RoleUser, Email: "alice@example.com"}},
+			claims:  map[string]interface{}{"email": "alice@example.com", "email_verified": true},
+			wantErr: ErrOIDCNoAccount,
+		},
+		{
+			name: "unverified email isn't provisioned onto the account",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email"}
+				cfg.AutoProvision = true
+			},
+			users:    []models.User{{Username: "alice", Role: models.RoleUser, Email: "alice@example.com"}},
+			claims:   map[string]interface{}{"preferred_username": "mallory", "email": "alice@example.com", "email_verified": true},
+			wantUser: "mallory",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "links username",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.LinkBy = []string{"email", "username"}
+			},
+			users:    []models.User{{Username: "alice", Role: models.RoleUser}},
+			claims:   map[string]interface{}{"preferred_username": "alice"},
+			wantUser: "alice",
+			wantRole: models.RoleUser,
+		},
+		{
+			name:    "no account without provisioning",
+			claims:  map[string]interface{}{"preferred_username": "bob"},
+			wantErr: ErrOIDCNoAccount,
+		},
+		{
+			name: "provisions with default role",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.AutoProvision = true
+			},
+			claims:   map[string]interface{}{"email": "bob@example.com", "email_verified": true, "groups": []string{"traders"}},
+			wantUser: "bob",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "provisions with mapped role",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.AutoProvision = true
+			},
+			claims:   map[string]interface{}{"preferred_username": "bob", "groups": []string{"traders", "admins"}},
+			wantUser: "bob",
+			wantRole: models.RoleAdmin,
+		},
+		{
+			name: "provisioning doesn't take an existing username",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.AutoProvision = true
+			},
+			users:    []models.User{{Username: "bob", Role: models.RoleUser}},
+			claims:   map[string]interface{}{"preferred_username": "bob"},
+			wantText: "already taken",
+		},
+		{
+			name:             "maps role of linked user",
+			users:            []models.User{{Username: "alice", Role: models.RoleUser, TokenVersion: 1}},
+			linked:           "alice",
+			claims:           map[string]interface{}{"groups": []string{"admins"}},
+			wantUser:         "alice",
+			wantRole:         models.RoleAdmin,
+			wantTokenVersion: 2,
+		},
+		{
+			name:     "maps groups sent as a string",
+			users:    []models.User{{Username: "alice", Role: models.RoleUser}},
+			linked:   "alice",
+			claims:   map[string]interface{}{"groups": "traders admins"},
+			wantUser: "alice",
+			wantRole: models.RoleAdmin,
+		},
+		{
+			name:             "unmapped groups keep the role",
+			users:            []models.User{{Username: "alice", Role: models.RoleAdmin, TokenVersion: 1}},
+			linked:           "alice",
+			claims:           map[string]interface{}{"groups": []string{"traders"}},
+			wantUser:         "alice",
+			wantRole:         models.RoleAdmin,
+			wantTokenVersion: 1,
+		},
+		{
+			name: "mapped role must exist",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.RoleMapping = []config.OIDCRoleMapping{{Group: "admins", Role: "superuser"}}
+			},
+			users:    []models.User{{Username: "alice", Role: models.RoleUser}},
+			linked:   "alice",
+			claims:   map[string]interface{}{"groups": []string{"admins"}},
+			wantText: `role "superuser" does not exist`,
+		},
+		{
+			name:     "nonce mismatch",
+			users:    []models.User{{Username: "alice", Role: models.RoleUser}},
+			linked:   "alice",
+			nonce:    "replayed",
+			wantText: "nonce mismatch",
+		},
+		{
+			name:         "blocked login changes nothing",
+			users:        []models.User{{Username: "alice", Role: models.RoleUser, TokenVersion: 1}},
+			linked:       "alice",
+			claims:       map[string]interface{}{"groups": []string{"admins"}},
+			checkAllowed: func(string) error { return errBlocked },
+			wantErr:      errBlocked,
+		},
+		{
+			name: "blocked login provisions nothing",
+			configure: func(cfg *config.OIDCConfig) {
+				cfg.AutoProvision = true
+			},
+			claims:       map[string]interface{}{"preferred_username": "bob"},
+			checkAllowed: func(string) error { return errBlocked },
+			wantErr:      errBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, idp, db := newOIDCTestService(t, tt.configure)
+
+			for i := range tt.users {
+				if err := db.Create(&tt.users[i]).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.linked != "" {
+				var user models.User
+				if err := db.Where("username = ?", tt.linked).First(&user).Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Create(&models.UserIdentity{UserID: user.ID, Issuer: idp.URL, Subject: "subject-1"}).Error; err != nil {
+					t.Fatal(err)
+				}
+				if tt.deleteLinked {
+					if err := db.Delete(&user).Error; err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			var usersBefore, identitiesBefore int64
+			db.Unscoped().Model(&models.User{}).Count(&usersBefore)
+			db.Model(&models.UserIdentity{}).Count(&identitiesBefore)
+
+			checkAllowed := tt.checkAllowed
+			if checkAllowed == nil {
+				checkAllowed = allowLogin
+			}
+			got, err := idp.login(s, func(nonce string) jwt.MapClaims {
+				if tt.nonce != "" {
+					nonce = tt.nonce
+				}
+				claims := idp.identityClaims("subject-1", nonce)
+				for name, value := range tt.claims {
+					claims[name] = value
+				}
+				return claims
+			}, checkAllowed)
+
+			if tt.wantErr != nil || tt.wantText != "" {
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CompleteLogin() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantText != "" && (err == nil || !strings.Contains(err.Error(), tt.wantText)) {
+					t.Fatalf("CompleteLogin() error = %v, want %q", err, tt.wantText)
+				}
+
+				// A refused login links, provisions and re-roles nobody
+				var usersAfter, identitiesAfter int64
+				db.Unscoped().Model(&models.User{}).Count(&usersAfter)
+				db.Model(&models.UserIdentity{}).Count(&identitiesAfter)
+				if usersAfter != usersBefore || identitiesAfter != identitiesBefore {
+					t.Errorf("users %d -> %d, identities %d -> %d, want no change", usersBefore, usersAfter, identitiesBefore, identitiesAfter)
+				}
+				for _, user := range tt.users {
+					var stored models.User
+					db.Unscoped().First(&stored, user.ID)
+					if stored.Role != user.Role || stored.TokenVersion != user.TokenVersion {
+						t.Errorf("%s has role %q and token version %d, want %q and %d", user.Username, stored.Role, stored.TokenVersion, user.Role, user.TokenVersion)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+
+			if got.Username != tt.wantUser {
+				t.Errorf("user = %q, want %q", got.Username, tt.wantUser)
+			}
+			var stored models.User
+			if err := db.First(&stored, got.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", stored.Role, tt.wantRole)
+			}
+			if tt.wantTokenVersion != 0 && stored.TokenVersion != tt.wantTokenVersion {
+				t.Errorf("token version = %d, want %d", stored.TokenVersion, tt.wantTokenVersion)
+			}
+
+			var identity models.UserIdentity
+			if err := db.Where("issuer = ? AND subject = ?", idp.URL, "subject-1").First(&identity).Error; err != nil {
+				t.Fatalf("identity isn't linked: %v", err)
+			}
+			if identity.UserID != got.ID {
+				t.Errorf("identity is linked to user %d, want %d", identity.UserID, got.ID)
+			}
+		})
+	}
+}
+
+func TestOIDCCompleteLoginProvisionsVerifiedEmail(t *testing.T) {
+	s, idp, db := newOIDCTestService(t, func(cfg *config.OIDCConfig) {
+		cfg.AutoProvision = true
+	})
+	verifiedAt := time.Now()
+	if err := db.Create(&models.User{Username: "alice", Email: "taken@example.com", EmailVerifiedAt: &verifiedAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		subject   string
+		email     string
+		verified  bool
+		wantEmail string
+	}{
+		{subject: "subject-1", email: "bob@example.com", verified: true, wantEmail: "bob@example.com"},
+		{subject: "subject-2", email: "carol@example.com", verified: false, wantEmail: ""},
+		{subject: "subject-3", email: "taken@example.com", verified: true, wantEmail: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			user, err := idp.login(s, func(nonce string) jwt.MapClaims {
+				claims := idp.identityClaims(tt.subject, nonce)
+				claims["preferred_username"] = tt.subject
+				claims["email"] = tt.email
+				claims["email_verified"] = tt.verified
+				return claims
+			}, allowLogin)
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+			if user.Email != tt.wantEmail || user.HasVerifiedEmail() != (tt.wantEmail != "") {
+				t.Errorf("email = %q (verified %v), want %q", user.Email, user.HasVerifiedEmail(), tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestOIDCCompleteLoginState(t *testing.T) {
+	s, idp, db := newOIDCTestService(t, nil)
+	if err := db.Create(&models.User{Username: "alice", Role: models.RoleUser}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var user models.User
+	db.First(&user)
+	if err := db.Create(&models.UserIdentity{UserID: user.ID, Issuer: idp.URL, Subject: "subject-1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := s.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	idp.claims = idp.identityClaims("subject-1", query.Get("nonce"))
+
+	if _, err := s.CompleteLogin(Actor{}, "code", "forged", allowLogin); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("CompleteLogin() with an unknown state error = %v, want %v", err, ErrOIDCInvalidState)
+	}
+	if _, err := s.CompleteLogin(Actor{}, "code", query.Get("state"), allowLogin); err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if _, err := s.CompleteLogin(Actor{}, "code", query.Get("state"), allowLogin); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("CompleteLogin() with a used state error = %v, want %v", err, ErrOIDCInvalidState)
+	}
+}
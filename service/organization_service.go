@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrganizationNotFound is returned when an organization does not exist
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrganizationAccessDenied is returned when a user's role is too low for an action
+	ErrOrganizationAccessDenied = errors.New("access denied")
+)
+
+// OrganizationDashboard aggregates the latest state of an organization's accounts.
+// Per-account rows are only included for analysts and above.
+type OrganizationDashboard struct {
+	Organization models.Organization        `json:"organization"`
+	AccountCount int                        `json:"account_count"`
+	MemberCount  int64                      `json:"member_count"`
+	TotalBalance float64                    `json:"total_balance"`
+	TodayPL      float64                    `json:"today_pl"`
+	Accounts     []OrganizationAccountState `json:"accounts,omitempty"`
+}
+
+// OrganizationAccountState is the latest state of one account on an organization dashboard
+type OrganizationAccountState struct {
+	AccountID    uint       `json:"account_id"`
+	Name         string     `json:"name"`
+	Balance      float64    `json:"balance"`
+	DailyPL      float64    `json:"daily_pl"`
+	LatestUpdate *time.Time `json:"latest_update"`
+}
+
+type OrganizationService struct {
+	orgRepo        *repository.OrganizationRepository
+	accountRepo    *repository.AccountRepository
+	userRepo       *repository.UserRepository
+	statisticRepo  *repository.StatisticRepository
+	accountService *AccountService
+	auditService   *AuditService
+}
+
+func NewOrganizationService(db *gorm.DB) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:        repository.NewOrganizationRepository(db),
+		accountRepo:    repository.NewAccountRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		statisticRepo:  repository.NewStatisticRepository(db),
+		accountService: NewAccountService(db),
+		auditService:   NewAuditService(db),
+	}
+}
+
+// CreateOrganization creates an organization owned by the given user
+func (s *OrganizationService) CreateOrganization(name string, ownerID uint) (*models.Organization, error) {
+	org := &models.Organization{Name: name}
+	if err := s.orgRepo.Create(org, ownerID); err != nil {
+		return nil, err
+	}
+	org.Role = models.OrgRoleOwner
+	return org, nil
+}
+
+// GetOrganizationsForUser retrieves the organizations a user belongs to, with their role
+func (s *OrganizationService) GetOrganizationsForUser(userID uint) ([]models.Organization, error) {
+	return s.orgRepo.FindByUserID(userID)
+}
+
+// GetAllOrganizations retrieves all organizations
+func (s *OrganizationService) GetAllOrganizations() ([]models.Organization, error) {
+	return s.orgRepo.FindAll()
+}
+
+// CheckRole loads an organization and verifies the user has at least the given role in it.
+// Users allowed to manage all organizations act as owners of every organization.
+func (s *OrganizationService) CheckRole(orgID, userID uint, manageAll bool, minRole string) (*models.Organization, error) {
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	if manageAll {
+		org.Role = models.OrgRoleOwner
+		return org, nil
+	}
+
+	member, err := s.orgRepo.FindMember(orgID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationAccessDenied
+	}
+	if err != nil {
+		return nil, err
+	}
+	if models.OrgRoleRank(member.Role) < models.OrgRoleRank(minRole) {
+		return nil, ErrOrganizationAccessDenied
+	}
+
+	org.Role = member.Role
+	return org, nil
+}
+
+// UpdateOrganization renames an organization
+func (s *OrganizationService) UpdateOrganization(org *models.Organization, name string) (*models.Organization, error) {
+	org.Name = name
+	if err := s.orgRepo.Update(org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// UpdateQuotas sets the account and member limits of an organization; 0 means unlimited
+func (s *OrganizationService) UpdateQuotas(org *models.Organization, maxAccounts, maxMembers int) (*models.Organization, error) {
+	org.MaxAccounts = maxAccounts
+	org.MaxMembers = maxMembers
+	if err := s.orgRepo.Update(org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization that no longer has accounts
+func (s *OrganizationService) DeleteOrganization(orgID uint) error {
+	count, err := s.accountRepo.CountByOrganizationID(orgID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("delete or move the organization's accounts first")
+	}
+	return s.orgRepo.Delete(orgID)
+}
+
+// ListMembers retrieves the members of an organization
+func (s *OrganizationService) ListMembers(orgID uint) ([]models.OrganizationMember, error) {
+	return s.orgRepo.FindMembers(orgID)
+}
+
+// SetMember adds a user to an organization or changes their role. Only owners
+// may grant or take away the owner role.
+func (s *OrganizationService) SetMember(actor Actor, org *models.Organization, username, role string) (*models.OrganizationMember, error) {
+	if models.OrgRoleRank(role) == 0 {
+		return nil, errors.New("role must be owner, manager, analyst or viewer")
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	existing, err := s.orgRepo.FindMember(org.ID, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if org.Role != models.OrgRoleOwner && (role == models.OrgRoleOwner || (existing != nil && existing.Role == models.OrgRoleOwner)) {
+		return nil, ErrOrganizationAccessDenied
+	}
+
+	if existing == nil && org.MaxMembers > 0 {
+		count, err := s.orgRepo.CountMembers(org.ID, "")
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(org.MaxMembers) {
+			return nil, errors.New("organization member quota reached")
+		}
+	}
+
+	if existing != nil && existing.Role == models.OrgRoleOwner && role != models.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(org.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         user.ID,
+		Role:           role,
+	}
+	if err := s.orgRepo.UpsertMember(member); err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		s.auditService.Record(actor, models.AuditOrgMemberAdd, models.AuditTargetOrganizationMember, member.ID, nil, member)
+	} else {
+		s.auditService.Record(actor, models.AuditOrgMemberUpdate, models.AuditTargetOrganizationMember, member.ID, existing, member)
+	}
+
+	member.User = *user
+	return member, nil
+}
+
+// RemoveMember removes a user from an organization. Only owners may remove owners,
+// and the last owner can't be removed.
+func (s *OrganizationService) RemoveMember(actor Actor, org *models.Organization, userID uint) error {
+	member, err := s.orgRepo.FindMember(org.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == models.OrgRoleOwner {
+		if org.Role != models.OrgRoleOwner {
+			return ErrOrganizationAccessDenied
+		}
+		if err := s.ensureAnotherOwner(org.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgRepo.DeleteMember(org.ID, userID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditOrgMemberRemove, models.AuditTargetOrganizationMember, member.ID, member, nil)
+	return nil
+}
+
+// ListAccounts retrieves the accounts of an organization
+func (s *OrganizationService) ListAccounts(orgID uint) ([]models.Account, error) {
+	return s.accountRepo.FindByOrganizationID(orgID)
+}
+
+// CreateAccount creates an account in an organization within its account quota
+func (s *OrganizationService) CreateAccount(actor Actor, org *models.Organization, userID uint, name string) (*models.Account, error) {
+	if org.MaxAccounts > 0 {
+		count, err := s.accountRepo.CountByOrganizationID(org.ID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(org.MaxAccounts) {
+			return nil, errors.New("organization account quota reached")
+		}
+	}
+
+	return s.accountService.CreateAccount(actor, userID, name, &org.ID)
+}
+
+// GetDashboard aggregates the latest statistics of an organization's accounts
+func (s *OrganizationService) GetDashboard(org *models.Organization) (*OrganizationDashboard, error) {
+	accounts, err := s.accountRepo.FindByOrganizationID(org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberCount, err := s.orgRepo.CountMembers(org.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	accountIDs := make([]uint, 0, len(accounts))
+	for _, account := range accounts {
+		accountIDs = append(accountIDs, account.ID)
+	}
+
+	latest, err := s.statisticRepo.FindLatestByAccountIDs(accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	latestByAccount := make(map[uint]models.Statistic, len(latest))
+	for _, stat := range latest {
+		latestByAccount[stat.AccountID] = stat
+	}
+
+	dashboard := &OrganizationDashboard{
+		Organization: *org,
+		AccountCount: len(accounts),
+		MemberCount:  memberCount,
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	showAccounts := models.OrgRoleRank(org.Role) >= models.OrgRoleRank(models.OrgRoleAnalyst)
+
+	for _, account := range accounts {
+		state := OrganizationAccountState{AccountID: account.ID, Name: account.Name}
+		if stat, ok := latestByAccount[account.ID]; ok {
+			state.Balance = stat.TotalBalance
+			state.LatestUpdate = &stat.Timestamp
+			// A daily P/L reported on an earlier day is not today's
+			if !stat.Timestamp.Before(today) {
+				state.DailyPL = stat.DailyPL
+			}
+		}
+
+		dashboard.TotalBalance += state.Balance
+		dashboard.TodayPL += state.DailyPL
+		if showAccounts {
+			dashboard.Accounts = append(dashboard.Accounts, state)
+		}
+	}
+
+	return dashboard, nil
+}
+
+// ensureAnotherOwner refuses changes that would leave an organization without owners
+func (s *OrganizationService) ensureAnotherOwner(orgID uint) error {
+	owners, err := s.orgRepo.CountMembers(orgID, models.OrgRoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errors.New("an organization needs at least one owner")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"unicode/utf8"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// bcryptMaxPasswordBytes is the most bcrypt hashes; longer passwords are rejected rather than truncated
+	bcryptMaxPasswordBytes = 72
+	// argon2MaxPasswordBytes bounds the work of hashing attacker-chosen passwords
+	argon2MaxPasswordBytes = 256
+)
+
+// ErrPasswordReused is returned when a new password matches one of the user's recent passwords
+var ErrPasswordReused = errors.New("password was used recently, choose a different one")
+
+// dummyHash is a hash of a random password made with the configured algorithm and cost.
+// Logins naming an unknown user are checked against it, so they take as long to refuse
+// as a wrong password and timing doesn't reveal which usernames exist.
+var dummyHash struct {
+	once sync.Once
+	hash string
+}
+
+// PasswordPolicy is the part of the password policy shown to users choosing a password
+type PasswordPolicy struct {
+	MinLength    int `json:"min_length"`
+	MaxLength    int `json:"max_length"`
+	HistoryCount int `json:"history_count"`
+}
+
+// PasswordPolicyService enforces the configured password policy and hashes passwords
+// with the configured algorithm and cost
+type PasswordPolicyService struct {
+	cfg         *config.PasswordConfig
+	historyRepo *repository.PasswordHistoryRepository
+}
+
+func NewPasswordPolicyService(db *gorm.DB) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		cfg:         &config.AppConfig.Password,
+		historyRepo: repository.NewPasswordHistoryRepository(db),
+	}
+}
+
+// Policy returns the password policy
+func (s *PasswordPolicyService) Policy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    s.cfg.MinLength,
+		MaxLength:    s.maxBytes(),
+		HistoryCount: s.cfg.HistoryCount,
+	}
+}
+
+// Validate checks a new password's length and that it isn't a common password
+func (s *PasswordPolicyService) Validate(password string) error {
+	if utf8.RuneCountInString(password) < s.cfg.MinLength {
+		return fmt.Errorf("password must be at least %d characters", s.cfg.MinLength)
+	}
+	if len(password) > s.maxBytes() {
+		return fmt.Errorf("password must be at most %d bytes", s.maxBytes())
+	}
+	if s.cfg.CheckCommon && utils.IsCommonPassword(password) {
+		return errors.New("password is too common, choose a less predictable one")
+	}
+	return nil
+}
+
+// CheckReuse rejects a new password that matches the user's current password or one of
+// their recent ones
+func (s *PasswordPolicyService) CheckReuse(user *models.User, password string) error {
+	if s.cfg.HistoryCount <= 0 {
+		return nil
+	}
+
+	if utils.CheckPassword(user.PasswordHash, password) == nil {
+		return ErrPasswordReused
+	}
+	if s.cfg.HistoryCount == 1 {
+		return nil
+	}
+
+	previous, err := s.historyRepo.FindRecent(user.ID, s.cfg.HistoryCount-1)
+	if err != nil {
+		return err
+	}
+	for _, entry := range previous {
+		if utils.CheckPassword(entry.PasswordHash, password) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// Remember records the password a user is replacing, keeping as many as the policy checks
+func (s *PasswordPolicyService) Remember(userID uint, previousHash string) error {
+	keep := s.cfg.HistoryCount - 1
+	if keep > 0 {
+		if err := s.historyRepo.Create(&models.PasswordHistory{UserID: userID, PasswordHash: previousHash}); err != nil {
+			return err
+		}
+	}
+	return s.historyRepo.Prune(userID, keep)
+}
+
+// Hash hashes a password with the configured algorithm and cost
+func (s *PasswordPolicyService) Hash(password string) (string, error) {
+	return utils.HashPassword(password, s.hashParams())
+}
+
+// DummyHash returns the hash unknown usernames are checked against, computing it on first use
+func (s *PasswordPolicyService) DummyHash() string {
+	dummyHash.once.Do(func() {
+		secret, err := utils.GenerateSecureToken(32)
+		if err == nil {
+			dummyHash.hash, err = s.Hash(secret)
+		}
+		if err != nil {
+			log.Printf("Failed to create the dummy password hash: %v", err)
+		}
+	})
+	return dummyHash.hash
+}
+
+// NeedsRehash reports whether a stored hash is weaker than the configured algorithm and cost
+func (s *PasswordPolicyService) NeedsRehash(hashedPassword string) bool {
+	return utils.PasswordNeedsRehash(hashedPassword, s.hashParams())
+}
+
+// hashParams converts the configuration to hash parameters
+func (s *PasswordPolicyService) hashParams() utils.PasswordHashParams {
+	return utils.PasswordHashParams{
+		Algorithm:         s.cfg.HashAlgorithm,
+		BcryptCost:        s.cfg.BcryptCost,
+		Argon2Memory:      uint32(s.cfg.Argon2Memory),
+		Argon2Iterations:  uint32(s.cfg.Argon2Iterations),
+		Argon2Parallelism: uint8(s.cfg.Argon2Parallelism),
+	}
+}
+
+// maxBytes is the longest password the configured algorithm accepts
+func (s *PasswordPolicyService) maxBytes() int {
+	if s.cfg.HashAlgorithm == utils.PasswordHashArgon2id {
+		return argon2MaxPasswordBytes
+	}
+	return bcryptMaxPasswordBytes
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// passwordResetTTL is how long an admin-issued password reset stays valid
+const passwordResetTTL = 24 * time.Hour
+
+type PasswordService struct {
+	userRepo       *repository.UserRepository
+	resetRepo      *repository.PasswordResetRepository
+	sessionService *SessionService
+	emailService   *EmailService
+	passwordPolicy *PasswordPolicyService
+	roleService    *RoleService
+	auditService   *AuditService
+}
+
+func NewPasswordService(db *gorm.DB) *PasswordService {
+	return &PasswordService{
+		userRepo:       repository.NewUserRepository(db),
+		resetRepo:      repository.NewPasswordResetRepository(db),
+		sessionService: NewSessionService(db),
+		emailService:   NewEmailService(db),
+		passwordPolicy: NewPasswordPolicyService(db),
+		roleService:    NewRoleService(db),
+		auditService:   NewAuditService(db),
+	}
+}
+
+// PasswordResetTicket is a newly issued password reset; the token is only available here
+type PasswordResetTicket struct {
+	Token     string    `json:"token"`
+	Link      string    `json:"link"`
+	ExpiresAt time.Time `json:"expires_at"`
+	EmailSent bool      `json:"email_sent"`
+}
+
+// ChangePassword changes a user's own password, revoking every other session and
+// returning fresh tokens for the current one
+func (s *PasswordService) ChangePassword(actor Actor, userID uint, sessionID, currentPassword, newPassword string) (*TokenPair, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := utils.CheckPassword(user.PasswordHash, currentPassword); err != nil {
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if currentPassword == newPassword {
+		return nil, errors.New("new password must be different from the current password")
+	}
+	if err := s.checkNewPassword(user, newPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.setPassword(user, newPassword, sessionID); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditPasswordChange, models.AuditTargetUser, user.ID, nil, nil)
+
+	return s.sessionService.ReissueSession(user, sessionID)
+}
+
+// CreateReset issues a single-use reset token for a user and emails the link if possible
+func (s *PasswordService) CreateReset(actor Actor, userID uint) (*PasswordResetTicket, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	// The ticket hands the account to the actor, who must hold every permission it has
+	if err := s.roleService.CheckRoleGrantable(actor, user.Role); err != nil {
+		return nil, err
+	}
+
+	// Only the most recent reset stays valid
+	if err := s.resetRepo.DeleteUnusedByUserID(user.ID); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	reset := &models.PasswordReset{
+		UserID:      user.ID,
+		CreatedByID: actor.UserID,
+		TokenHash:   utils.HashToken(token),
+		ExpiresAt:   time.Now().Add(passwordResetTTL),
+	}
+	if err := s.resetRepo.Create(reset); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditUserPasswordReset, models.AuditTargetUser, user.ID, nil, map[string]interface{}{"expires_at": reset.ExpiresAt})
+
+	ticket := &PasswordResetTicket{
+		Token:     token,
+		Link:      config.AppConfig.Server.BaseURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresAt: reset.ExpiresAt,
+	}
+
+	if user.HasVerifiedEmail() {
+		err := s.emailService.Send(user.Email, "password_reset", map[string]interface{}{
+			"Username":  user.Username,
+			"Link":      ticket.Link,
+			"ExpiresAt": ticket.ExpiresAt,
+		})
+		ticket.EmailSent = err == nil
+	}
+
+	return ticket, nil
+}
+
+// ResetPassword consumes a reset token and sets a new password, revoking all sessions
+func (s *PasswordService) ResetPassword(actor Actor, token, newPassword string) error {
+	reset, err := s.resetRepo.FindByTokenHash(utils.HashToken(token))
+	if err != nil {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.FindByID(reset.UserID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	// A rejected password leaves the token usable for another attempt
+	if err := s.checkNewPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	claimed, err := s.resetRepo.MarkUsed(reset.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.setPassword(user, newPassword, ""); err != nil {
+		return err
+	}
+	// Holding the reset token authenticates the user
+	s.auditService.Record(actor.WithUser(user), models.AuditPasswordReset, models.AuditTargetUser, user.ID, nil, nil)
+	return nil
+}
+
+// checkNewPassword applies the password policy to a password the user chose
+func (s *PasswordService) checkNewPassword(user *models.User, password string) error {
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return err
+	}
+	return s.passwordPolicy.CheckReuse(user, password)
+}
+
+// setPassword stores a new password hash, remembering the old one, and invalidates every
+// session except keepSessionID
+func (s *PasswordService) setPassword(user *models.User, password, keepSessionID string) error {
+	hashedPassword, err := s.passwordPolicy.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Remember(user.ID, user.PasswordHash); err != nil {
+		return err
+	}
+
+	user.PasswordHash = hashedPassword
+	user.MustChangePassword = false
+	user.TokenVersion++
+
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.sessionService.InvalidateUser(user.ID, keepSessionID)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// MaxPersonalAccessTokenLifetime is the longest a personal access token may be valid for
+const MaxPersonalAccessTokenLifetime = 366 * 24 * time.Hour
+
+// ErrPersonalAccessTokenInvalid is returned for unknown, expired or orphaned personal access tokens
+var ErrPersonalAccessTokenInvalid = errors.New("invalid or expired personal access token")
+
+type PersonalAccessTokenService struct {
+	tokenRepo    *repository.PersonalAccessTokenRepository
+	roleService  *RoleService
+	auditService *AuditService
+}
+
+func NewPersonalAccessTokenService(db *gorm.DB) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		tokenRepo:    repository.NewPersonalAccessTokenRepository(db),
+		roleService:  NewRoleService(db),
+		auditService: NewAuditService(db),
+	}
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token rather than a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, models.PersonalAccessTokenPrefix)
+}
+
+// ListTokens retrieves all personal access tokens of a user
+func (s *PersonalAccessTokenService) ListTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	return s.tokenRepo.FindByUserID(userID)
+}
+
+// CreateToken issues a personal access token limited to scopes the user's role grants.
+// The returned token carries the plaintext secret, which cannot be retrieved again afterwards.
+func (s *PersonalAccessTokenService) CreateToken(actor Actor, user *models.User, name string, scopes []string, expiresAt time.Time) (*models.PersonalAccessToken, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	granted, err := s.roleService.Permissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if !models.IsPermission(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !granted.Has(scope) {
+			return nil, fmt.Errorf("your role does not grant %q", scope)
+		}
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+	if expiresAt.After(time.Now().Add(MaxPersonalAccessTokenLifetime)) {
+		return nil, errors.New("expiry must be within one year")
+	}
+
+	token := &models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      name,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := s.issue(token); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditPersonalTokenCreate, models.AuditTargetPersonalAccessToken, token.ID, nil, token)
+
+	return token, nil
+}
+
+// RevokeToken deletes a personal access token of a user immediately
+func (s *PersonalAccessTokenService) RevokeToken(actor Actor, userID, tokenID uint) error {
+	if err := s.tokenRepo.Delete(userID, tokenID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditPersonalTokenRevoke, models.AuditTargetPersonalAccessToken, tokenID, nil, nil)
+	return nil
+}
+
+// Authenticate resolves a plaintext token used from an IP to the token, with its owner, and
+// the permissions the request gets: the token's scopes the owner's role still grants
+func (s *PersonalAccessTokenService) Authenticate(plain, ip string) (*models.PersonalAccessToken, models.PermissionSet, error) {
+	token, err := s.tokenRepo.FindByHash(utils.HashToken(plain))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrPersonalAccessTokenInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if token.IsExpired() || token.User.ID == 0 {
+		return nil, nil, ErrPersonalAccessTokenInvalid
+	}
+	if err := checkUserActive(&token.User); err != nil {
+		return nil, nil, err
+	}
+
+	granted, err := s.roleService.Permissions(token.User.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > tokenTouchInterval || token.LastUsedIP != ip {
+		// Usage tracking must not fail the request
+		_ = s.tokenRepo.Touch(token.ID, ip)
+	}
+
+	return token, granted.Intersect(token.Scopes), nil
+}
+
+// issue generates a unique secret for a token and stores it
+func (s *PersonalAccessTokenService) issue(token *models.PersonalAccessToken) error {
+	maxAttempts := 5
+	for i := 0; i < maxAttempts; i++ {
+		secret, err := utils.GenerateSecureToken(32)
+		if err != nil {
+			return err
+		}
+
+		plain := models.PersonalAccessTokenPrefix + secret
+		hash := utils.HashToken(plain)
+		exists, err := s.tokenRepo.HashExists(hash)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			token.TokenHash = hash
+			token.TokenPrefix = plain[:len(models.PersonalAccessTokenPrefix)+models.APITokenPrefixLength]
+			if err := s.tokenRepo.Create(token); err != nil {
+				return err
+			}
+			token.Token = plain
+			return nil
+		}
+	}
+
+	return errors.New("failed to generate unique token")
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"x-track/events"
+	"x-track/models"
+	"x-track/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleNotFound is returned when a role does not exist
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrPermissionNotHeld is returned when an actor tries to grant a permission they don't hold
+var ErrPermissionNotHeld = errors.New("you can't grant a permission you don't hold")
+
+// ErrOwnRole is returned when an actor tries to change or delete the role they hold
+var ErrOwnRole = errors.New("you can't change or delete your own role")
+
+// EffectivePermissions is a user's role with the permissions it grants
+type EffectivePermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,19}$`)
+
+// Recently resolved custom roles, so each request does not hit the database
+var permissionCache = newTTLCache[string, models.PermissionSet](authCacheTTL)
+
+type RoleService struct {
+	roleRepo       *repository.RoleRepository
+	userRepo       *repository.UserRepository
+	sessionService *SessionService
+	auditService   *AuditService
+}
+
+func NewRoleService(db *gorm.DB) *RoleService {
+	return &RoleService{
+		roleRepo:       repository.NewRoleRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		sessionService: NewSessionService(db),
+		auditService:   NewAuditService(db),
+	}
+}
+
+// Permissions resolves the effective permissions of a role. Unknown roles, e.g. a custom
+// role that was deleted, have no permissions.
+func (s *RoleService) Permissions(roleName string) (models.PermissionSet, error) {
+	if permissions, ok := permissionCache.Get(roleName); ok {
+		return permissions, nil
+	}
+
+	role, err := s.GetRole(roleName)
+	if errors.Is(err, ErrRoleNotFound) {
+		role, err = &models.Role{Name: roleName}, nil
+	}
+	if err != nil {
+		// Don't cache transient database errors
+		return nil, err
+	}
+
+	permissions := models.NewPermissionSet(role.Permissions)
+	permissionCache.Set(roleName, permissions)
+	return permissions, nil
+}
+
+// GetRole retrieves a built-in or custom role by name
+func (s *RoleService) GetRole(name string) (*models.Role, error) {
+	if role := builtInRole(name); role != nil {
+		return role, nil
+	}
+
+	role, err := s.roleRepo.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+// RoleExists checks if a role can be assigned to a user
+func (s *RoleService) RoleExists(name string) (bool, error) {
+	_, err := s.GetRole(name)
+	if errors.Is(err, ErrRoleNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RolesWithPermission lists the built-in and custom roles that grant a permission
+func (s *RoleService) RolesWithPermission(permission string) ([]string, error) {
+	roles, err := s.GetAllRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, role := range roles {
+		if models.NewPermissionSet(role.Permissions).Has(permission) {
+			names = append(names, role.Name)
+		}
+	}
+	return names, nil
+}
+
+// GetAllRoles retrieves the built-in roles followed by the custom roles
+func (s *RoleService) GetAllRoles() ([]models.Role, error) {
+	custom, err := s.roleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]models.Role{}, models.BuiltInRoles...), custom...), nil
+}
+
+// CheckGrantable refuses permissions the actor doesn't hold, so managing users, roles
+// or invites never widens anyone's access beyond the actor's own
+func (s *RoleService) CheckGrantable(actor Actor, permissions []string) error {
+	for _, permission := range permissions {
+		if !actor.Permissions.Has(permission) {
+			return fmt.Errorf("%w: %s", ErrPermissionNotHeld, permission)
+		}
+	}
+	return nil
+}
+
+// CheckRoleGrantable refuses roles with permissions the actor doesn't hold
+func (s *RoleService) CheckRoleGrantable(actor Actor, roleName string) error {
+	permissions, err := s.Permissions(roleName)
+	if err != nil {
+		return err
+	}
+	return s.CheckGrantable(actor, permissions.List())
+}
+
+// CreateRole creates a custom role
+func (s *RoleService) CreateRole(actor Actor, name, description string, permissions []string) (*models.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("role name must be 2-20 lowercase letters, digits, '-' or '_' and start with a letter")
+	}
+	if exists, err := s.RoleExists(name); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errors.New("role already exists")
+	}
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+	if err := s.CheckGrantable(actor, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditRoleCreate, models.AuditTargetRole, role.ID, nil, role)
+	publishRoleChanged(name)
+
+	return role, nil
+}
+
+// UpdateRole replaces the description and permissions of a custom role. The actor must
+// hold both the role's current and new permissions, and can't change their own role.
+// Access tokens of the role's users are rejected, so the change applies to them on
+// every instance once they refresh.
+func (s *RoleService) UpdateRole(actor Actor, name, description string, permissions []string) (*models.Role, error) {
+	if builtInRole(name) != nil {
+		return nil, errors.New("built-in roles can't be changed")
+	}
+	role, err := s.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+	if err := s.checkRoleModifiable(actor, role); err != nil {
+		return nil, err
+	}
+	if err := s.CheckGrantable(actor, permissions); err != nil {
+		return nil, err
+	}
+
+	before := *role
+	role.Description = description
+	role.Permissions = permissions
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditRoleUpdate, models.AuditTargetRole, role.ID, &before, role)
+	publishRoleChanged(name)
+
+	userIDs, err := s.userRepo.BumpTokenVersionsByRole(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		s.sessionService.ForgetTokenVersion(userID)
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes a custom role that no user holds. As for updates, the actor must
+// hold the role's permissions and can't delete their own role.
+func (s *RoleService) DeleteRole(actor Actor, name string) error {
+	if builtInRole(name) != nil {
+		return errors.New("built-in roles can't be deleted")
+	}
+	role, err := s.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRoleModifiable(actor, role); err != nil {
+		return err
+	}
+
+	count, err := s.userRepo.CountByRole(name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("role is assigned to %d user(s)", count)
+	}
+
+	if err := s.roleRepo.Delete(role.ID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditRoleDelete, models.AuditTargetRole, role.ID, role, nil)
+	publishRoleChanged(name)
+	return nil
+}
+
+// checkRoleModifiable refuses changes to a role with permissions the actor doesn't hold,
+// which may be assigned to users who outrank them, and to the actor's own role
+func (s *RoleService) checkRoleModifiable(actor Actor, role *models.Role) error {
+	if err := s.CheckGrantable(actor, role.Permissions); err != nil {
+		return err
+	}
+	if actor.UserID == 0 {
+		return nil
+	}
+	user, err := s.userRepo.FindByID(actor.UserID)
+	if err != nil {
+		return err
+	}
+	if user.Role == role.Name {
+		return ErrOwnRole
+	}
+	return nil
+}
+
+// HandleEvent drops cached permissions of roles changed on any instance
+func (s *RoleService) HandleEvent(event events.Event) {
+	if event.Type != events.RoleChanged {
+		return
+	}
+	var name string
+	if err := json.Unmarshal(event.Payload, &name); err == nil {
+		permissionCache.Delete(name)
+	}
+}
+
+// publishRoleChanged tells every instance to forget the cached permissions of a role
+func publishRoleChanged(name string) {
+	permissionCache.Delete(name)
+
+	if err := events.Publish(events.RoleChanged, 0, name); err != nil {
+		log.Printf("Failed to publish %s event: %v", events.RoleChanged, err)
+	}
+}
+
+// builtInRole returns a copy of a built-in role, or nil if name isn't one
+func builtInRole(name string) *models.Role {
+	for _, role := range models.BuiltInRoles {
+		if role.Name == name {
+			return &role
+		}
+	}
+	return nil
+}
+
+// validatePermissions checks a custom role only holds known permissions
+func validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		if !models.IsPermission(permission) {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+	return nil
+}
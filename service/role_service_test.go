@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"x-track/config"
+	"x-track/models"
+)
+
+func TestDeleteRole(t *testing.T) {
+	config.AppConfig = &config.Config{}
+
+	tests := []struct {
+		name     string
+		role     string
+		wantErr  error
+		wantKept bool
+	}{
+		{name: "role within the actor's permissions", role: "readers"},
+		{name: "role with a permission the actor lacks", role: "auditors", wantErr: ErrPermissionNotHeld, wantKept: true},
+		{name: "actor's own role", role: "ops", wantErr: ErrOwnRole, wantKept: true},
+		{name: "unknown role", role: "nobody", wantErr: ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.User{}, &models.Role{}, &models.AuditEvent{})
+			roles := []models.Role{
+				{Name: "ops", Permissions: []string{models.PermRolesManage, models.PermUsersRead}},
+				{Name: "readers", Permissions: []string{models.PermUsersRead}},
+				{Name: "auditors", Permissions: []string{models.PermAuditRead}},
+			}
+			if err := db.Create(&roles).Error; err != nil {
+				t.Fatal(err)
+			}
+			user := &models.User{Username: "operator", Role: "ops"}
+			if err := db.Create(user).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewRoleService(db)
+			actor := Actor{UserID: user.ID, Username: user.Username, Permissions: models.NewPermissionSet(roles[0].Permissions)}
+			err := s.DeleteRole(actor, tt.role)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteRole() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var count int64
+			db.Model(&models.Role{}).Where("name = ?", tt.role).Count(&count)
+			if kept := count > 0; kept != tt.wantKept {
+				t.Errorf("role kept = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+	"x-track/config"
+	"x-track/events"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenReused is returned when a rotated refresh token is presented again
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+
+// TokenPair is an access token together with the refresh token that renews it
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Recent lookups, so each request does not hit the database
+var (
+	sessionCache      = newTTLCache[string, cachedSession](authCacheTTL)
+	tokenVersionCache = newTTLCache[uint, int](authCacheTTL)
+)
+
+type SessionService struct {
+	db                *gorm.DB
+	sessionRepo       *repository.SessionRepository
+	userRepo          *repository.UserRepository
+	settingService    *SettingService
+	signingKeyService *SigningKeyService
+	auditService      *AuditService
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{
+		db:                db,
+		sessionRepo:       repository.NewSessionRepository(db),
+		userRepo:          repository.NewUserRepository(db),
+		settingService:    NewSettingService(db),
+		signingKeyService: NewSigningKeyService(db),
+		auditService:      NewAuditService(db),
+	}
+}
+
+// CreateSession starts a new session for an authenticated user, logging them in from
+// the actor's IP and user agent
+func (s *SessionService) CreateSession(actor Actor, user *models.User) (*TokenPair, error) {
+	if err := checkUserActive(user); err != nil {
+		return nil, err
+	}
+
+	sessionID, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		IP:         actor.IP,
+		UserAgent:  truncate(actor.UserAgent, 255),
+		LastUsedAt: time.Now(),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor.WithUser(user), models.AuditLogin, models.AuditTargetUser, user.ID, nil, nil)
+
+	return s.issueTokens(user, session.ID)
+}
+
+// Refresh rotates a refresh token and issues a new token pair.
+// Presenting an already rotated token revokes the whole session.
+func (s *SessionService) Refresh(refreshToken, ip, userAgent string) (*TokenPair, *models.User, error) {
+	token, err := s.sessionRepo.FindRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, nil, errors.New("invalid refresh token")
+	}
+
+	if token.Session.IsRevoked() {
+		return nil, nil, errors.New("session has been revoked")
+	}
+
+	if token.UsedAt != nil {
+		s.revokeReused(token.SessionID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, errors.New("refresh token expired")
+	}
+
+	// Lost race against a concurrent refresh with the same token
+	claimed, err := s.sessionRepo.MarkRefreshTokenUsed(token.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claimed {
+		s.revokeReused(token.SessionID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.FindByID(token.Session.UserID)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	if err := checkUserActive(user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.sessionRepo.Touch(token.SessionID, ip, truncate(userAgent, 255)); err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.issueTokens(user, token.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pair, user, nil
+}
+
+// Revoke revokes a single session
+func (s *SessionService) Revoke(sessionID string) error {
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.publishSessionRevoked(sessionID)
+	return nil
+}
+
+// Logout revokes the actor's current session
+func (s *SessionService) Logout(actor Actor, sessionID string) error {
+	if err := s.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditLogout, models.AuditTargetUser, actor.UserID, nil, nil)
+	return nil
+}
+
+// RevokeAllForUser revokes every session of a user
+func (s *SessionService) RevokeAllForUser(actor Actor, userID uint) error {
+	if err := s.revokeUserSessions(userID, ""); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditLogoutAll, models.AuditTargetUser, userID, nil, nil)
+	return nil
+}
+
+// revokeUserSessions revokes every session of a user except keepSessionID
+func (s *SessionService) revokeUserSessions(userID uint, keepSessionID string) error {
+	ids, err := s.sessionRepo.RevokeByUserID(userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.publishSessionRevoked(id)
+	}
+	return nil
+}
+
+// InvalidateUser revokes every session of a user except keepSessionID and forgets their
+// cached token version. Callers bump the user's token version so outstanding access
+// tokens are rejected.
+func (s *SessionService) InvalidateUser(userID uint, keepSessionID string) error {
+	if err := s.revokeUserSessions(userID, keepSessionID); err != nil {
+		return err
+	}
+
+	s.ForgetTokenVersion(userID)
+	return nil
+}
+
+// ForgetTokenVersion drops a user's cached token version on every instance. Callers bump
+// the user's token version so outstanding access tokens are rejected, while their
+// sessions stay and can refresh.
+func (s *SessionService) ForgetTokenVersion(userID uint) {
+	tokenVersionCache.Delete(userID)
+	if err := events.PublishWith(s.db, events.UserSecurityChanged, 0, userID); err != nil {
+		log.Printf("Failed to publish %s event: %v", events.UserSecurityChanged, err)
+	}
+}
+
+// ReissueSession replaces the outstanding refresh token of a session and issues
+// tokens carrying the user's current token version
+func (s *SessionService) ReissueSession(user *models.User, sessionID string) (*TokenPair, error) {
+	if err := s.sessionRepo.DeleteUnusedRefreshTokens(sessionID); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, sessionID)
+}
+
+// ValidateAccess checks that an access token's session is active and that it was
+// issued for the user's current token version. Impersonation tokens also need the
+// admin's own session and token version to still be valid.
+func (s *SessionService) ValidateAccess(claims *utils.JWTClaims) error {
+	if claims.SessionID == "" {
+		return errors.New("session has been revoked")
+	}
+	session := s.lookupSession(claims.SessionID)
+	if !session.active {
+		return errors.New("session has been revoked")
+	}
+	if session.expired() {
+		return errors.New("impersonation has expired")
+	}
+
+	version, err := s.tokenVersion(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.TokenVersion != version {
+		return errors.New("token is no longer valid, please login again")
+	}
+
+	if imp := claims.Impersonator; imp != nil {
+		if !s.IsActive(imp.SessionID) {
+			return errors.New("impersonating session has been revoked")
+		}
+		version, err := s.tokenVersion(imp.UserID)
+		if err != nil {
+			return err
+		}
+		if imp.TokenVersion != version {
+			return errors.New("impersonation is no longer valid")
+		}
+	}
+	return nil
+}
+
+// tokenVersion returns the token version access tokens of a user must carry,
+// -1 for deleted, suspended and deactivated users
+func (s *SessionService) tokenVersion(userID uint) (int, error) {
+	if version, ok := tokenVersionCache.Get(userID); ok {
+		return version, nil
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// Don't cache transient database errors
+		return 0, err
+	}
+
+	version := -1
+	if err == nil && user.IsActive() {
+		version = user.TokenVersion
+	}
+	tokenVersionCache.Set(userID, version)
+	return version, nil
+}
+
+// cachedSession is what the session cache keeps of a session
+type cachedSession struct {
+	active    bool       // exists and has not been revoked
+	expiresAt *time.Time // fixed end of an impersonation session
+}
+
+// expired reports whether a session with a fixed end has ended
+func (c cachedSession) expired() bool {
+	return c.expiresAt != nil && !time.Now().Before(*c.expiresAt)
+}
+
+// IsActive reports whether a session exists, has not been revoked and has not expired
+func (s *SessionService) IsActive(sessionID string) bool {
+	session := s.lookupSession(sessionID)
+	return session.active && !session.expired()
+}
+
+// lookupSession returns the cached state of a session, loading it when not cached
+func (s *SessionService) lookupSession(sessionID string) cachedSession {
+	if cached, ok := sessionCache.Get(sessionID); ok {
+		return cached
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// Don't cache transient database errors
+		return cachedSession{}
+	}
+	cached := cachedSession{}
+	if err == nil {
+		cached = cachedSession{active: !session.IsRevoked(), expiresAt: session.ExpiresAt}
+	}
+
+	sessionCache.Set(sessionID, cached)
+	return cached
+}
+
+// HandleEvent drops cached sessions and token versions changed on any instance
+func (s *SessionService) HandleEvent(event events.Event) {
+	switch event.Type {
+	case events.SessionRevoked:
+		var sessionID string
+		if err := json.Unmarshal(event.Payload, &sessionID); err == nil {
+			sessionCache.Delete(sessionID)
+		}
+	case events.UserSecurityChanged:
+		var userID uint
+		if err := json.Unmarshal(event.Payload, &userID); err == nil {
+			tokenVersionCache.Delete(userID)
+		}
+	}
+}
+
+// issueTokens creates an access token and a fresh refresh token for a session
+func (s *SessionService) issueTokens(user *models.User, sessionID string) (*TokenPair, error) {
+	jwtConfig := config.AppConfig.JWT
+	accessTTL := time.Duration(jwtConfig.AccessTokenMinutes) * time.Minute
+
+	claims, err := s.accessClaims(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.signingKeyService.SignAccessToken(claims, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.CreateRefreshToken(&models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().AddDate(0, 0, jwtConfig.RefreshTokenDays),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(accessTTL),
+	}, nil
+}
+
+// accessClaims builds the claims of an access token for a user's session
+func (s *SessionService) accessClaims(user *models.User, sessionID string) (utils.JWTClaims, error) {
+	// Admins without 2FA are limited to enrolling when policy requires it
+	mustEnroll2FA := false
+	if user.IsAdmin() && !user.TOTPEnabled {
+		settings, err := s.settingService.GetSecuritySettings()
+		if err != nil {
+			return utils.JWTClaims{}, err
+		}
+		mustEnroll2FA = settings.RequireAdmin2FA
+	}
+
+	return utils.JWTClaims{
+		UserID:             user.ID,
+		Username:           user.Username,
+		Role:               user.Role,
+		SessionID:          sessionID,
+		TokenVersion:       user.TokenVersion,
+		MustChangePassword: user.MustChangePassword,
+		MustEnroll2FA:      mustEnroll2FA,
+	}, nil
+}
+
+// revokeReused kills a session whose refresh token was replayed
+func (s *SessionService) revokeReused(sessionID string) {
+	log.Printf("Refresh token reuse detected for session %s, revoking", sessionID)
+	if err := s.Revoke(sessionID); err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID, err)
+	}
+}
+
+// publishSessionRevoked tells every instance to forget a cached session
+func (s *SessionService) publishSessionRevoked(sessionID string) {
+	sessionCache.Delete(sessionID)
+
+	if err := events.PublishWith(s.db, events.SessionRevoked, 0, sessionID); err != nil {
+		log.Printf("Failed to publish %s event: %v", events.SessionRevoked, err)
+	}
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
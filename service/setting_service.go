@@ -0,0 +1,65 @@
+package service
+
+import (
+	"strconv"
+	"x-track/models"
+	"x-track/repository"
+
+	"gorm.io/gorm"
+)
+
+type SettingService struct {
+	settingRepo  *repository.SettingRepository
+	auditService *AuditService
+}
+
+func NewSettingService(db *gorm.DB) *SettingService {
+	return &SettingService{
+		settingRepo:  repository.NewSettingRepository(db),
+		auditService: NewAuditService(db),
+	}
+}
+
+// SecuritySettings represents the system-wide security settings
+type SecuritySettings struct {
+	RequireAdmin2FA bool `json:"require_admin_2fa"`
+}
+
+// GetSecuritySettings retrieves the security settings
+func (s *SettingService) GetSecuritySettings() (*SecuritySettings, error) {
+	requireAdmin2FA, err := s.getBool(models.SettingRequireAdmin2FA, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecuritySettings{RequireAdmin2FA: requireAdmin2FA}, nil
+}
+
+// UpdateSecuritySettings stores the security settings
+func (s *SettingService) UpdateSecuritySettings(actor Actor, settings SecuritySettings) (*SecuritySettings, error) {
+	before, err := s.GetSecuritySettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.settingRepo.Set(models.SettingRequireAdmin2FA, strconv.FormatBool(settings.RequireAdmin2FA)); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditSettingsUpdate, models.AuditTargetSettings, 0, before, &settings)
+
+	return &settings, nil
+}
+
+// getBool reads a boolean setting
+func (s *SettingService) getBool(key string, defaultValue bool) (bool, error) {
+	value, err := s.settingRepo.Get(key, strconv.FormatBool(defaultValue))
+	if err != nil {
+		return false, err
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, nil
+	}
+	return parsed, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrShareLinkInvalid is returned for unknown, expired or orphaned share links
+var ErrShareLinkInvalid = errors.New("share link is invalid or has expired")
+
+// ShareVisibility selects what a share link reveals
+type ShareVisibility struct {
+	ShowAccountName bool
+	ShowBalances    bool
+	ShowEquityCurve bool
+	ShowDrawdown    bool
+}
+
+// SharedAccountView is the public read-only view of an account behind a share link.
+// Absolute amounts are nil unless the link shows balances.
+type SharedAccountView struct {
+	AccountName        string              `json:"account_name,omitempty"`
+	Label              string              `json:"label,omitempty"`
+	HasData            bool                `json:"has_data"`
+	StartingBalance    *float64            `json:"starting_balance,omitempty"`
+	CurrentBalance     *float64            `json:"current_balance,omitempty"`
+	TotalReturnPercent float64             `json:"total_return_percent"`
+	TodayPL            *float64            `json:"today_pl,omitempty"`
+	TodayPLPercent     float64             `json:"today_pl_percent"`
+	DrawdownPercent    *float64            `json:"drawdown_percent,omitempty"`
+	MaxDrawdownPercent *float64            `json:"max_drawdown_percent,omitempty"`
+	TradingDays        int                 `json:"trading_days"`
+	LatestUpdate       *time.Time          `json:"latest_update"`
+	EquityCurve        []SharedEquityPoint `json:"equity_curve,omitempty"`
+}
+
+// SharedEquityPoint is one daily close on a shared equity curve
+type SharedEquityPoint struct {
+	Date            string   `json:"date"`
+	Balance         *float64 `json:"balance,omitempty"`
+	DailyPL         *float64 `json:"daily_pl,omitempty"`
+	DailyPLPercent  float64  `json:"daily_pl_percent"`
+	ReturnPercent   float64  `json:"return_percent"`
+	DrawdownPercent *float64 `json:"drawdown_percent,omitempty"`
+}
+
+type ShareLinkService struct {
+	linkRepo      *repository.ShareLinkRepository
+	statisticRepo *repository.StatisticRepository
+	auditService  *AuditService
+}
+
+func NewShareLinkService(db *gorm.DB) *ShareLinkService {
+	return &ShareLinkService{
+		linkRepo:      repository.NewShareLinkRepository(db),
+		statisticRepo: repository.NewStatisticRepository(db),
+		auditService:  NewAuditService(db),
+	}
+}
+
+// ListLinks retrieves all share links of an account
+func (s *ShareLinkService) ListLinks(accountID uint) ([]models.ShareLink, error) {
+	return s.linkRepo.FindByAccountID(accountID)
+}
+
+// CreateLink creates a share link for an account. The returned link carries the
+// public URL, which cannot be retrieved again afterwards.
+func (s *ShareLinkService) CreateLink(actor Actor, accountID uint, label string, visibility ShareVisibility, expiresAt *time.Time) (*models.ShareLink, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{
+		AccountID:       accountID,
+		CreatedByID:     actor.UserID,
+		Label:           label,
+		TokenHash:       utils.HashToken(token),
+		TokenPrefix:     token[:models.APITokenPrefixLength],
+		ShowAccountName: visibility.ShowAccountName,
+		ShowBalances:    visibility.ShowBalances,
+		ShowEquityCurve: visibility.ShowEquityCurve,
+		ShowDrawdown:    visibility.ShowDrawdown,
+		ExpiresAt:       expiresAt,
+	}
+
+	if err := s.linkRepo.Create(link); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditShareLinkCreate, models.AuditTargetShareLink, link.ID, nil, link)
+
+	link.URL = config.AppConfig.Server.BaseURL + "/share/" + token
+	return link, nil
+}
+
+// RevokeLink deletes a share link
+func (s *ShareLinkService) RevokeLink(actor Actor, accountID, linkID uint) error {
+	link, err := s.linkRepo.Find(accountID, linkID)
+	if err != nil {
+		return err
+	}
+	if err := s.linkRepo.Delete(accountID, linkID); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditShareLinkRevoke, models.AuditTargetShareLink, link.ID, link, nil)
+	return nil
+}
+
+// Resolve finds the active share link for a token
+func (s *ShareLinkService) Resolve(token string) (*models.ShareLink, error) {
+	link, err := s.linkRepo.FindByHash(utils.HashToken(token))
+	if err != nil || link.IsExpired() || link.Account.ID == 0 {
+		return nil, ErrShareLinkInvalid
+	}
+
+	// View tracking must not fail the request
+	_ = s.linkRepo.Touch(link.ID)
+
+	return link, nil
+}
+
+// BuildView builds the read-only view of a share link's account, limited to the fields it shows
+func (s *ShareLinkService) BuildView(link *models.ShareLink) (*SharedAccountView, error) {
+	view := &SharedAccountView{Label: link.Label}
+	if link.ShowAccountName {
+		view.AccountName = link.Account.Name
+	}
+
+	closes, err := s.statisticRepo.FindDailyCloses(link.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(closes) == 0 {
+		return view, nil
+	}
+
+	first, latest := closes[0], closes[len(closes)-1]
+	// The first day's P/L is already in its close, so the curve starts from the balance before it
+	startBalance := first.TotalBalance - first.DailyPL
+
+	view.HasData = true
+	view.TradingDays = len(closes)
+	view.LatestUpdate = &latest.Timestamp
+	view.TotalReturnPercent = percentChange(startBalance, latest.TotalBalance)
+	view.TodayPLPercent = percentChange(latest.TotalBalance-latest.DailyPL, latest.TotalBalance)
+
+	peak, maxDrawdown := startBalance, 0.0
+	for _, stat := range closes {
+		if stat.TotalBalance > peak {
+			peak = stat.TotalBalance
+		}
+		drawdown := drawdownPercent(peak, stat.TotalBalance)
+		if drawdown > maxDrawdown {
+			maxDrawdown = drawdown
+		}
+
+		if link.ShowEquityCurve {
+			point := SharedEquityPoint{
+				Date:           stat.Timestamp.Format("2006-01-02"),
+				DailyPLPercent: percentChange(stat.TotalBalance-stat.DailyPL, stat.TotalBalance),
+				ReturnPercent:  percentChange(startBalance, stat.TotalBalance),
+			}
+			if link.ShowBalances {
+				point.Balance = &stat.TotalBalance
+				point.DailyPL = &stat.DailyPL
+			}
+			if link.ShowDrawdown {
+				point.DrawdownPercent = &drawdown
+			}
+			view.EquityCurve = append(view.EquityCurve, point)
+		}
+	}
+
+	if link.ShowDrawdown {
+		current := drawdownPercent(peak, latest.TotalBalance)
+		view.DrawdownPercent = &current
+		view.MaxDrawdownPercent = &maxDrawdown
+	}
+
+	if link.ShowBalances {
+		view.StartingBalance = &startBalance
+		view.CurrentBalance = &latest.TotalBalance
+		view.TodayPL = &latest.DailyPL
+	}
+
+	return view, nil
+}
+
+// percentChange returns the change from base to value in percent
+func percentChange(base, value float64) float64 {
+	if base <= 0 {
+		return 0
+	}
+	return (value - base) / base * 100
+}
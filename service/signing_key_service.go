@@ -0,0 +1,589 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// signingKeyReloadInterval is how often the key ring is reloaded, picking up keys other instances created
+	signingKeyReloadInterval = time.Minute
+	// signingKeyRefreshInterval limits reloading the key ring when a token names an unknown key
+	signingKeyRefreshInterval = 5 * time.Second
+	// signingKeyGrace keeps a retired key published past its last token's expiry, covering
+	// clock skew and login challenges
+	signingKeyGrace = 10 * time.Minute
+	// signingKeyMaxLead is the longest a successor key is published before it starts signing
+	signingKeyMaxLead = 24 * time.Hour
+	// signingKeyCheckInterval is how often Run checks whether a rotation is due
+	signingKeyCheckInterval = time.Hour
+	// challengeAudience is the audience of login challenges, so they are never accepted as access tokens
+	challengeAudience = "x-track:login-challenge"
+)
+
+// signingMethods are the algorithms X-Track signs with, by JWT alg name
+var signingMethods = map[string]jwt.SigningMethod{
+	"RS256": jwt.SigningMethodRS256,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
+// JWKSet is the public half of the signing keys, as published at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// loadedSigningKey is a signing key with its keys parsed. signer is nil when the private
+// key can't be decrypted; the key then only verifies.
+type loadedSigningKey struct {
+	models.SigningKey
+	public crypto.PublicKey
+	signer crypto.Signer
+}
+
+// signingKeySnapshot is the key ring as loaded at one time. It is never modified, so it
+// can be read without locking.
+type signingKeySnapshot struct {
+	keys     []loadedSigningKey
+	loadedAt time.Time
+}
+
+// signingKeyRing caches the unexpired signing keys, shared by all services of the process.
+// Tokens are verified against the current snapshot without locking; mu only serializes
+// loading and rotating, so a reload never blocks requests that can use the old snapshot.
+type signingKeyRing struct {
+	mu       sync.Mutex
+	snapshot atomic.Pointer[signingKeySnapshot]
+}
+
+var signingKeys = &signingKeyRing{}
+
+// SigningKeyService signs and verifies X-Track tokens with rotating asymmetric keys.
+// Keys rotate lazily when a token is signed, so no background job is required; Run
+// additionally checks on a timer.
+type SigningKeyService struct {
+	cfg  *config.JWTConfig
+	repo *repository.SigningKeyRepository
+}
+
+func NewSigningKeyService(db *gorm.DB) *SigningKeyService {
+	return &SigningKeyService{
+		cfg:  &config.AppConfig.JWT,
+		repo: repository.NewSigningKeyRepository(db),
+	}
+}
+
+// SignAccessToken signs an access token with the given claims and lifetime
+func (s *SigningKeyService) SignAccessToken(claims utils.JWTClaims, expiresIn time.Duration) (string, error) {
+	claims.RegisteredClaims = s.registeredClaims(strconv.FormatUint(uint64(claims.UserID), 10), s.cfg.Audience, expiresIn)
+	return s.sign(claims)
+}
+
+// ParseAccessToken verifies an access token and returns its claims
+func (s *SigningKeyService) ParseAccessToken(tokenString string) (*utils.JWTClaims, error) {
+	claims := &utils.JWTClaims{}
+	if err := s.parse(tokenString, claims, s.cfg.Audience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// SignChallenge signs a login challenge for the given purpose
+func (s *SigningKeyService) SignChallenge(userID uint, purpose string, tokenVersion int, expiresIn time.Duration) (string, error) {
+	return s.sign(utils.ChallengeClaims{
+		UserID:           userID,
+		Purpose:          purpose,
+		TokenVersion:     tokenVersion,
+		RegisteredClaims: s.registeredClaims(strconv.FormatUint(uint64(userID), 10), challengeAudience, expiresIn),
+	})
+}
+
+// ParseChallenge verifies a login challenge issued for the given purpose and returns its claims
+func (s *SigningKeyService) ParseChallenge(tokenString, purpose string) (*utils.ChallengeClaims, error) {
+	claims := &utils.ChallengeClaims{}
+	if err := s.parse(tokenString, claims, challengeAudience); err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys that verify tokens, including successors that don't sign yet
+func (s *SigningKeyService) JWKS() (*JWKSet, error) {
+	snapshot, err := s.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &JWKSet{Keys: []jsonWebKey{}}
+	now := time.Now()
+	for _, key := range snapshot.keys {
+		if key.IsExpired(now) {
+			continue
+		}
+		jwk, err := publicJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// EnsureKeys rotates the signing keys when due: it creates a signing key if there is none
+// or the configured algorithm changed, publishes a successor ahead of the current key's
+// retirement and deletes expired keys
+func (s *SigningKeyService) EnsureKeys() error {
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+
+	_, err := s.rotate()
+	return err
+}
+
+// Run checks for due rotations until ctx is cancelled
+func (s *SigningKeyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(signingKeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.EnsureKeys(); err != nil {
+			log.Printf("Signing key rotation: %v", err)
+		}
+	}
+}
+
+// registeredClaims builds the standard claims of a token issued now
+func (s *SigningKeyService) registeredClaims(subject, audience string, expiresIn time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    s.cfg.Issuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+// sign signs claims with the current key, rotating first when due
+func (s *SigningKeyService) sign(claims jwt.Claims) (string, error) {
+	snapshot, err := s.keys()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if s.rotationDue(snapshot.keys, now) {
+		snapshot = s.rotateIfDue(now)
+	}
+
+	current := currentSigningKey(snapshot.keys, now)
+	if current == nil {
+		return "", errors.New("no signing key available")
+	}
+	if current.signer == nil {
+		return "", fmt.Errorf("signing key %s cannot be decrypted, check JWT_KEY_ENCRYPTION_KEY", current.KID)
+	}
+
+	return utils.SignJWT(claims, utils.JWTKey{
+		ID:     current.KID,
+		Method: signingMethods[current.Algorithm],
+		Key:    current.signer,
+	})
+}
+
+// parse verifies a token's signature, issuer, audience and expiry
+func (s *SigningKeyService) parse(tokenString string, claims jwt.Claims, audience string) error {
+	return utils.ParseJWT(tokenString, claims, s.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+// keyFunc finds the key a token was signed with, reloading the key ring once if the
+// token names a key another instance created
+func (s *SigningKeyService) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	snapshot, err := s.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	key := lookupSigningKey(snapshot.keys, kid)
+	if key == nil {
+		if snapshot, err = s.reload(signingKeyRefreshInterval); err != nil {
+			return nil, err
+		}
+		key = lookupSigningKey(snapshot.keys, kid)
+	}
+	if key == nil || key.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.public, nil
+}
+
+// rotationDue reports whether the key ring needs a new key
+func (s *SigningKeyService) rotationDue(keys []loadedSigningKey, now time.Time) bool {
+	current := currentSigningKey(keys, now)
+	if current == nil || current.Algorithm != s.cfg.Algorithm {
+		return true
+	}
+	if hasSuccessor(keys, &current.SigningKey) {
+		return false
+	}
+	return !now.Before(current.RetiresAt.Add(-s.successorLead()))
+}
+
+// rotateIfDue rotates unless another caller did while this one waited for the lock, and
+// returns the resulting key ring. A failed rotation only fails signing when no key can
+// sign at all, so the key ring from before is returned then.
+func (s *SigningKeyService) rotateIfDue(now time.Time) *signingKeySnapshot {
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+
+	snapshot := signingKeys.snapshot.Load()
+	if !s.rotationDue(snapshot.keys, now) {
+		return snapshot
+	}
+	rotated, err := s.rotate()
+	if err != nil {
+		log.Printf("Signing key rotation: %v", err)
+		return snapshot
+	}
+	return rotated
+}
+
+// rotate creates the keys that are due under the rotation lock, then reloads the key
+// ring; callers must hold signingKeys.mu
+func (s *SigningKeyService) rotate() (*signingKeySnapshot, error) {
+	err := s.repo.WithRotationLock(func(repo *repository.SigningKeyRepository) error {
+		now := time.Now()
+		if err := repo.DeleteExpired(now); err != nil {
+			return err
+		}
+		records, err := repo.FindUnexpired(now)
+		if err != nil {
+			return err
+		}
+
+		// Keys of another algorithm stop signing as soon as it is changed
+		for i := range records {
+			if records[i].Algorithm != s.cfg.Algorithm && records[i].RetiresAt.After(now) {
+				if err := repo.Retire(records[i].ID, now); err != nil {
+					return err
+				}
+				records[i].RetiresAt = now
+			}
+		}
+
+		var current *models.SigningKey
+		for i := range records {
+			if records[i].IsSigning(now) && (current == nil || records[i].NotBefore.After(current.NotBefore)) {
+				current = &records[i]
+			}
+		}
+
+		if current == nil {
+			if current, err = s.createKey(repo, now); err != nil {
+				return err
+			}
+			log.Printf("Created signing key %s", current.KID)
+		}
+
+		successorDue := !now.Before(current.RetiresAt.Add(-s.successorLead()))
+		if successorDue && !hasSuccessorRecord(records, current) {
+			successor, err := s.createKey(repo, current.RetiresAt)
+			if err != nil {
+				return err
+			}
+			log.Printf("Published signing key %s, signing from %s", successor.KID, successor.NotBefore.Format(time.RFC3339))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rotate signing keys: %w", err)
+	}
+
+	return s.load()
+}
+
+// successorLead is how long before the current key retires its successor is published,
+// giving verifiers time to fetch it
+func (s *SigningKeyService) successorLead() time.Duration {
+	lead := time.Duration(s.cfg.KeyRotationDays) * 24 * time.Hour / 4
+	if lead > signingKeyMaxLead {
+		return signingKeyMaxLead
+	}
+	return lead
+}
+
+// maxTokenLifetime is the longest a token signed by a key stays valid: an access token
+// or an impersonation token, which can outlive it
+func (s *SigningKeyService) maxTokenLifetime() time.Duration {
+	accessTTL := time.Duration(s.cfg.AccessTokenMinutes) * time.Minute
+	if accessTTL < MaxImpersonationDuration {
+		return MaxImpersonationDuration
+	}
+	return accessTTL
+}
+
+// createKey generates and stores a key pair of the configured algorithm that signs from notBefore
+func (s *SigningKeyService) createKey(repo *repository.SigningKeyRepository, notBefore time.Time) (*models.SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch s.cfg.Algorithm {
+	case "RS256":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported signing algorithm %q", s.cfg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid, err := utils.GenerateSecureToken(8)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	encrypted := s.cfg.KeyEncryptionKey != ""
+	if encrypted {
+		if privateKey, err = utils.EncryptSecret([]byte(privateKey), s.cfg.KeyEncryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	retiresAt := notBefore.Add(time.Duration(s.cfg.KeyRotationDays) * 24 * time.Hour)
+	key := &models.SigningKey{
+		KID:        kid,
+		Algorithm:  s.cfg.Algorithm,
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		PrivateKey: privateKey,
+		Encrypted:  encrypted,
+		NotBefore:  notBefore,
+		RetiresAt:  retiresAt,
+		ExpiresAt:  retiresAt.Add(s.maxTokenLifetime() + signingKeyGrace),
+	}
+	if err := repo.Create(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// keys returns the key ring, reloading it when it is older than the reload interval.
+// While one caller reloads, the others keep using the old key ring; they only wait when
+// no key ring was loaded yet.
+func (s *SigningKeyService) keys() (*signingKeySnapshot, error) {
+	snapshot := signingKeys.snapshot.Load()
+	if snapshot == nil {
+		return s.reload(signingKeyReloadInterval)
+	}
+	if time.Since(snapshot.loadedAt) < signingKeyReloadInterval || !signingKeys.mu.TryLock() {
+		return snapshot, nil
+	}
+	defer signingKeys.mu.Unlock()
+
+	if latest := signingKeys.snapshot.Load(); time.Since(latest.loadedAt) < signingKeyReloadInterval {
+		return latest, nil
+	}
+	return s.load()
+}
+
+// reload loads the key ring unless it was loaded within maxAge
+func (s *SigningKeyService) reload(maxAge time.Duration) (*signingKeySnapshot, error) {
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+
+	if snapshot := signingKeys.snapshot.Load(); snapshot != nil && time.Since(snapshot.loadedAt) < maxAge {
+		return snapshot, nil
+	}
+	return s.load()
+}
+
+// load replaces the key ring with the unexpired keys in the database; callers must hold signingKeys.mu
+func (s *SigningKeyService) load() (*signingKeySnapshot, error) {
+	records, err := s.repo.FindUnexpired(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]loadedSigningKey, 0, len(records))
+	for _, record := range records {
+		key, err := s.parseKey(record)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", record.KID, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	snapshot := &signingKeySnapshot{keys: keys, loadedAt: time.Now()}
+	signingKeys.snapshot.Store(snapshot)
+	return snapshot, nil
+}
+
+// parseKey parses a stored key pair. A private key that can't be decrypted leaves the key verify-only.
+func (s *SigningKeyService) parseKey(record models.SigningKey) (loadedSigningKey, error) {
+	key := loadedSigningKey{SigningKey: record}
+	if _, ok := signingMethods[record.Algorithm]; !ok {
+		return key, fmt.Errorf("unsupported algorithm %q", record.Algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(record.PublicKey))
+	if block == nil {
+		return key, errors.New("invalid public key")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return key, err
+	}
+	key.public = public
+
+	signer, err := s.parsePrivateKey(record)
+	if err != nil {
+		log.Printf("Signing key %s can only verify: %v", record.KID, err)
+		return key, nil
+	}
+	key.signer = signer
+	return key, nil
+}
+
+// parsePrivateKey decrypts and parses a stored private key
+func (s *SigningKeyService) parsePrivateKey(record models.SigningKey) (crypto.Signer, error) {
+	privatePEM := []byte(record.PrivateKey)
+	if record.Encrypted {
+		if s.cfg.KeyEncryptionKey == "" {
+			return nil, errors.New("private key is encrypted but JWT_KEY_ENCRYPTION_KEY is not set")
+		}
+		decrypted, err := utils.DecryptSecret(record.PrivateKey, s.cfg.KeyEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt private key: %w", err)
+		}
+		privatePEM = decrypted
+	}
+
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return signer, nil
+}
+
+// currentSigningKey returns the most recent key that signs at the given time
+func currentSigningKey(keys []loadedSigningKey, at time.Time) *loadedSigningKey {
+	var current *loadedSigningKey
+	for i := range keys {
+		if keys[i].IsSigning(at) && (current == nil || keys[i].NotBefore.After(current.NotBefore)) {
+			current = &keys[i]
+		}
+	}
+	return current
+}
+
+// hasSuccessor reports whether a key of the ring takes over when current retires
+func hasSuccessor(keys []loadedSigningKey, current *models.SigningKey) bool {
+	for i := range keys {
+		if isSuccessor(&keys[i].SigningKey, current) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuccessorRecord reports whether a key takes over when current retires
+func hasSuccessorRecord(keys []models.SigningKey, current *models.SigningKey) bool {
+	for i := range keys {
+		if isSuccessor(&keys[i], current) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSuccessor reports whether key signs, with the same algorithm, once current retires
+func isSuccessor(key, current *models.SigningKey) bool {
+	return key.ID != current.ID && key.Algorithm == current.Algorithm && key.IsSigning(current.RetiresAt)
+}
+
+// lookupSigningKey finds a key of the ring by its key ID
+func lookupSigningKey(keys []loadedSigningKey, kid string) *loadedSigningKey {
+	for i := range keys {
+		if keys[i].KID == kid {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// publicJWK converts the public half of a signing key to a JWK
+func publicJWK(key loadedSigningKey) (jsonWebKey, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := jsonWebKey{Kid: key.KID, Use: "sig", Alg: key.Algorithm}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(public)
+	default:
+		return jwk, fmt.Errorf("signing key %s has an unsupported key type", key.KID)
+	}
+	return jwk, nil
+}
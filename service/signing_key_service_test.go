@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenSignedBeforeRotationVerifiesUntilExpiry(t *testing.T) {
+	config.AppConfig = &config.Config{JWT: testJWTConfig()}
+	db := newTestDB(t, &models.SigningKey{})
+	signingKeys.snapshot.Store(nil)
+	t.Cleanup(func() { signingKeys.snapshot.Store(nil) })
+
+	s := NewSigningKeyService(db)
+	repo := repository.NewSigningKeyRepository(db)
+	rotation := time.Duration(config.AppConfig.JWT.KeyRotationDays) * 24 * time.Hour
+
+	// The current key retires in a moment; its successor is already published
+	current, err := s.createKey(repo, time.Now().Add(time.Second-rotation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.createKey(repo, current.RetiresAt); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+	}{
+		{name: "access token", expiresIn: time.Duration(config.AppConfig.JWT.AccessTokenMinutes) * time.Minute},
+		{name: "impersonation token", expiresIn: MaxImpersonationDuration},
+	}
+
+	tokens := make([]string, len(tests))
+	for i, tt := range tests {
+		if tokens[i], err = s.SignAccessToken(utils.JWTClaims{UserID: 1, SessionID: "session-1"}, tt.expiresIn); err != nil {
+			t.Fatalf("SignAccessToken() error = %v", err)
+		}
+	}
+
+	time.Sleep(time.Until(current.RetiresAt) + 10*time.Millisecond)
+	rotated, err := s.SignAccessToken(utils.JWTClaims{UserID: 1, SessionID: "session-1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("SignAccessToken() after rotation error = %v", err)
+	}
+	if kid(t, rotated) == current.KID {
+		t.Fatal("the retired key still signs")
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kid(t, tokens[i]) != current.KID {
+				t.Fatal("token wasn't signed with the key that retired")
+			}
+			claims, err := s.ParseAccessToken(tokens[i])
+			if err != nil {
+				t.Fatalf("ParseAccessToken() after rotation error = %v", err)
+			}
+			// The key is published for verification as long as the token is valid
+			if current.ExpiresAt.Before(claims.ExpiresAt.Time) {
+				t.Errorf("signing key expires at %v, before the token at %v", current.ExpiresAt, claims.ExpiresAt.Time)
+			}
+		})
+	}
+}
+
+// kid returns the key ID a token names
+func kid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := parsed.Header["kid"].(string)
+	return id
+}
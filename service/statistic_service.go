@@ -2,8 +2,12 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"math"
 	"time"
+	"x-track/config"
+	"x-track/events"
 	"x-track/models"
 	"x-track/repository"
 	"x-track/utils"
@@ -11,6 +15,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrNoStatisticForTradingDay is returned when trades are reported for a trading day
+// without statistics
+var ErrNoStatisticForTradingDay = errors.New("no statistics have been ingested for this trading day yet")
+
 type StatisticService struct {
 	statisticRepo *repository.StatisticRepository
 	accountRepo   *repository.AccountRepository
@@ -42,9 +50,90 @@ func (s *StatisticService) CreateStatistic(accountID uint, timestamp time.Time,
 		return nil, err
 	}
 
+	// Live consumers are best effort; the statistic is already stored
+	if err := events.Publish(events.StatisticIngested, accountID, statistic); err != nil {
+		log.Printf("Failed to publish %s event for account %d: %v", events.StatisticIngested, accountID, err)
+	}
+	s.checkDrawdownAlert(statistic)
+
 	return statistic, nil
 }
 
+// checkDrawdownAlert raises an alert when a statistic takes the account's drawdown past
+// ALERT_DRAWDOWN_PERCENT. Only crossing the threshold raises one, not every statistic below it.
+func (s *StatisticService) checkDrawdownAlert(statistic *models.Statistic) {
+	threshold := float64(config.AppConfig.Alerts.DrawdownPercent)
+	if threshold <= 0 {
+		return
+	}
+
+	previous, err := s.statisticRepo.FindLatestBefore(statistic.AccountID, statistic.Timestamp)
+	if err != nil {
+		return
+	}
+	peak, err := s.statisticRepo.GetPeakBalance(statistic.AccountID, statistic.Timestamp)
+	if err != nil {
+		log.Printf("Failed to check drawdown of account %d: %v", statistic.AccountID, err)
+		return
+	}
+
+	drawdown := drawdownPercent(peak, statistic.TotalBalance)
+	if drawdown < threshold || drawdownPercent(peak, previous.TotalBalance) >= threshold {
+		return
+	}
+
+	alert := events.Alert{
+		Title:   fmt.Sprintf("Drawdown of %.1f%%", drawdown),
+		Message: fmt.Sprintf("The balance fell to %.2f, %.1f%% below its peak of %.2f, passing the alert threshold of %.0f%%.", statistic.TotalBalance, drawdown, peak, threshold),
+	}
+	if err := events.Publish(events.AlertRaised, statistic.AccountID, alert); err != nil {
+		log.Printf("Failed to publish %s event for account %d: %v", events.AlertRaised, statistic.AccountID, err)
+	}
+}
+
+// RecordTrades sets the trade count of the account's latest statistic, which must be from
+// the same trading day as timestamp, for clients that report trades separately
+func (s *StatisticService) RecordTrades(accountID uint, timestamp time.Time, tradesToday int) (*models.Statistic, error) {
+	statistic, err := s.statisticRepo.GetLatestStatistic(accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoStatisticForTradingDay
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	day, err := TradingDayEnd(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	statisticDay, err := TradingDayEnd(statistic.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if !day.Equal(statisticDay) {
+		return nil, ErrNoStatisticForTradingDay
+	}
+
+	if err := s.statisticRepo.UpdateTradesToday(statistic.ID, tradesToday); err != nil {
+		return nil, err
+	}
+	statistic.TradesToday = tradesToday
+
+	if err := events.Publish(events.StatisticIngested, accountID, statistic); err != nil {
+		log.Printf("Failed to publish %s event for account %d: %v", events.StatisticIngested, accountID, err)
+	}
+	return statistic, nil
+}
+
+// RecordHeartbeat tells live streams that an account's client is connected. Heartbeats
+// are not stored.
+func (s *StatisticService) RecordHeartbeat(accountID uint, timestamp time.Time) error {
+	if err := events.Publish(events.HeartbeatReceived, accountID, events.Heartbeat{Time: timestamp}); err != nil {
+		return fmt.Errorf("failed to publish heartbeat: %w", err)
+	}
+	return nil
+}
+
 // GetStatisticsByDateRange retrieves statistics within a date range
 func (s *StatisticService) GetStatisticsByDateRange(accountID uint, startDate, endDate time.Time, page, pageSize int) ([]models.Statistic, *utils.PaginationMeta, error) {
 	statistics, total, err := s.statisticRepo.FindByDateRange(accountID, startDate, endDate, page, pageSize)
@@ -134,3 +223,31 @@ func (s *StatisticService) GetStatisticsByAccountID(accountID uint, page, pageSi
 
 	return statistics, pagination, nil
 }
+
+// ImportStatistics stores historical statistics for an account, skipping timestamps it
+// already has so an import can be repeated. No events are published, live consumers
+// only care about new data.
+func (s *StatisticService) ImportStatistics(accountID uint, statistics []models.Statistic) (int, error) {
+	if _, err := s.accountRepo.FindByID(accountID); err != nil {
+		return 0, errors.New("account not found")
+	}
+
+	for i, statistic := range statistics {
+		if statistic.Timestamp.IsZero() {
+			return 0, fmt.Errorf("statistic %d: timestamp is required", i+1)
+		}
+		if statistic.TradesToday < 0 || statistic.TotalBalance < 0 {
+			return 0, fmt.Errorf("statistic %d: trades and balance must not be negative", i+1)
+		}
+	}
+
+	return s.statisticRepo.CreateMissing(accountID, statistics)
+}
+
+// ExportStatistics retrieves the statistics of an account within [start, end) in chronological order
+func (s *StatisticService) ExportStatistics(accountID uint, start, end time.Time) ([]models.Statistic, error) {
+	if _, err := s.accountRepo.FindByID(accountID); err != nil {
+		return nil, errors.New("account not found")
+	}
+	return s.statisticRepo.FindBetween(accountID, start, end)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"sync"
+	"time"
+	"x-track/events"
+)
+
+// Event types pushed to live account streams
+const (
+	EventSnapshot  = "snapshot"
+	EventHeartbeat = "heartbeat"
+	EventAlert     = "alert"
+)
+
+// streamBufferSize is how many events a slow subscriber may lag behind before events are dropped
+const streamBufferSize = 64
+
+// AccountEvent is a live update for a single account
+type AccountEvent struct {
+	Type      string      `json:"type"`
+	AccountID uint        `json:"account_id"`
+	Data      interface{} `json:"data"`
+	Time      time.Time   `json:"time"`
+}
+
+// Subscription receives events for a set of accounts
+type Subscription struct {
+	C          chan AccountEvent
+	accountIDs map[uint]struct{}
+}
+
+// StreamHub fans out account events to connected stream subscribers
+type StreamHub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Streams is the process-wide hub used by the live stream endpoints
+var Streams = NewStreamHub()
+
+func NewStreamHub() *StreamHub {
+	return &StreamHub{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscription for events of the given accounts
+func (h *StreamHub) Subscribe(accountIDs []uint) *Subscription {
+	sub := &Subscription{
+		C:          make(chan AccountEvent, streamBufferSize),
+		accountIDs: make(map[uint]struct{}, len(accountIDs)),
+	}
+	for _, id := range accountIDs {
+		sub.accountIDs[id] = struct{}{}
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// SetAccounts changes the accounts a subscription receives events of
+func (h *StreamHub) SetAccounts(sub *Subscription, accountIDs []uint) {
+	ids := make(map[uint]struct{}, len(accountIDs))
+	for _, id := range accountIDs {
+		ids[id] = struct{}{}
+	}
+
+	h.mu.Lock()
+	sub.accountIDs = ids
+	h.mu.Unlock()
+}
+
+// Unsubscribe removes a subscription and closes its channel
+func (h *StreamHub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.C)
+	}
+	h.mu.Unlock()
+}
+
+// HandleEvent forwards a domain event from the bus to stream subscribers
+func (h *StreamHub) HandleEvent(event events.Event) {
+	var streamType string
+	switch event.Type {
+	case events.StatisticIngested:
+		streamType = EventSnapshot
+	case events.HeartbeatReceived:
+		streamType = EventHeartbeat
+	case events.AlertRaised:
+		streamType = EventAlert
+	default:
+		return
+	}
+
+	h.Publish(AccountEvent{
+		Type:      streamType,
+		AccountID: event.AccountID,
+		Data:      event.Payload,
+		Time:      event.Time,
+	})
+}
+
+// Publish delivers an event to every subscription watching its account
+func (h *StreamHub) Publish(event AccountEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if _, ok := sub.accountIDs[event.AccountID]; !ok {
+			continue
+		}
+
+		// Never block ingestion on a slow client
+		select {
+		case sub.C <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// streamTicketTTL is how long a client has to open the stream after asking for a ticket
+const streamTicketTTL = 30 * time.Second
+
+// streamTicketSweepInterval is how often tickets that were never used are deleted
+const streamTicketSweepInterval = time.Minute
+
+// ErrStreamTicketInvalid is returned for an unknown, used or expired stream ticket
+var ErrStreamTicketInvalid = errors.New("stream ticket expired or is invalid")
+
+type StreamTicketService struct {
+	ticketRepo     *repository.StreamTicketRepository
+	userRepo       *repository.UserRepository
+	sessionService *SessionService
+}
+
+func NewStreamTicketService(db *gorm.DB) *StreamTicketService {
+	return &StreamTicketService{
+		ticketRepo:     repository.NewStreamTicketRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		sessionService: NewSessionService(db),
+	}
+}
+
+// IssueTicket creates a single-use ticket that opens a live stream for the session of the
+// access token claims were taken from. Only the ticket's hash and the session are stored,
+// with the token's expiry so the stream doesn't outlive it.
+func (s *StreamTicketService) IssueTicket(claims *utils.JWTClaims) (string, time.Time, error) {
+	if claims.ExpiresAt == nil {
+		return "", time.Time{}, errors.New("access token has no expiry")
+	}
+
+	ticket, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(streamTicketTTL)
+	stored := &models.StreamTicket{
+		TicketHash:     utils.HashToken(ticket),
+		SessionID:      claims.SessionID,
+		UserID:         claims.UserID,
+		TokenVersion:   claims.TokenVersion,
+		ReadOnly:       claims.ReadOnly,
+		TokenExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:      expiresAt,
+	}
+	if imp := claims.Impersonator; imp != nil {
+		stored.ImpersonatorID = &imp.UserID
+		stored.ImpersonatorSessionID = imp.SessionID
+		stored.ImpersonatorTokenVersion = imp.TokenVersion
+	}
+	if err := s.ticketRepo.Create(stored); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return ticket, expiresAt, nil
+}
+
+// RedeemTicket uses up a ticket and rebuilds the claims of the session it was issued for
+// from the current user. The claims carry the token versions and expiry the ticket was
+// issued with, so they still have to pass SessionService.ValidateAccess.
+func (s *StreamTicketService) RedeemTicket(ticket string) (*utils.JWTClaims, error) {
+	stored, err := s.ticketRepo.Consume(utils.HashToken(ticket))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStreamTicketInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStreamTicketInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	registered := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(stored.TokenExpiresAt)}
+	if stored.ImpersonatorID == nil {
+		claims, err := s.sessionService.accessClaims(user, stored.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		claims.TokenVersion = stored.TokenVersion
+		claims.RegisteredClaims = registered
+		return &claims, nil
+	}
+
+	impersonator, err := s.userRepo.FindByID(*stored.ImpersonatorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStreamTicketInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &utils.JWTClaims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		SessionID:    stored.SessionID,
+		TokenVersion: stored.TokenVersion,
+		Impersonator: &utils.ImpersonatorClaims{
+			UserID:       impersonator.ID,
+			Username:     impersonator.Username,
+			SessionID:    stored.ImpersonatorSessionID,
+			TokenVersion: stored.ImpersonatorTokenVersion,
+		},
+		ReadOnly:         stored.ReadOnly,
+		RegisteredClaims: registered,
+	}, nil
+}
+
+// Run deletes tickets that were never used until ctx is cancelled
+func (s *StreamTicketService) Run(ctx context.Context) {
+	ticker := time.NewTicker(streamTicketSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.ticketRepo.DeleteExpired(); err != nil {
+			log.Printf("Failed to delete expired stream tickets: %v", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"x-track/config"
+	"x-track/models"
+	"x-track/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamTicketKeepsTokenExpiry(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	db := newTestDB(t, &models.User{}, &models.StreamTicket{})
+	s := NewStreamTicketService(db)
+
+	users := []models.User{{Username: "alice", Role: models.RoleUser, TokenVersion: 3}, {Username: "admin", Role: models.RoleAdmin, TokenVersion: 1}}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		claims utils.JWTClaims
+	}{
+		{
+			name:   "session",
+			claims: utils.JWTClaims{UserID: users[0].ID, SessionID: "session-1", TokenVersion: 3},
+		},
+		{
+			name: "impersonation",
+			claims: utils.JWTClaims{UserID: users[0].ID, SessionID: "session-2", TokenVersion: 3, ReadOnly: true,
+				Impersonator: &utils.ImpersonatorClaims{UserID: users[1].ID, SessionID: "session-3", TokenVersion: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claims.RegisteredClaims = jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)}
+			ticket, _, err := s.IssueTicket(&tt.claims)
+			if err != nil {
+				t.Fatalf("IssueTicket() error = %v", err)
+			}
+
+			got, err := s.RedeemTicket(ticket)
+			if err != nil {
+				t.Fatalf("RedeemTicket() error = %v", err)
+			}
+			if got.ExpiresAt == nil || !got.ExpiresAt.Time.Equal(expiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+			}
+			if got.SessionID != tt.claims.SessionID || got.TokenVersion != tt.claims.TokenVersion || got.ReadOnly != tt.claims.ReadOnly {
+				t.Errorf("RedeemTicket() = %+v, want the session of %+v", got, tt.claims)
+			}
+			if (got.Impersonator == nil) != (tt.claims.Impersonator == nil) {
+				t.Errorf("Impersonator = %+v, want %+v", got.Impersonator, tt.claims.Impersonator)
+			}
+
+			if _, err := s.RedeemTicket(ticket); !errors.Is(err, ErrStreamTicketInvalid) {
+				t.Errorf("RedeemTicket() of a used ticket error = %v, want %v", err, ErrStreamTicketInvalid)
+			}
+		})
+	}
+
+	if _, _, err := s.IssueTicket(&utils.JWTClaims{UserID: users[0].ID, SessionID: "session-1"}); err == nil {
+		t.Error("IssueTicket() accepted claims without an expiry")
+	}
+}
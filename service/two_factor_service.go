@@ -0,0 +1,285 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"x-track/models"
+	"x-track/repository"
+	"x-track/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// totpIssuer is shown as the account issuer in authenticator apps
+	totpIssuer = "X-Track"
+	// twoFactorChallengeTTL is how long a user has to enter their code after the password step
+	twoFactorChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes are issued at once
+	recoveryCodeCount = 10
+
+	challengePurpose2FA = "2fa"
+)
+
+type TwoFactorService struct {
+	userRepo          *repository.UserRepository
+	recoveryRepo      *repository.RecoveryCodeRepository
+	settingService    *SettingService
+	sessionService    *SessionService
+	signingKeyService *SigningKeyService
+	auditService      *AuditService
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{
+		userRepo:          repository.NewUserRepository(db),
+		recoveryRepo:      repository.NewRecoveryCodeRepository(db),
+		settingService:    NewSettingService(db),
+		sessionService:    NewSessionService(db),
+		signingKeyService: NewSigningKeyService(db),
+		auditService:      NewAuditService(db),
+	}
+}
+
+// TwoFactorSetup contains the secret to enrol in an authenticator app
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorEnabled contains the recovery codes and fresh tokens issued when 2FA is enabled
+type TwoFactorEnabled struct {
+	RecoveryCodes []string   `json:"recovery_codes"`
+	Tokens        *TokenPair `json:"tokens"`
+}
+
+// BeginSetup generates a new pending TOTP secret for a user
+func (s *TwoFactorService) BeginSetup(userID uint) (*TwoFactorSetup, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// Enable confirms the pending secret with a code, enables 2FA and issues recovery codes
+func (s *TwoFactorService) Enable(actor Actor, userID uint, sessionID, code string) (*TwoFactorEnabled, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("start two-factor setup first")
+	}
+
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditTwoFactorEnable, models.AuditTargetUser, user.ID, nil, nil)
+
+	// Replace a token that was limited to 2FA enrolment
+	tokens, err := s.sessionService.ReissueSession(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnabled{RecoveryCodes: codes, Tokens: tokens}, nil
+}
+
+// Disable turns off 2FA after re-checking the password and a current code
+func (s *TwoFactorService) Disable(actor Actor, userID uint, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := utils.CheckPassword(user.PasswordHash, password); err != nil {
+		return errors.New("password is incorrect")
+	}
+
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+
+	required, err := s.IsRequiredFor(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("two-factor authentication is required for your role")
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.recoveryRepo.ReplaceForUser(user.ID, nil); err != nil {
+		return err
+	}
+	s.auditService.Record(actor, models.AuditTwoFactorDisable, models.AuditTargetUser, user.ID, nil, nil)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a current code
+func (s *TwoFactorService) RegenerateRecoveryCodes(actor Actor, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditRecoveryCodesReissue, models.AuditTargetUser, user.ID, nil, nil)
+	return codes, nil
+}
+
+// CreateChallenge issues the token that carries a password-verified login to the code step
+func (s *TwoFactorService) CreateChallenge(user *models.User) (string, error) {
+	return s.signingKeyService.SignChallenge(user.ID, challengePurpose2FA, user.TokenVersion, twoFactorChallengeTTL)
+}
+
+// ParseChallenge validates a login challenge and returns the user it was issued for
+func (s *TwoFactorService) ParseChallenge(challenge string) (*models.User, error) {
+	claims, err := s.signingKeyService.ParseChallenge(challenge, challengePurpose2FA)
+	if err != nil {
+		return nil, errors.New("invalid or expired login challenge")
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.TokenVersion != claims.TokenVersion {
+		return nil, errors.New("invalid or expired login challenge")
+	}
+
+	return user, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code
+func (s *TwoFactorService) Verify(user *models.User, code string) error {
+	if err := s.verifyTOTP(user, code); err == nil {
+		return nil
+	}
+
+	consumed, err := s.recoveryRepo.Consume(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return errors.New("invalid two-factor code")
+	}
+	return nil
+}
+
+// IsRequiredFor reports whether policy requires the user to use 2FA
+func (s *TwoFactorService) IsRequiredFor(user *models.User) (bool, error) {
+	if !user.IsAdmin() {
+		return false, nil
+	}
+
+	settings, err := s.settingService.GetSecuritySettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.RequireAdmin2FA, nil
+}
+
+// verifyTOTP checks a TOTP code and records its time step so it cannot be reused
+func (s *TwoFactorService) verifyTOTP(user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+
+	claimed, err := s.userRepo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errors.New("invalid two-factor code")
+	}
+
+	user.TOTPLastStep = step
+	return nil
+}
+
+// issueRecoveryCodes replaces a user's recovery codes and returns the plaintext codes
+func (s *TwoFactorService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateSecureToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := s.recoveryRepo.ReplaceForUser(userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in recovery codes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
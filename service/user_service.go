@@ -2,6 +2,9 @@ package service
 
 import (
 	"errors"
+	"log"
+	"time"
+	"x-track/events"
 	"x-track/models"
 	"x-track/repository"
 	"x-track/utils"
@@ -9,21 +12,79 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrUserSuspended is returned when a suspended user tries to log in or use a token
+	ErrUserSuspended = errors.New("this account is suspended, contact an administrator")
+	// ErrUserDeactivated is returned when a deactivated user tries to log in or use a token
+	ErrUserDeactivated = errors.New("this account has been deactivated")
+	// ErrDeleteSelf is returned when users try to delete themselves
+	ErrDeleteSelf = errors.New("you can't delete yourself")
+	// ErrLastUserManager is returned when a change would leave no active user whose role
+	// grants users:manage
+	ErrLastUserManager = errors.New("this is the last active user who can manage users")
+)
+
+// checkUserActive returns the error refusing a suspended or deactivated user
+func checkUserActive(user *models.User) error {
+	switch user.Status {
+	case models.UserStatusActive:
+		return nil
+	case models.UserStatusDeactivated:
+		return ErrUserDeactivated
+	default:
+		return ErrUserSuspended
+	}
+}
+
+// IsInactiveUserError reports whether err refuses a suspended or deactivated user
+func IsInactiveUserError(err error) bool {
+	return errors.Is(err, ErrUserSuspended) || errors.Is(err, ErrUserDeactivated)
+}
+
 type UserService struct {
-	userRepo *repository.UserRepository
+	db             *gorm.DB
+	userRepo       *repository.UserRepository
+	memberRepo     *repository.AccountMemberRepository
+	orgRepo        *repository.OrganizationRepository
+	identityRepo   *repository.UserIdentityRepository
+	patRepo        *repository.PersonalAccessTokenRepository
+	historyRepo    *repository.PasswordHistoryRepository
+	accountRepo    *repository.AccountRepository
+	accountService *AccountService
+	roleService    *RoleService
+	emailService   *EmailService
+	sessionService *SessionService
+	passwordPolicy *PasswordPolicyService
+	auditService   *AuditService
 }
 
 func NewUserService(db *gorm.DB) *UserService {
 	return &UserService{
-		userRepo: repository.NewUserRepository(db),
+		db:             db,
+		userRepo:       repository.NewUserRepository(db),
+		memberRepo:     repository.NewAccountMemberRepository(db),
+		orgRepo:        repository.NewOrganizationRepository(db),
+		identityRepo:   repository.NewUserIdentityRepository(db),
+		patRepo:        repository.NewPersonalAccessTokenRepository(db),
+		historyRepo:    repository.NewPasswordHistoryRepository(db),
+		accountRepo:    repository.NewAccountRepository(db),
+		accountService: NewAccountService(db),
+		roleService:    NewRoleService(db),
+		emailService:   NewEmailService(db),
+		sessionService: NewSessionService(db),
+		passwordPolicy: NewPasswordPolicyService(db),
+		auditService:   NewAuditService(db),
 	}
 }
 
 // CreateUser creates a new user
-func (s *UserService) CreateUser(username, password, role string) (*models.User, error) {
+func (s *UserService) CreateUser(actor Actor, username, email, password, role string) (*models.User, error) {
 	// Validate role
-	if role != "admin" && role != "user" {
-		return nil, errors.New("invalid role, must be 'admin' or 'user'")
+	if err := s.validateRole(role); err != nil {
+		return nil, err
+	}
+	if err := s.roleService.CheckRoleGrantable(actor, role); err != nil {
+		return nil, err
 	}
 
 	// Check if username already exists
@@ -35,21 +96,41 @@ func (s *UserService) CreateUser(username, password, role string) (*models.User,
 		return nil, errors.New("username already exists")
 	}
 
+	if email != "" {
+		if err := s.checkEmailAvailable(email, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
-	hashedPassword, err := utils.HashPassword(password)
+	hashedPassword, err := s.passwordPolicy.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
+	// Admin-chosen passwords must be replaced on first login
 	user := &models.User{
-		Username:     username,
-		PasswordHash: hashedPassword,
-		Role:         role,
+		Username:           username,
+		Email:              email,
+		PasswordHash:       hashedPassword,
+		Role:               role,
+		MustChangePassword: true,
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, models.AuditUserCreate, models.AuditTargetUser, user.ID, nil, user)
+
+	if user.Email != "" {
+		if err := s.emailService.RequestVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
 
 	return user, nil
 }
@@ -59,17 +140,26 @@ func (s *UserService) GetUserByID(id uint) (*models.User, error) {
 	return s.userRepo.FindByID(id)
 }
 
+// GetUserByUsername retrieves a user by username
+func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
+	return s.userRepo.FindByUsername(username)
+}
+
 // GetAllUsers retrieves all users
 func (s *UserService) GetAllUsers() ([]models.User, error) {
 	return s.userRepo.FindAll()
 }
 
 // UpdateUser updates a user's information
-func (s *UserService) UpdateUser(id uint, username, password, role string) (*models.User, error) {
+func (s *UserService) UpdateUser(actor Actor, id uint, username, email, password, role string) (*models.User, error) {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkManageable(actor, user); err != nil {
+		return nil, err
+	}
+	before := *user
 
 	if username != "" && username != user.Username {
 		exists, err := s.userRepo.UsernameExists(username)
@@ -82,58 +172,323 @@ func (s *UserService) UpdateUser(id uint, username, password, role string) (*mod
 		user.Username = username
 	}
 
+	securityChanged := false
+
 	if password != "" {
-		hashedPassword, err := utils.HashPassword(password)
+		// Admin-set passwords aren't checked against the user's history, which would
+		// reveal whether the admin guessed one of the user's previous passwords
+		if err := s.passwordPolicy.Validate(password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := s.passwordPolicy.Hash(password)
 		if err != nil {
 			return nil, err
 		}
+		if err := s.passwordPolicy.Remember(user.ID, user.PasswordHash); err != nil {
+			return nil, err
+		}
 		user.PasswordHash = hashedPassword
+		user.MustChangePassword = true
+		securityChanged = true
 	}
 
 	if role != "" {
-		if role != "admin" && role != "user" {
-			return nil, errors.New("invalid role, must be 'admin' or 'user'")
+		if err := s.validateRole(role); err != nil {
+			return nil, err
+		}
+		if err := s.roleService.CheckRoleGrantable(actor, role); err != nil {
+			return nil, err
+		}
+		if role != user.Role {
+			if permissions, err := s.roleService.Permissions(role); err != nil {
+				return nil, err
+			} else if !permissions.Has(models.PermUsersManage) {
+				if err := s.checkNotLastUserManager(user); err != nil {
+					return nil, err
+				}
+			}
+			user.Role = role
+			securityChanged = true
 		}
-		user.Role = role
+	}
+
+	if securityChanged {
+		user.TokenVersion++
+	}
+
+	emailChanged := false
+	if email != "" && email != user.Email {
+		if err := s.checkEmailAvailable(email, user.ID); err != nil {
+			return nil, err
+		}
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(actor, models.AuditUserUpdate, models.AuditTargetUser, user.ID, &before, user)
+	if password != "" {
+		s.auditService.Record(actor, models.AuditUserPasswordSet, models.AuditTargetUser, user.ID, nil, nil)
+	}
+
+	if securityChanged {
+		if err := s.sessionService.InvalidateUser(user.ID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if emailChanged {
+		if err := s.emailService.RequestVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// UpdateEmail changes a user's email address and sends a new verification link
+func (s *UserService) UpdateEmail(actor Actor, id uint, email string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if email != user.Email {
+		if err := s.checkEmailAvailable(email, user.ID); err != nil {
+			return nil, err
+		}
+		before := *user
+		user.Email = email
+		user.EmailVerifiedAt = nil
+
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+		s.auditService.Record(actor, models.AuditUserEmailChange, models.AuditTargetUser, user.ID, &before, user)
+	}
+
+	if !user.HasVerifiedEmail() {
+		if err := s.emailService.RequestVerification(user); err != nil {
+			return nil, errors.New("failed to send verification email")
+		}
+	}
 
 	return user, nil
 }
 
-// DeleteUser deletes a user
-func (s *UserService) DeleteUser(id uint) error {
-	return s.userRepo.Delete(id)
+// SetDigestEnabled opts a user in or out of the daily digest email
+func (s *UserService) SetDigestEnabled(id uint, enabled bool) (*models.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled && !user.HasVerifiedEmail() {
+		return nil, errors.New("a verified email address is required for the daily digest")
+	}
+
+	user.DigestEnabled = enabled
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkEmailAvailable ensures no other user already uses the email address
+func (s *UserService) checkEmailAvailable(email string, excludeUserID uint) error {
+	exists, err := s.userRepo.EmailExists(email, excludeUserID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("email already in use")
+	}
+	return nil
+}
+
+// SetStatus suspends, deactivates or reactivates a user. Leaving the active status ends
+// all of the user's sessions and rejects their outstanding access tokens.
+func (s *UserService) SetStatus(actor Actor, id uint, status string) (*models.User, error) {
+	if !models.IsValidUserStatus(status) {
+		return nil, errors.New("status must be active, suspended or deactivated")
+	}
+	if id == actor.UserID && status != models.UserStatusActive {
+		return nil, errors.New("you can't suspend or deactivate yourself")
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkManageable(actor, user); err != nil {
+		return nil, err
+	}
+	if user.Status == status {
+		return user, nil
+	}
+
+	if err := s.checkNotLastUserManager(user); err != nil {
+		return nil, err
+	}
+
+	before := *user
+	now := time.Now()
+	user.Status = status
+	user.StatusChangedAt = &now
+	user.TokenVersion++
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, models.AuditUserStatusChange, models.AuditTargetUser, user.ID, &before, user)
+
+	if err := s.sessionService.InvalidateUser(user.ID, ""); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser deletes a user with their personal accounts and invalidates all of their
+// tokens. Organization accounts the user created stay with the organization.
+func (s *UserService) DeleteUser(actor Actor, id uint) error {
+	if id == actor.UserID {
+		return ErrDeleteSelf
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageable(actor, user); err != nil {
+		return err
+	}
+
+	if err := s.checkNotLastUserManager(user); err != nil {
+		return err
+	}
+
+	// Either the user and everything that belongs to them is gone, or nothing is
+	err = events.Transaction(s.db, func(tx *gorm.DB) error {
+		return NewUserService(tx).deleteUserData(actor, user)
+	})
+	if err != nil {
+		return err
+	}
+	return s.sessionService.InvalidateUser(id, "")
+}
+
+// deleteUserData deletes a user with their personal accounts, grants, memberships,
+// identities, tokens and password history
+func (s *UserService) deleteUserData(actor Actor, user *models.User) error {
+	accounts, err := s.accountRepo.FindPersonalByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if err := s.accountService.DeleteAccount(actor, account.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.userRepo.Delete(user.ID); err != nil {
+		return err
+	}
+	if err := s.auditService.Append(actor, models.AuditUserDelete, models.AuditTargetUser, user.ID, user, nil); err != nil {
+		return err
+	}
+
+	if err := s.memberRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.orgRepo.DeleteMembershipsByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.identityRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.patRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	return s.historyRepo.DeleteByUserID(user.ID)
 }
 
 // AuthenticateUser validates user credentials and returns user info
 func (s *UserService) AuthenticateUser(username, password string) (*models.User, error) {
 	user, err := s.userRepo.FindByUsername(username)
 	if err != nil {
+		// Spend as long as on a wrong password so unknown usernames can't be told apart
+		utils.CheckPassword(s.passwordPolicy.DummyHash(), password)
 		return nil, errors.New("invalid credentials")
 	}
 
 	if err := utils.CheckPassword(user.PasswordHash, password); err != nil {
 		return nil, errors.New("invalid credentials")
 	}
+	if err := checkUserActive(user); err != nil {
+		return nil, err
+	}
+
+	// The password is only known now, so hashes weaker than the policy are upgraded here
+	if s.passwordPolicy.NeedsRehash(user.PasswordHash) {
+		if hashedPassword, err := s.passwordPolicy.Hash(password); err != nil {
+			log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		} else if err := s.userRepo.UpgradePasswordHash(user.ID, user.PasswordHash, hashedPassword); err != nil {
+			log.Printf("Failed to upgrade password hash of user %d: %v", user.ID, err)
+		} else {
+			user.PasswordHash = hashedPassword
+		}
+	}
 
 	return user, nil
 }
 
-// EnsureAdminExists creates admin user if none exists
-func (s *UserService) EnsureAdminExists(username, password string) error {
-	var count int64
-	if err := models.DB.Model(&models.User{}).Where("role = ?", "admin").Count(&count).Error; err != nil {
+// checkNotLastUserManager refuses to delete, deactivate or take users:manage from the last
+// active user whose role grants it, whether a built-in or custom role, so someone can
+// always manage users
+func (s *UserService) checkNotLastUserManager(user *models.User) error {
+	if !user.IsActive() {
+		return nil
+	}
+	permissions, err := s.roleService.Permissions(user.Role)
+	if err != nil {
 		return err
 	}
+	if !permissions.Has(models.PermUsersManage) {
+		return nil
+	}
 
-	if count == 0 {
-		_, err := s.CreateUser(username, password, "admin")
+	roles, err := s.roleService.RolesWithPermission(models.PermUsersManage)
+	if err != nil {
 		return err
 	}
+	count, err := s.userRepo.CountActiveByRoles(roles)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastUserManager
+	}
+	return nil
+}
+
+// checkManageable refuses changes to users holding permissions the actor doesn't, which
+// would let the actor take over or lock out a more privileged user
+func (s *UserService) checkManageable(actor Actor, user *models.User) error {
+	return s.roleService.CheckRoleGrantable(actor, user.Role)
+}
 
+// validateRole checks a role is built-in or an existing custom role
+func (s *UserService) validateRole(role string) error {
+	exists, err := s.roleService.RoleExists(role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("invalid role, must be a built-in or custom role")
+	}
 	return nil
 }
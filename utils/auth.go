@@ -1,29 +1,18 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedBytes), nil
-}
-
-// CheckPassword compares a hashed password with a plain password
-func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-}
-
 // GenerateSecureToken generates a random secure token
 func GenerateSecureToken(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -33,46 +22,109 @@ func GenerateSecureToken(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashToken returns the SHA-256 hex digest of a token for storage at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // JWTClaims represents the JWT claims
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID             uint   `json:"user_id"`
+	Username           string `json:"username"`
+	Role               string `json:"role"`
+	SessionID          string `json:"sid"`
+	TokenVersion       int    `json:"ver"`
+	MustChangePassword bool   `json:"mcp,omitempty"`
+	MustEnroll2FA      bool   `json:"m2fa,omitempty"`
+	// Impersonator is set on tokens an admin uses to act as the user (RFC 8693 actor claim)
+	Impersonator *ImpersonatorClaims `json:"act,omitempty"`
+	ReadOnly     bool                `json:"ro,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(userID uint, username, role, secret string, expirationHours int) (string, error) {
-	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expirationHours))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// ImpersonatorClaims identifies the admin behind an impersonation token and the
+// admin's own session, so the token dies with it
+type ImpersonatorClaims struct {
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	SessionID    string `json:"sid"`
+	TokenVersion int    `json:"ver"`
+}
+
+// ChallengeClaims represents a short-lived token for completing a multi-step login
+type ChallengeClaims struct {
+	UserID       uint   `json:"user_id"`
+	Purpose      string `json:"purpose"`
+	TokenVersion int    `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// JWTKey is a private key tokens are signed with, named in the token's kid header
+type JWTKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.Signer
+}
+
+// SignJWT signs claims with key
+func SignJWT(claims jwt.Claims, key JWTKey) (string, error) {
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Key)
+}
+
+// ParseJWT verifies a token against the key keyFunc returns and decodes it into claims
+func ParseJWT(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc, options ...jwt.ParserOption) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, options...)
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid {
+		return errors.New("invalid token")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return nil
 }
 
-// ValidateJWT validates a JWT token and returns the claims
-func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(secret), nil
-	})
+// EncryptSecret seals plaintext with AES-256-GCM under a key derived from passphrase
+func EncryptSecret(plaintext []byte, passphrase string) (string, error) {
+	gcm, err := secretCipher(passphrase)
+	if err != nil {
+		return "", err
+	}
 
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// DecryptSecret opens a value sealed by EncryptSecret
+func DecryptSecret(sealed, passphrase string) ([]byte, error) {
+	gcm, err := secretCipher(passphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("sealed secret is too short")
 	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
 
-	return nil, errors.New("invalid token")
+// secretCipher builds the AES-GCM cipher for a passphrase
+func secretCipher(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+	"x-track/config"
+)
+
+// SendMail sends an HTML email through the configured SMTP server
+func SendMail(cfg config.SMTPConfig, to, subject, htmlBody string) error {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM address: %w", err)
+	}
+
+	// Local catchers such as MailHog accept unauthenticated mail
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/html; charset=UTF-8",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + htmlBody
+
+	return smtp.SendMail(cfg.Addr(), auth, from.Address, []string{to}, []byte(msg))
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash algorithms
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrPasswordMismatch is returned when a password does not match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHashParams selects the algorithm and cost of new password hashes
+type PasswordHashParams struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// argon2Hash is a decoded argon2id hash in PHC string format
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// HashPassword hashes a password with the algorithm and cost of params
+func HashPassword(password string, params PasswordHashParams) (string, error) {
+	switch params.Algorithm {
+	case PasswordHashBcrypt:
+		hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedBytes), nil
+
+	case PasswordHashArgon2id:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, params.Argon2Memory, params.Argon2Iterations, params.Argon2Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	return "", fmt.Errorf("unsupported password hash algorithm %q", params.Algorithm)
+}
+
+// CheckPassword compares a bcrypt or argon2id hash with a plain password
+func CheckPassword(hashedPassword, password string) error {
+	if !strings.HasPrefix(hashedPassword, "$argon2id$") {
+		if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
+	hash, err := parseArgon2Hash(hashedPassword)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+	if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether a hash is weaker than params: a bcrypt hash when
+// argon2id is configured, or a hash of the configured algorithm with a lower cost
+func PasswordNeedsRehash(hashedPassword string, params PasswordHashParams) bool {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		if params.Algorithm != PasswordHashArgon2id {
+			return false
+		}
+		hash, err := parseArgon2Hash(hashedPassword)
+		if err != nil {
+			return true
+		}
+		return hash.memory < params.Argon2Memory || hash.iterations < params.Argon2Iterations ||
+			hash.parallelism < params.Argon2Parallelism || len(hash.key) < argon2KeyLength
+	}
+
+	if params.Algorithm == PasswordHashArgon2id {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost < params.BcryptCost
+}
+
+// parseArgon2Hash decodes "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>"
+func parseArgon2Hash(hashedPassword string) (*argon2Hash, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("unsupported argon2id version")
+	}
+
+	hash := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism); err != nil {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.New("invalid argon2id salt")
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, errors.New("invalid argon2id key")
+	}
+	return hash, nil
+}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswords     map[string]struct{}
+	commonPasswordsOnce sync.Once
+)
+
+// IsCommonPassword reports whether a password, ignoring case, is on the bundled list of
+// commonly used and breached passwords
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				commonPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+
+	_, found := commonPasswords[strings.ToLower(password)]
+	return found
+}